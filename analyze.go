@@ -0,0 +1,24 @@
+package main
+
+import "fmt"
+
+// runAnalyzeVotes 立即执行一次刷票分析, 不受vote_audit.enable影响
+func runAnalyzeVotes(args []string) error {
+	fs, cf := newFlagSet("analyze-votes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := cf.bootstrap(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	n, err := a.Service.AnalyzeVotes()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("flagged %d suspicious votes\n", n)
+	return nil
+}
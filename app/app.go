@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logger"
+	"bluebell/logic"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/mailer"
+	"bluebell/pkg/snowflake"
+	"bluebell/realtime"
+	"bluebell/setting"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// App 保存一个服务实例的全部依赖, 不同实例之间互不影响
+type App struct {
+	Config   *setting.Holder // 当前生效的配置, 热更新时整体替换
+	Logger   *zap.Logger
+	LogLevel zap.AtomicLevel
+	DB       *mysql.Store // 注入内存存储时为nil
+	Redis    *redis.Store
+	IDs      *snowflake.Node
+	Tokens   *jwt.Manager
+	Clock    func() time.Time
+	Service  *logic.Service
+	Hub      *realtime.Hub // 实时事件的订阅, 调用ListenEvents之后开始接收
+}
+
+// New 根据配置创建App, 连接MySQL和Redis
+func New(cfg *setting.Config) (*App, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Log.Level)
+	if err != nil {
+		return nil, err
+	}
+	log := logger.New(cfg.Log, cfg.App.Mode, level)
+
+	// 连接mysql
+	db, err := mysql.Init(cfg.MySQL, log)
+	if err != nil {
+		log.Error("init mysql failed", zap.Error(err))
+		return nil, err
+	}
+
+	// 连接redis
+	rds, err := redis.Init(cfg.Redis, log)
+	if err != nil {
+		log.Error("init redis failed", zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+
+	// mysql和redis分别实现业务逻辑需要的存储接口
+	stores := logic.Stores{
+		Users:         db,
+		Posts:         db,
+		Communities:   db,
+		Votes:         rds,
+		Auth:          rds,
+		Audit:         db,
+		Events:        rds,
+		Notifications: db,
+	}
+	a, err := NewWithStores(cfg, log, rds, stores)
+	if err != nil {
+		db.Close()
+		rds.Close()
+		return nil, err
+	}
+	a.DB = db
+	a.LogLevel = level
+	return a, nil
+}
+
+// NewWithStores 使用已有的存储创建App, 测试时可以注入内存存储
+// rds用于限流, 和stores中的存储可以是不同的实现, log的级别不受LogLevel控制
+func NewWithStores(cfg *setting.Config, log *zap.Logger, rds *redis.Store, stores logic.Stores) (*App, error) {
+	// 初始化雪花算法
+	ids, err := snowflake.New(cfg.App.StartTime, cfg.App.MachineID)
+	if err != nil {
+		log.Error("init snowflake failed", zap.Error(err))
+		return nil, err
+	}
+	tokens := jwt.New(cfg.Auth.JWTSecret, time.Duration(cfg.Auth.JWTExpire)*time.Hour)
+	// 没有开启邮件时只把邮件写到日志, 正文只在dev模式写出
+	var m mailer.Mailer = mailer.NewLog(log, cfg.App.Mode == setting.ModeDev)
+	if !cfg.Mail.Enable && cfg.App.Mode == setting.ModeRelease {
+		log.Warn("mail disabled in release mode, verify and reset password mails will not be sent")
+	}
+	if cfg.Mail.Enable {
+		if m, err = mailer.NewSMTP(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From); err != nil {
+			log.Error("init mailer failed", zap.Error(err))
+			return nil, err
+		}
+	}
+	clock := time.Now
+	holder := setting.NewHolder(cfg)
+
+	return &App{
+		Config:   holder,
+		Logger:   log,
+		LogLevel: zap.NewAtomicLevel(),
+		Redis:    rds,
+		IDs:      ids,
+		Tokens:   tokens,
+		Clock:    clock,
+		Service:  logic.NewService(holder, log, stores, ids, tokens, m, clock),
+		Hub:      realtime.NewHub(log),
+	}, nil
+}
+
+// ListenEvents 通过Redis订阅所有实例发布的实时事件, 返回的函数用于停止订阅
+func (a *App) ListenEvents() (stop func(), err error) {
+	return a.Hub.Listen(a.Redis)
+}
+
+// Close 等待后台任务完成之后释放App持有的连接
+func (a *App) Close() {
+	a.Service.WaitMails()
+	if a.DB != nil {
+		a.DB.Close()
+	}
+	if a.Redis != nil {
+		a.Redis.Close()
+	}
+	_ = a.Logger.Sync()
+}
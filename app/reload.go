@@ -0,0 +1,46 @@
+package app
+
+import (
+	"bluebell/setting"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Reload 应用新的配置, 只有可以热更新的配置项会立即生效
+// 新配置不合法时保留当前配置, 不能热更新的配置项发生变化时提示需要重启
+func (a *App) Reload(next *setting.Config) error {
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	cur := a.Config.Get()
+	merged, restart := setting.MergeReloadable(cur, next)
+	for _, key := range restart {
+		a.Logger.Warn("config changed but requires restart to take effect", zap.String("key", key))
+	}
+
+	level, err := zapcore.ParseLevel(merged.Log.Level)
+	if err != nil {
+		return err
+	}
+	old := a.LogLevel.Level()
+	a.LogLevel.SetLevel(level)
+	a.Config.Set(merged)
+	if old != level {
+		a.Logger.Info("log level changed", zap.Stringer("from", old), zap.Stringer("to", level))
+	}
+	a.Logger.Info("config reloaded")
+	return nil
+}
+
+// WatchConfig 监听配置文件, 变化时自动热更新
+func (a *App) WatchConfig(file, env string) (stop func(), err error) {
+	return setting.Watch(file, env, func(cfg *setting.Config, err error) {
+		if err == nil {
+			err = a.Reload(cfg)
+		}
+		if err != nil {
+			a.Logger.Error("reload config failed, keep current config", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bluebell/app"
+	"bluebell/dao/mysql"
+	"bluebell/logger"
+	"bluebell/setting"
+	"flag"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// commonFlags 所有子命令共用的参数
+type commonFlags struct {
+	config string
+	env    string
+}
+
+// newFlagSet 创建子命令的参数解析器, 自动加上 -config 和 -env 参数
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cf := new(commonFlags)
+	fs.StringVar(&cf.config, "config", setting.DefaultConfigFile, "配置文件路径")
+	fs.StringVar(&cf.env, "env", os.Getenv("GOVOTE_ENV"), "运行环境(dev/test/prod), 加载同目录下的config.<env>.yaml覆盖默认配置")
+	return fs, cf
+}
+
+// loadConfig 加载并校验配置
+func (cf *commonFlags) loadConfig() (*setting.Config, error) {
+	return setting.Load(cf.config, cf.env)
+}
+
+// openDB 只连接MySQL, 数据库迁移不需要其他依赖
+func (cf *commonFlags) openDB() (*mysql.Store, *zap.Logger, error) {
+	cfg, err := cf.loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	log := logger.New(cfg.Log, cfg.App.Mode, zap.NewAtomicLevelAt(zap.InfoLevel))
+	db, err := mysql.Init(cfg.MySQL, log)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, log, nil
+}
+
+// bootstrap 加载配置并创建App, autoMigrate为true时先执行数据库迁移
+// 数据库版本落后时返回错误
+func (cf *commonFlags) bootstrap(autoMigrate bool) (*app.App, error) {
+	cfg, err := cf.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	a, err := app.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if autoMigrate || cfg.MySQL.AutoMigrate {
+		if err := migrateUp(a.DB, a.Logger); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+	if err := a.DB.CheckSchemaVersion(); err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bluebell/models"
+	"fmt"
+)
+
+// rebuildBatchSize 每次从MySQL读取的帖子数量
+const rebuildBatchSize = 500
+
+// runRebuildCache 根据MySQL中的帖子和Redis中的投票记录重建帖子排序、社区帖子和karma
+// 重建期间帖子列表可能不完整, 建议在维护模式下执行
+func runRebuildCache(args []string) error {
+	fs, cf := newFlagSet("rebuild-cache")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := cf.bootstrap(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if err := a.Redis.ClearPostCache(); err != nil {
+		return err
+	}
+	// 每个社区的分数可能不同, 社区不存在时使用全局配置
+	weights := make(map[int64]float64)
+	scorePerVote := func(communityID int64) float64 {
+		w, ok := weights[communityID]
+		if !ok {
+			rule, err := a.Service.CommunityVoteRule(communityID)
+			if err != nil {
+				rule = a.Service.VoteRule()
+			}
+			w = rule.ScorePerVote
+			weights[communityID] = w
+		}
+		return w
+	}
+	// 秘密投票的帖子中作者的化名, 用来排除作者给自己的投票
+	authorVoter := func(p *models.Post) string {
+		return a.Service.VoterID(p, p.AuthorID)
+	}
+	total := 0
+	for page := int64(1); ; page++ {
+		posts, err := a.DB.GetPostList(page, rebuildBatchSize)
+		if err != nil {
+			return err
+		}
+		if err := a.Redis.RebuildPostCache(posts, scorePerVote, authorVoter); err != nil {
+			return err
+		}
+		total += len(posts)
+		if len(posts) < rebuildBatchSize {
+			break
+		}
+	}
+	fmt.Printf("rebuilt cache for %d posts\n", total)
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// runConfig 配置管理子命令, govote config validate 校验配置文件但不连接数据库
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("usage: govote config validate [-config file] [-env dev|test|prod]")
+	}
+
+	fs, cf := newFlagSet("config validate")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if _, err := cf.loadConfig(); err != nil {
+		return err
+	}
+	fmt.Println("config ok")
+	return nil
+}
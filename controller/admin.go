@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UnlockUserHandler 管理员解除账号的登录锁定
+func (h *Handler) UnlockUserHandler(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong user id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	// 请求体可以为空
+	p := new(models.ParamUnlockUser)
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(p); err != nil {
+			h.log.Error("UnlockUserHandler with invalid param", zap.Error(err))
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+	}
+
+	if err := h.svc.UnlockUser(userID, p.IP); err != nil {
+		h.log.Error("logic.UnlockUser failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
+
+// LoginAttemptsHandler 管理员查询某个用户名的登录审计记录
+func (h *Handler) LoginAttemptsHandler(c *gin.Context) {
+	p := &models.ParamLoginAttempts{
+		Page: 1,
+		Size: 20,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		h.log.Error("LoginAttemptsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.GetLoginAttempts(p.Username, p.Page, p.Size)
+	if err != nil {
+		h.log.Error("logic.GetLoginAttempts failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// VoteFlagsHandler 管理员查询可疑投票的审核队列
+func (h *Handler) VoteFlagsHandler(c *gin.Context) {
+	p := &models.ParamVoteFlags{
+		Status: models.VoteFlagPending,
+		Page:   1,
+		Size:   20,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		h.log.Error("VoteFlagsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.GetVoteFlags(p.Status, p.Page, p.Size)
+	if err != nil {
+		h.log.Error("logic.GetVoteFlags failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// NullifyVoteFlagHandler 管理员作废可疑投票, 重新计算帖子分数
+func (h *Handler) NullifyVoteFlagHandler(c *gin.Context) {
+	h.reviewVoteFlag(c, true)
+}
+
+// DismissVoteFlagHandler 管理员忽略可疑投票
+func (h *Handler) DismissVoteFlagHandler(c *gin.Context) {
+	h.reviewVoteFlag(c, false)
+}
+
+func (h *Handler) reviewVoteFlag(c *gin.Context, nullify bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong vote flag id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	reviewerID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if err := h.svc.ReviewVoteFlag(id, reviewerID, nullify); err != nil {
+		h.log.Error("logic.ReviewVoteFlag failed", zap.Error(err))
+		switch {
+		case errors.Is(err, mysql.ErrorVoteFlagNotExist):
+			ResponseError(c, CodeVoteFlagNotExist)
+		case errors.Is(err, logic.ErrVoteFlagReviewed):
+			ResponseError(c, CodeVoteFlagReviewed)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
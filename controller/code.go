@@ -14,6 +14,38 @@ const (
 
 	CodeNeedLogin
 	CodeInvalidToken
+
+	CodeTooManyRequests
+	CodeAccountLocked
+	CodeNoPermission
+	CodeInvalidMFACode
+	CodeMFANotEnrolled
+	CodeMFAAlreadyEnabled
+	CodeMFARequired
+	CodeKarmaTooLow
+	CodeCommunityExist
+	CodeMaintenance
+	CodeFeatureDisabled
+	CodeVoteTimeExpire
+	CodeDownvoteDisabled
+	CodeAccountTooNew
+	CodeVoteFlagNotExist
+	CodeVoteFlagReviewed
+	CodeNotificationNotExist
+	CodeEmailExist
+	CodeInvalidMailToken
+	CodeEmailNotSet
+	CodeEmailAlreadyVerified
+	CodeTagNotAllowed
+	CodeTagExist
+	CodeTagNotExist
+	CodeNotPoll
+	CodePollClosed
+	CodePollVoted
+	CodeNotDecision
+	CodeDecisionOpen
+	CodePostPublished
+	CodeVoteNullified
 )
 
 var codeMsg = map[ResCode]string{
@@ -27,6 +59,38 @@ var codeMsg = map[ResCode]string{
 	CodeNeedLogin:       "用户未登录",
 	CodeInvalidToken:    "Token已失效",
 	CodePostNotExist:    "查询不到帖子",
+	CodeTooManyRequests: "请求过于频繁,请稍后再试",
+	CodeAccountLocked:   "登录失败次数过多,请稍后再试",
+	CodeNoPermission:    "没有权限",
+
+	CodeInvalidMFACode:       "验证码错误",
+	CodeMFANotEnrolled:       "未开启两步验证",
+	CodeMFAAlreadyEnabled:    "已经开启两步验证",
+	CodeMFARequired:          "当前角色必须开启两步验证",
+	CodeKarmaTooLow:          "karma不足,暂时无法进行该操作",
+	CodeCommunityExist:       "社区已存在",
+	CodeMaintenance:          "系统维护中,请稍后再试",
+	CodeFeatureDisabled:      "功能暂未开放",
+	CodeVoteTimeExpire:       "投票时间已过",
+	CodeDownvoteDisabled:     "该社区不允许投反对票",
+	CodeAccountTooNew:        "注册时间太短,暂时不能在该社区投票",
+	CodeVoteFlagNotExist:     "审核记录不存在",
+	CodeVoteFlagReviewed:     "该投票已经审核过",
+	CodeNotificationNotExist: "通知不存在",
+	CodeEmailExist:           "邮箱已被使用",
+	CodeInvalidMailToken:     "链接无效或已过期",
+	CodeEmailNotSet:          "还没有设置邮箱",
+	CodeEmailAlreadyVerified: "邮箱已经验证过",
+	CodeTagNotAllowed:        "该社区只能使用社区定义的标签",
+	CodeTagExist:             "标签已存在",
+	CodeTagNotExist:          "标签不存在",
+	CodeNotPoll:              "该帖子不是投票帖",
+	CodePollClosed:           "投票已截止",
+	CodePollVoted:            "已经投过票了",
+	CodeNotDecision:          "该帖子不是决策帖",
+	CodeDecisionOpen:         "投票尚未截止",
+	CodePostPublished:        "帖子已经发布",
+	CodeVoteNullified:        "投票已被作废, 不能再次投票",
 }
 
 func (c ResCode) Msg() string {
@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/logic"
+	"bluebell/models"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -9,9 +12,9 @@ import (
 )
 
 // CommunityHandler查询所有的社区的列表
-func CommunityHandler(c *gin.Context) {
+func (h *Handler) CommunityHandler(c *gin.Context) {
 	// 查询到所有的社区,以community_id, community_name的形式返回
-	data, err := logic.GetCommunityList(c)
+	data, err := h.svc.GetCommunityList()
 	if err != nil {
 		ResponseError(c, CodeServerBusy)
 		return
@@ -21,23 +24,123 @@ func CommunityHandler(c *gin.Context) {
 }
 
 // CommunityDetailHandler根据社区的id查询社区的详情
-func CommunityDetailHandler(c *gin.Context) {
+func (h *Handler) CommunityDetailHandler(c *gin.Context) {
 	// 1 获取社区的id
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		zap.L().Error("wrong CommunityID param ", zap.Error(err))
+		h.log.Error("wrong CommunityID param ", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
 	// 2 根据社区id查询社区详情
-	data, err := logic.GetCommunityDetail(c, int64(id))
+	data, err := h.svc.GetCommunityDetail(int64(id))
 	if err != nil {
-		zap.L().Error("logic.GetCommunityDetail failed", zap.Error(err))
+		h.log.Error("logic.GetCommunityDetail failed", zap.Error(err))
 		ResponseError(c, CodeServerBusy)
 		return
 	}
 
 	ResponseSuccess(c, data)
 }
+
+// CreateCommunityHandler 创建社区, 需要达到一定的karma
+func (h *Handler) CreateCommunityHandler(c *gin.Context) {
+	p := new(models.ParamCreateCommunity)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("CreateCommunity with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.CreateCommunity(userID, p)
+	if err != nil {
+		h.log.Error("logic.CreateCommunity failed", zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrKarmaTooLow):
+			ResponseError(c, CodeKarmaTooLow)
+		case errors.Is(err, mysql.ErrorCommunityExist):
+			ResponseError(c, CodeCommunityExist)
+		default:
+			ResponseError(c, CodeServerBusy)
+		}
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// UpdateCommunityVoteRuleHandler 管理员修改社区的投票规则
+func (h *Handler) UpdateCommunityVoteRuleHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong community id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.CommunityVoteRule)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("UpdateCommunityVoteRule with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.UpdateCommunityVoteRule(id, p); err != nil {
+		h.log.Error("logic.UpdateCommunityVoteRule failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
+
+// FollowCommunityHandler 关注社区
+func (h *Handler) FollowCommunityHandler(c *gin.Context) {
+	h.followCommunity(c, true)
+}
+
+// UnfollowCommunityHandler 取消关注社区
+func (h *Handler) UnfollowCommunityHandler(c *gin.Context) {
+	h.followCommunity(c, false)
+}
+
+func (h *Handler) followCommunity(c *gin.Context, follow bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong CommunityID param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if follow {
+		err = h.svc.FollowCommunity(userID, id)
+	} else {
+		err = h.svc.UnfollowCommunity(userID, id)
+	}
+	if err != nil {
+		h.log.Error("logic.FollowCommunity failed", zap.Bool("follow", follow), zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"bluebell/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CastBallotHandler 给决策帖投票, 每个用户只能投一次
+func (h *Handler) CastBallotHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamBallot)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("CastBallot with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.CastBallot(postID, userID, p.Choices); err != nil {
+		h.log.Error("logic.CastBallot failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DecisionTallyHandler 查询决策帖的计票结果和每一轮的淘汰过程
+func (h *Handler) DecisionTallyHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, _ := getCurrentUser(c)
+
+	data, err := h.svc.GetDecisionTally(postID, userID)
+	if err != nil {
+		h.log.Error("logic.GetDecisionTally failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// CloseDecisionHandler 作者、版主或管理员提前截止决策帖
+func (h *Handler) CloseDecisionHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.CloseDecision(postID, userID); err != nil {
+		h.log.Error("logic.CloseDecision failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// DecisionAuditHandler 导出截止之后的审计记录, download=1时作为附件下载
+func (h *Handler) DecisionAuditHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.ExportDecisionAudit(postID)
+	if err != nil {
+		h.log.Error("logic.ExportDecisionAudit failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	if c.Query("download") == "1" {
+		c.Header("Content-Disposition", `attachment; filename="decision-`+c.Param("id")+`-audit.json"`)
+	}
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"bluebell/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MyDraftsHandler 分页查询当前登录用户的草稿和定时发布的帖子
+func (h *Handler) MyDraftsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	page, size := GetPageInfo(c)
+
+	data, err := h.svc.GetDrafts(userID, page, size)
+	if err != nil {
+		h.log.Error("logic.GetDrafts failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// UpdateDraftHandler 修改草稿, 设置了发布时间时改为定时发布
+func (h *Handler) UpdateDraftHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamDraft)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("UpdateDraft with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	post, err := h.svc.UpdateDraft(postID, userID, p)
+	if err != nil {
+		h.log.Error("logic.UpdateDraft failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, post)
+}
+
+// PublishPostHandler 立即发布草稿或定时发布的帖子
+func (h *Handler) PublishPostHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.PublishPost(postID, userID); err != nil {
+		h.log.Error("logic.PublishPost failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
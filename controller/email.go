@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// VerifyEmailHandler 通过邮件中的链接验证邮箱
+func (h *Handler) VerifyEmailHandler(c *gin.Context) {
+	p := new(models.ParamMailToken)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("VerifyEmail with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.VerifyEmail(p.Token); err != nil {
+		h.log.Error("logic.VerifyEmail failed", zap.Error(err))
+		responseEmailError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ResendVerifyEmailHandler 重新发送邮箱验证邮件
+func (h *Handler) ResendVerifyEmailHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if err := h.svc.ResendVerifyEmail(userID); err != nil {
+		h.log.Error("logic.ResendVerifyEmail failed", zap.Error(err))
+		responseEmailError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// UpdateEmailHandler 修改邮箱, 新邮箱会收到验证邮件
+func (h *Handler) UpdateEmailHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamUpdateEmail)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("UpdateEmail with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.UpdateEmail(userID, p.Email); err != nil {
+		h.log.Error("logic.UpdateEmail failed", zap.Error(err))
+		responseEmailError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// ForgotPasswordHandler 发送重置密码邮件, 不管邮箱是否存在都返回成功
+func (h *Handler) ForgotPasswordHandler(c *gin.Context) {
+	p := new(models.ParamForgotPassword)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("ForgotPassword with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	h.svc.ForgotPassword(p.Email)
+	ResponseSuccess(c, nil)
+}
+
+// ResetPasswordHandler 通过邮件中的链接重置密码
+func (h *Handler) ResetPasswordHandler(c *gin.Context) {
+	p := new(models.ParamResetPassword)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("ResetPassword with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.ResetPassword(p); err != nil {
+		h.log.Error("logic.ResetPassword failed", zap.Error(err))
+		responseEmailError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// responseEmailError 把邮箱相关的错误转换成响应码
+func responseEmailError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrInvalidMailToken):
+		ResponseError(c, CodeInvalidMailToken)
+	case errors.Is(err, logic.ErrEmailNotSet):
+		ResponseError(c, CodeEmailNotSet)
+	case errors.Is(err, logic.ErrEmailAlreadyVerified):
+		ResponseError(c, CodeEmailAlreadyVerified)
+	case errors.Is(err, mysql.ErrorEmailExist):
+		ResponseError(c, CodeEmailExist)
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(c, CodeUserNotExist)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/realtime"
+
+	"go.uber.org/zap"
+)
+
+// Handler 持有处理请求需要的依赖, 所有的接口都是它的方法
+type Handler struct {
+	svc *logic.Service
+	hub *realtime.Hub
+	log *zap.Logger
+}
+
+// NewHandler 创建Handler
+func NewHandler(svc *logic.Service, hub *realtime.Hub, log *zap.Logger) *Handler {
+	return &Handler{svc: svc, hub: hub, log: log}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// EnrollMFAHandler 生成两步验证密钥, 返回otpauth链接用于生成二维码
+func (h *Handler) EnrollMFAHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.EnrollMFA(userID)
+	if err != nil {
+		h.log.Error("logic.EnrollMFA failed", zap.Error(err))
+		responseMFAError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// ActivateMFAHandler 校验验证码并开启两步验证, 返回一次性恢复码
+func (h *Handler) ActivateMFAHandler(c *gin.Context) {
+	p := new(models.ParamMFACode)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("ActivateMFA with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.ActivateMFA(userID, p.Code)
+	if err != nil {
+		h.log.Error("logic.ActivateMFA failed", zap.Error(err))
+		responseMFAError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// DisableMFAHandler 关闭两步验证, 需要验证码或恢复码
+func (h *Handler) DisableMFAHandler(c *gin.Context) {
+	p := new(models.ParamMFACode)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("DisableMFA with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if err := h.svc.DisableMFA(userID, p.Code); err != nil {
+		h.log.Error("logic.DisableMFA failed", zap.Error(err))
+		responseMFAError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// RecoveryCodesHandler 重新生成恢复码
+func (h *Handler) RecoveryCodesHandler(c *gin.Context) {
+	p := new(models.ParamMFACode)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("RecoveryCodes with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(userID, p.Code)
+	if err != nil {
+		h.log.Error("logic.RegenerateRecoveryCodes failed", zap.Error(err))
+		responseMFAError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"recovery_codes": codes})
+}
+
+// SetRequireMFAHandler 管理员设置版主是否必须开启两步验证
+func (h *Handler) SetRequireMFAHandler(c *gin.Context) {
+	p := new(models.ParamRequireMFA)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("SetRequireMFA with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.SetRequireMFAForModerators(*p.Moderators); err != nil {
+		h.log.Error("logic.SetRequireMFAForModerators failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+func responseMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrInvalidMFACode):
+		ResponseError(c, CodeInvalidMFACode)
+	case errors.Is(err, logic.ErrMFANotEnrolled):
+		ResponseError(c, CodeMFANotEnrolled)
+	case errors.Is(err, logic.ErrMFAAlreadyEnabled):
+		ResponseError(c, CodeMFAAlreadyEnabled)
+	case errors.Is(err, logic.ErrMFARequired):
+		ResponseError(c, CodeMFARequired)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
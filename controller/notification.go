@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// NotificationsHandler 分页查询当前用户的通知, 同时返回未读数量
+func (h *Handler) NotificationsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamNotifications{
+		Page: 1,
+		Size: 20,
+	}
+	if err := c.ShouldBindQuery(p); err != nil {
+		h.log.Error("NotificationsHandler with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.GetNotifications(userID, p)
+	if err != nil {
+		h.log.Error("logic.GetNotifications failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// ReadNotificationHandler 把一条通知标记为已读
+func (h *Handler) ReadNotificationHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong notification id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.MarkNotificationRead(userID, id); err != nil {
+		h.log.Error("logic.MarkNotificationRead failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorNotificationNotExist) {
+			ResponseError(c, CodeNotificationNotExist)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
+
+// ReadAllNotificationsHandler 把全部通知标记为已读
+func (h *Handler) ReadAllNotificationsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	if err := h.svc.MarkAllNotificationsRead(userID); err != nil {
+		h.log.Error("logic.MarkAllNotificationsRead failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, nil)
+}
+
+// NotificationPrefsHandler 查询当前用户的通知设置
+func (h *Handler) NotificationPrefsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.GetNotificationPrefs(userID)
+	if err != nil {
+		h.log.Error("logic.GetNotificationPrefs failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// UpdateNotificationPrefsHandler 修改当前用户的通知设置, 请求体为 {"<type>": true/false}
+func (h *Handler) UpdateNotificationPrefsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	var prefs map[string]bool
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		h.log.Error("UpdateNotificationPrefs with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.UpdateNotificationPrefs(userID, prefs)
+	if err != nil {
+		h.log.Error("logic.UpdateNotificationPrefs failed", zap.Error(err))
+		if errors.Is(err, logic.ErrUnknownNotificationType) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PollVoteHandler 给投票帖投票, 每个用户只能投一次
+func (h *Handler) PollVoteHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamPollVote)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("PollVote with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.VotePoll(postID, userID, p.Choices)
+	if err != nil {
+		h.log.Error("logic.VotePoll failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// PollResultHandler 查询投票帖的结果, 登录时返回当前用户的选择
+func (h *Handler) PollResultHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, _ := getCurrentUser(c)
+
+	data, err := h.svc.GetPollResult(postID, userID)
+	if err != nil {
+		h.log.Error("logic.GetPollResult failed", zap.Error(err))
+		responsePollError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// responsePollError 把投票帖和决策帖相关的错误转换成响应码
+func responsePollError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrInvalidChoice):
+		ResponseError(c, CodeInvalidParam)
+	case errors.Is(err, mysql.ErrorPostNotExist):
+		ResponseError(c, CodePostNotExist)
+	case errors.Is(err, logic.ErrNotPoll):
+		ResponseError(c, CodeNotPoll)
+	case errors.Is(err, logic.ErrPollClosed):
+		ResponseError(c, CodePollClosed)
+	case errors.Is(err, logic.ErrNotDecision):
+		ResponseError(c, CodeNotDecision)
+	case errors.Is(err, logic.ErrDecisionOpen):
+		ResponseError(c, CodeDecisionOpen)
+	case errors.Is(err, logic.ErrNoPermission):
+		ResponseError(c, CodeNoPermission)
+	case errors.Is(err, redis.ErrPollVoted), errors.Is(err, mysql.ErrorBallotExist):
+		ResponseError(c, CodePollVoted)
+	case errors.Is(err, logic.ErrKarmaTooLow):
+		ResponseError(c, CodeKarmaTooLow)
+	case errors.Is(err, logic.ErrAccountTooNew):
+		ResponseError(c, CodeAccountTooNew)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
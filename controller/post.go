@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/logic"
 	"bluebell/models"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -10,11 +12,11 @@ import (
 )
 
 // CreatePostHandler 创建帖子的处理函数
-func CreatePostHandler(c *gin.Context) {
+func (h *Handler) CreatePostHandler(c *gin.Context) {
 	// 1 获取参数以及参数校验
 	p := new(models.Post)
 	if err := c.ShouldBind(p); err != nil {
-		zap.L().Error("create post with invalid param", zap.Error(err))
+		h.log.Error("create post with invalid param", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
@@ -22,16 +24,16 @@ func CreatePostHandler(c *gin.Context) {
 	// 从c中获取用户id
 	userID, err := getCurrentUser(c)
 	if err != nil {
-		zap.L().Error("user need to login again", zap.Error(err))
+		h.log.Error("user need to login again", zap.Error(err))
 		ResponseError(c, CodeNeedLogin)
 		return
 	}
 	p.AuthorID = userID
 
 	// 2 logic处理
-	if err = logic.CreatePost(p); err != nil {
-		zap.L().Error("logic.createpost failed", zap.Error(err))
-		ResponseError(c, CodeServerBusy)
+	if err = h.svc.CreatePost(p); err != nil {
+		h.log.Error("logic.createpost failed", zap.Error(err))
+		responseTagError(c, err)
 		return
 	}
 
@@ -40,12 +42,18 @@ func CreatePostHandler(c *gin.Context) {
 }
 
 // GetPostDetailHandler 获取帖子详情的处理函数
-func GetPostDetailHandler(c *gin.Context) {
+// format=markdown(默认)时content为Markdown原文, format=html时为渲染并过滤之后的HTML
+func (h *Handler) GetPostDetailHandler(c *gin.Context) {
 	// 1 参数以及校验
 	pidStr := c.Param("id")
 	pid, err := strconv.Atoi(pidStr)
 	if err != nil {
-		zap.L().Error("invalid param", zap.Error(err))
+		h.log.Error("invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	format := c.DefaultQuery("format", models.FormatMarkdown)
+	if format != models.FormatMarkdown && format != models.FormatHTML {
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
@@ -58,26 +66,47 @@ func GetPostDetailHandler(c *gin.Context) {
 	}
 
 	// logic处理,根据帖子的id来查询帖子的具体数据
-	data, err := logic.GetPostByID(int64(pid), userID)
+	data, err := h.svc.GetPostByID(int64(pid), userID)
 	if err != nil {
-		zap.L().Error("logic.get post by id failed", zap.Error(err))
+		h.log.Error("logic.get post by id failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorPostNotExist) {
+			ResponseError(c, CodePostNotExist)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
 
+	if format == models.FormatHTML {
+		data.Post.Content = data.Post.ContentHTML
+	}
+
 	// 3返回响应
 	ResponseSuccess(c, data)
 }
 
+// PreviewPostHandler 预览帖子内容渲染之后的HTML
+func (h *Handler) PreviewPostHandler(c *gin.Context) {
+	p := new(models.ParamPreview)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("PreviewPost with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	ResponseSuccess(c, gin.H{"content_html": h.svc.PreviewContent(p.Content)})
+}
+
 // GetPostListHandler 获取所有帖子列表的处理函数
-func GetPostListHandler(c *gin.Context) {
+func (h *Handler) GetPostListHandler(c *gin.Context) {
 	// 获取分页参数
 	page, size := GetPageInfo(c)
+	// 不强制要求登录, 登录时返回投票状态
+	userID, _ := getCurrentUser(c)
 
 	// 获取数据
-	data, err := logic.GetPostList(page, size)
+	data, err := h.svc.GetPostList(page, size, userID)
 	if err != nil {
-		zap.L().Error("logic.GetPostList failed", zap.Error(err))
+		h.log.Error("logic.GetPostList failed", zap.Error(err))
 		ResponseError(c, CodePostNotExist)
 		return
 	}
@@ -87,7 +116,7 @@ func GetPostListHandler(c *gin.Context) {
 }
 
 // GetPostListHandler2 根据顺序来查找所有帖子
-func GetPostListHandler2(c *gin.Context) {
+func (h *Handler) GetPostListHandler2(c *gin.Context) {
 	// 处理请求参数, 默认值如下
 	p := &models.ParamPostList{
 		Page:  1,
@@ -96,15 +125,22 @@ func GetPostListHandler2(c *gin.Context) {
 	}
 
 	if err := c.ShouldBind(p); err != nil {
-		zap.L().Error("GetPostListHandler2 param failed", zap.Error(err))
+		h.log.Error("GetPostListHandler2 param failed", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
+	// 不强制要求登录, 登录时返回投票状态
+	userID, _ := getCurrentUser(c)
+
 	// 2 获取帖子数据
-	data, err := logic.GetPostListNew(p)
+	data, err := h.svc.GetPostListNew(p, userID)
 	if err != nil {
-		zap.L().Error("logic.GetPostList failed", zap.Error(err))
+		h.log.Error("logic.GetPostList failed", zap.Error(err))
+		if errors.Is(err, logic.ErrInvalidTag) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UserDetailHandler 用户主页, 返回公开资料, 票数以及分页的帖子列表
+func (h *Handler) UserDetailHandler(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong user id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	page, size := GetPageInfo(c)
+
+	data, err := h.svc.GetUserDetail(userID, page, size)
+	if err != nil {
+		h.log.Error("logic.GetUserDetail failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			ResponseError(c, CodeUserNotExist)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// MyProfileHandler 查询当前登录用户的资料
+func (h *Handler) MyProfileHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.GetMyProfile(userID)
+	if err != nil {
+		h.log.Error("logic.GetMyProfile failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// MyVotesHandler 分页查询当前登录用户投过票的帖子
+func (h *Handler) MyVotesHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	page, size := GetPageInfo(c)
+
+	data, err := h.svc.GetUserVotedPosts(userID, page, size)
+	if err != nil {
+		h.log.Error("logic.GetUserVotedPosts failed", zap.Error(err))
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// UpdateProfileHandler 修改当前登录用户的资料
+func (h *Handler) UpdateProfileHandler(c *gin.Context) {
+	p := new(models.ParamUpdateProfile)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("UpdateProfile with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+
+	data, err := h.svc.UpdateProfile(userID, p)
+	if err != nil {
+		h.log.Error("logic.UpdateProfile failed", zap.Error(err))
+		if errors.Is(err, logic.ErrInvalidAvatar) {
+			ResponseError(c, CodeInvalidParam)
+			return
+		}
+		ResponseError(c, CodeServerBusy)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
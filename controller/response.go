@@ -31,3 +31,12 @@ func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg string) {
 		Data: nil,
 	})
 }
+
+// ResponseErrorWithStatus 返回指定HTTP状态码的错误响应, 例如限流时的429
+func ResponseErrorWithStatus(c *gin.Context, status int, code ResCode) {
+	c.JSON(status, &ResponseData{
+		Code: code,
+		Msg:  code.Msg(),
+		Data: nil,
+	})
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"bluebell/models"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// maxStreamTopics 一个连接最多订阅的频道数
+	maxStreamTopics = 20
+	// sseKeepAlive SSE连接的心跳间隔, 避免被代理断开
+	sseKeepAlive = 30 * time.Second
+)
+
+// parseTopics 获取要订阅的频道, 例如 ?topic=front&topic=community:1&topic=post:123, 默认订阅首页
+func parseTopics(c *gin.Context) ([]string, bool) {
+	topics := c.QueryArray("topic")
+	if len(topics) == 0 {
+		return []string{models.TopicFront}, true
+	}
+	if len(topics) > maxStreamTopics {
+		return nil, false
+	}
+	for _, t := range topics {
+		if !models.ValidTopic(t) {
+			return nil, false
+		}
+	}
+	return topics, true
+}
+
+// StreamSSEHandler 通过Server-Sent Events推送投票和新帖子事件
+func (h *Handler) StreamSSEHandler(c *gin.Context) {
+	topics, ok := parseTopics(c)
+	if !ok {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	sub := h.hub.Subscribe(topics)
+	defer h.hub.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx的缓冲
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Type, e)
+			return true
+		case <-time.After(sseKeepAlive):
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// StreamWSHandler 通过WebSocket推送投票和新帖子事件, 每条消息是一个json格式的事件
+func (h *Handler) StreamWSHandler(c *gin.Context) {
+	topics, ok := parseTopics(c)
+	if !ok {
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	// 握手之前订阅, 客户端连接成功之后的事件不会丢失
+	sub := h.hub.Subscribe(topics)
+	defer h.hub.Unsubscribe(sub)
+
+	srv := websocket.Server{Handler: func(ws *websocket.Conn) {
+		// 客户端不需要发送数据, 读取失败说明连接已经断开
+		closed := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, ws)
+			close(closed)
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, e); err != nil {
+					h.log.Debug("websocket send failed", zap.Error(err))
+					return
+				}
+			}
+		}
+	}}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UpdatePostTagsHandler 修改帖子的标签, 只有作者、版主和管理员可以修改
+func (h *Handler) UpdatePostTagsHandler(c *gin.Context) {
+	userID, err := getCurrentUser(c)
+	if err != nil {
+		ResponseError(c, CodeNeedLogin)
+		return
+	}
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong post id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamPostTags)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("UpdatePostTags with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	tags, err := h.svc.UpdatePostTags(postID, userID, p.Tags)
+	if err != nil {
+		h.log.Error("logic.UpdatePostTags failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"tags": tags})
+}
+
+// CommunityTagsHandler 查询社区的标签及每个标签的帖子数
+func (h *Handler) CommunityTagsHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong community id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	data, err := h.svc.GetCommunityTags(id)
+	if err != nil {
+		h.log.Error("logic.GetCommunityTags failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, data)
+}
+
+// CreateCommunityTagHandler 管理员给社区定义标签
+func (h *Handler) CreateCommunityTagHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong community id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamCommunityTag)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("CreateCommunityTag with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	tag, err := h.svc.CreateCommunityTag(id, p)
+	if err != nil {
+		h.log.Error("logic.CreateCommunityTag failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, tag)
+}
+
+// DeleteCommunityTagHandler 管理员删除社区定义的标签
+func (h *Handler) DeleteCommunityTagHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log.Error("wrong community id param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
+
+	if err := h.svc.DeleteCommunityTag(id, c.Param("name")); err != nil {
+		h.log.Error("logic.DeleteCommunityTag failed", zap.Error(err))
+		responseTagError(c, err)
+		return
+	}
+	ResponseSuccess(c, nil)
+}
+
+// responseTagError 把标签相关的错误转换成响应码
+func responseTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, logic.ErrInvalidTag), errors.Is(err, mysql.ErrorInvalidID),
+		errors.Is(err, logic.ErrInvalidPoll), errors.Is(err, logic.ErrInvalidCloseTime),
+		errors.Is(err, logic.ErrInvalidPublishTime):
+		ResponseError(c, CodeInvalidParam)
+	case errors.Is(err, logic.ErrTagNotAllowed):
+		ResponseError(c, CodeTagNotAllowed)
+	case errors.Is(err, logic.ErrNoPermission):
+		ResponseError(c, CodeNoPermission)
+	case errors.Is(err, mysql.ErrorPostNotExist):
+		ResponseError(c, CodePostNotExist)
+	case errors.Is(err, mysql.ErrorTagExist):
+		ResponseError(c, CodeTagExist)
+	case errors.Is(err, mysql.ErrorTagNotExist):
+		ResponseError(c, CodeTagNotExist)
+	case errors.Is(err, mysql.ErrorPostPublished):
+		ResponseError(c, CodePostPublished)
+	default:
+		ResponseError(c, CodeServerBusy)
+	}
+}
@@ -4,32 +4,39 @@ import (
 	"bluebell/dao/mysql"
 	"bluebell/logic"
 	"bluebell/models"
+	"bluebell/pkg/jwt"
 	"errors"
+	"math"
 	"strconv"
 
+	jwtgo "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 // SignUpHandler处理注册路由
-func SignUpHandler(c *gin.Context) {
+func (h *Handler) SignUpHandler(c *gin.Context) {
 	// 1 参数校验
 	p := new(models.ParamSignUp)
 	if err := c.ShouldBind(&p); err != nil {
 		//请求参数有误
-		zap.L().Error("Signup with invalid param", zap.Error(err))
+		h.log.Error("Signup with invalid param", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
 	// 2业务处理
-	if err := logic.SignUp(p); err != nil {
-		zap.L().Error("logic.Signup failed", zap.Error(err))
+	if err := h.svc.SignUp(p); err != nil {
+		h.log.Error("logic.Signup failed", zap.Error(err))
 		if errors.Is(err, mysql.ErrorUserExist) {
 			// 用户已经存在,提示前端
 			ResponseError(c, CodeUserExist)
 			return
 		}
+		if errors.Is(err, mysql.ErrorEmailExist) {
+			ResponseError(c, CodeEmailExist)
+			return
+		}
 		ResponseError(c, CodeServerBusy)
 		return
 	}
@@ -39,37 +46,67 @@ func SignUpHandler(c *gin.Context) {
 }
 
 // LoginHandler登录请求路由
-func LoginHandler(c *gin.Context) {
+func (h *Handler) LoginHandler(c *gin.Context) {
 	// 1 参数校验
 	p := new(models.ParamLogin)
 	if err := c.ShouldBind(&p); err != nil {
 		//请求参数有误
-		zap.L().Error("Signup with invalid param", zap.Error(err))
+		h.log.Error("Signup with invalid param", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
 
 	// 2 业务逻辑处理
-	token, userID, username, err := logic.Login(p)
+	data, err := h.svc.Login(p, c.ClientIP())
+	if err != nil {
+		h.log.Error("Login error", zap.Error(err))
+		responseLoginError(c, err)
+		return
+	}
+
+	// 3 返回响应
+	ResponseSuccess(c, data)
+}
+
+// LoginMFAHandler 登录第二步, 使用mfa_token和两步验证码换取正式token
+func (h *Handler) LoginMFAHandler(c *gin.Context) {
+	p := new(models.ParamLoginMFA)
+	if err := c.ShouldBindJSON(p); err != nil {
+		h.log.Error("LoginMFA with invalid param", zap.Error(err))
+		ResponseError(c, CodeInvalidParam)
+		return
+	}
 
+	data, err := h.svc.LoginMFA(p, c.ClientIP())
 	if err != nil {
-		zap.L().Error("Login error", zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserNotExist) {
-			ResponseError(c, CodeUserNotExist)
-			return
-		} else if errors.Is(err, mysql.ErrorInvalidPassword) {
-			ResponseError(c, CodeInvalidPassword)
-			return
-		} else {
-			ResponseError(c, CodeServerBusy)
+		h.log.Error("logic.LoginMFA failed", zap.Error(err))
+		responseLoginError(c, err)
+		return
+	}
+
+	ResponseSuccess(c, data)
+}
+
+// responseLoginError 登录相关接口统一的错误响应
+func responseLoginError(c *gin.Context, err error) {
+	var locked *logic.ErrLoginLocked
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		ResponseError(c, CodeAccountLocked)
+	case errors.Is(err, mysql.ErrorUserNotExist) || errors.Is(err, mysql.ErrorInvalidPassword):
+		// 用户不存在和密码错误返回同样的结果, 防止枚举用户名
+		ResponseError(c, CodeInvalidPassword)
+	case errors.Is(err, logic.ErrInvalidMFACode):
+		ResponseError(c, CodeInvalidMFACode)
+	case errors.Is(err, jwt.ErrTokenPurpose):
+		ResponseError(c, CodeInvalidToken)
+	default:
+		var ve *jwtgo.ValidationError
+		if errors.As(err, &ve) {
+			ResponseError(c, CodeInvalidToken)
 			return
 		}
+		ResponseError(c, CodeServerBusy)
 	}
-
-	// 3 返回响应
-	ResponseSuccess(c, gin.H{
-		"user_id":  strconv.FormatInt(userID, 10),
-		"username": username,
-		"token":    token,
-	})
 }
@@ -1,20 +1,22 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
 	"bluebell/logic"
 	"bluebell/models"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 // 帖子投票的控制函数
-func PostVoteHandler(c *gin.Context) {
+func (h *Handler) PostVoteHandler(c *gin.Context) {
 	// 1 参数绑定
 	p := new(models.ParamVoteData)
 	if err := c.ShouldBind(p); err != nil {
-		zap.L().Error("PostVoteHandler ShouldBind error", zap.Error(err))
+		h.log.Error("PostVoteHandler ShouldBind error", zap.Error(err))
 		ResponseError(c, CodeInvalidParam)
 		return
 	}
@@ -24,19 +26,34 @@ func PostVoteHandler(c *gin.Context) {
 	// 获取当前投票用户的id
 	userID, err := getCurrentUser(c)
 	if err != nil {
-		zap.L().Error("without login", zap.Error(err))
+		h.log.Error("without login", zap.Error(err))
 		ResponseError(c, CodeNeedLogin)
 		return
 	}
 
 	// 具体投票的业务逻辑
-	if err := logic.VoteForPost(userID, p); err != nil {
-		zap.L().Error("logic.VoteForPost error", zap.Error(err))
-		if err == redis.ErrVoteRepeated {
+	if err := h.svc.VoteForPost(userID, p, c.ClientIP()); err != nil {
+		h.log.Error("logic.VoteForPost error", zap.Error(err))
+		switch {
+		case errors.Is(err, redis.ErrVoteRepeated):
 			ResponseError(c, CodeVoteRepeated)
-			return
+		case errors.Is(err, redis.ErrVoteTimeExpire):
+			ResponseError(c, CodeVoteTimeExpire)
+		case errors.Is(err, logic.ErrDownvoteDisabled):
+			ResponseError(c, CodeDownvoteDisabled)
+		case errors.Is(err, logic.ErrAccountTooNew):
+			ResponseError(c, CodeAccountTooNew)
+		case errors.Is(err, logic.ErrVoteNullified):
+			ResponseError(c, CodeVoteNullified)
+		case errors.Is(err, mysql.ErrorPostNotExist):
+			ResponseError(c, CodePostNotExist)
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(c, CodeInvalidParam)
+		case errors.Is(err, logic.ErrKarmaTooLow):
+			ResponseError(c, CodeKarmaTooLow)
+		default:
+			ResponseError(c, CodeServerBusy)
 		}
-		ResponseError(c, CodeServerBusy)
 		return
 	}
 
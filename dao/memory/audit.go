@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AuditStore 投票记录和可疑投票审核队列的内存实现
+type AuditStore struct {
+	mu      sync.RWMutex
+	records []*models.VoteRecord
+	flags   []*models.VoteFlag
+}
+
+func NewAuditStore() *AuditStore {
+	return &AuditStore{}
+}
+
+func (s *AuditStore) InsertVoteRecord(r *models.VoteRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := *r
+	record.ID = int64(len(s.records) + 1)
+	s.records = append(s.records, &record)
+	return nil
+}
+
+func (s *AuditStore) GetVoteRecordsSince(since time.Time) ([]*models.VoteRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var records []*models.VoteRecord
+	for _, r := range s.records {
+		if !r.CreateTime.Before(since) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (s *AuditStore) InsertVoteFlags(flags []*models.VoteFlag) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, f := range flags {
+		if s.findFlag(f.PostID, f.UserID, f.Reason) != nil {
+			continue
+		}
+		flag := *f
+		flag.ID = int64(len(s.flags) + 1)
+		flag.Status = models.VoteFlagPending
+		flag.CreateTime = time.Now()
+		s.flags = append(s.flags, &flag)
+		n++
+	}
+	return n, nil
+}
+
+func (s *AuditStore) findFlag(postID, userID int64, reason string) *models.VoteFlag {
+	for _, f := range s.flags {
+		if f.PostID == postID && f.UserID == userID && f.Reason == reason {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *AuditStore) GetVoteFlags(status int8, page, size int64) ([]*models.VoteFlag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.VoteFlag
+	for _, f := range s.flags {
+		if f.Status == status {
+			flag := *f
+			matched = append(matched, &flag)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	start, end := paginate(len(matched), page, size)
+	return matched[start:end], nil
+}
+
+func (s *AuditStore) GetVoteFlagByID(id int64) (*models.VoteFlag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, f := range s.flags {
+		if f.ID == id {
+			flag := *f
+			return &flag, nil
+		}
+	}
+	return nil, mysql.ErrorVoteFlagNotExist
+}
+
+func (s *AuditStore) ReviewVoteFlags(postID, userID int64, status int8, reviewerID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, f := range s.flags {
+		if f.PostID == postID && f.UserID == userID && f.Status == models.VoteFlagPending {
+			f.Status = status
+			f.ReviewerID = reviewerID
+			f.ReviewTime = &now
+		}
+	}
+	return nil
+}
+
+func (s *AuditStore) IsVoteNullified(postID, userID int64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, f := range s.flags {
+		if f.PostID == postID && f.UserID == userID && f.Status == models.VoteFlagNullified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
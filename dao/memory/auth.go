@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuthStore 登录保护和两步验证状态的内存实现
+type AuthStore struct {
+	mu                sync.Mutex
+	values            map[string]expiring
+	requireMFAForMods bool
+	mailTokens        map[string]mailToken
+
+	// Now 当前时间, 测试中可以替换来模拟锁定过期
+	Now func() time.Time
+}
+
+func NewAuthStore() *AuthStore {
+	return &AuthStore{
+		values:     make(map[string]expiring),
+		mailTokens: make(map[string]mailToken),
+		Now:        time.Now,
+	}
+}
+
+// get 读取未过期的值
+func (s *AuthStore) get(key string) (expiring, bool) {
+	v, ok := s.values[key]
+	if !ok || !v.alive(s.Now()) {
+		delete(s.values, key)
+		return expiring{}, false
+	}
+	return v, true
+}
+
+func (s *AuthStore) ttl(key string) time.Duration {
+	v, ok := s.get(key)
+	if !ok {
+		return 0
+	}
+	if v.expireAt.IsZero() {
+		return -1
+	}
+	return v.expireAt.Sub(s.Now())
+}
+
+// incr 计数加一, 并重新设置过期时间
+func (s *AuthStore) incr(key string, window time.Duration) int64 {
+	v, _ := s.get(key)
+	v.value++
+	v.expireAt = s.Now().Add(window)
+	s.values[key] = v
+	return v.value
+}
+
+func (s *AuthStore) GetLoginLockTTL(username, ip string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ttl := s.ttl("lock:account:" + username)
+	if ipTTL := s.ttl("lock:ip:" + ip); ipTTL > ttl {
+		ttl = ipTTL
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, nil
+}
+
+func (s *AuthStore) GetLoginFailures(username, ip string) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, _ := s.get("fail:account:" + username)
+	byIP, _ := s.get("fail:ip:" + ip)
+	return account.value, byIP.value, nil
+}
+
+func (s *AuthStore) IncrLoginFailures(username, ip string, window time.Duration) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.incr("fail:account:"+username, window), s.incr("fail:ip:"+ip, window), nil
+}
+
+func (s *AuthStore) LockAccount(username string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values["lock:account:"+username] = expiring{value: 1, expireAt: s.Now().Add(d)}
+	return nil
+}
+
+func (s *AuthStore) LockIP(ip string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values["lock:ip:"+ip] = expiring{value: 1, expireAt: s.Now().Add(d)}
+	return nil
+}
+
+func (s *AuthStore) ClearLoginFailures(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, "fail:account:"+username)
+	return nil
+}
+
+func (s *AuthStore) UnlockAccount(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, "lock:account:"+username)
+	delete(s.values, "fail:account:"+username)
+	return nil
+}
+
+func (s *AuthStore) UnlockIP(ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, "lock:ip:"+ip)
+	delete(s.values, "fail:ip:"+ip)
+	return nil
+}
+
+func (s *AuthStore) MarkTOTPStepUsed(userID, step int64, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := "mfa:used:" + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(step, 10)
+	if _, ok := s.get(key); ok {
+		return false, nil
+	}
+	s.values[key] = expiring{value: 1, expireAt: s.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *AuthStore) GetRequireMFAForModerators() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requireMFAForMods, nil
+}
+
+func (s *AuthStore) SetRequireMFAForModerators(require bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requireMFAForMods = require
+	return nil
+}
+
+type mailToken struct {
+	value    string
+	expireAt time.Time
+}
+
+func (s *AuthStore) SaveMailToken(purpose, tokenHash, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mailTokens[purpose+":"+tokenHash] = mailToken{value: value, expireAt: s.Now().Add(ttl)}
+	return nil
+}
+
+func (s *AuthStore) ConsumeMailToken(purpose, tokenHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := purpose + ":" + tokenHash
+	t, ok := s.mailTokens[key]
+	delete(s.mailTokens, key)
+	if !ok || !s.Now().Before(t.expireAt) {
+		return "", nil
+	}
+	return t.value, nil
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CommunityStore 社区数据的内存实现
+type CommunityStore struct {
+	mu          sync.RWMutex
+	communities map[int64]*models.CommunityDetail
+	followers   map[int64]map[int64]bool // 社区id -> 关注的用户id
+	tags        map[int64]map[string]*models.CommunityTag
+}
+
+// NewCommunityStore 创建社区存储, 可以传入初始的社区
+func NewCommunityStore(communities ...*models.CommunityDetail) *CommunityStore {
+	s := &CommunityStore{
+		communities: make(map[int64]*models.CommunityDetail),
+		followers:   make(map[int64]map[int64]bool),
+		tags:        make(map[int64]map[string]*models.CommunityTag),
+	}
+	for _, c := range communities {
+		detail := *c
+		s.communities[c.ID] = &detail
+	}
+	return s
+}
+
+func (s *CommunityStore) GetCommunityList() ([]*models.Community, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*models.Community, 0, len(s.communities))
+	for _, c := range s.communities {
+		list = append(list, &models.Community{ID: c.ID, Name: c.Name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (s *CommunityStore) GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.communities[id]
+	if !ok {
+		return nil, mysql.ErrorInvalidID
+	}
+	detail := *c
+	return &detail, nil
+}
+
+func (s *CommunityStore) CreateCommunity(name, introduction string) (*models.CommunityDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var maxID int64
+	for id, c := range s.communities {
+		if c.Name == name {
+			return nil, mysql.ErrorCommunityExist
+		}
+		if id > maxID {
+			maxID = id
+		}
+	}
+	c := &models.CommunityDetail{
+		ID:           maxID + 1,
+		Name:         name,
+		Introduction: introduction,
+		CreateTime:   time.Now(),
+	}
+	s.communities[c.ID] = c
+	detail := *c
+	return &detail, nil
+}
+
+func (s *CommunityStore) UpdateCommunityVoteRule(id int64, rule *models.CommunityVoteRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.communities[id]
+	if !ok {
+		return mysql.ErrorInvalidID
+	}
+	c.CommunityVoteRule = *rule
+	return nil
+}
+
+func (s *CommunityStore) FollowCommunity(userID, communityID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.followers[communityID] == nil {
+		s.followers[communityID] = make(map[int64]bool)
+	}
+	s.followers[communityID][userID] = true
+	return nil
+}
+
+func (s *CommunityStore) UnfollowCommunity(userID, communityID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.followers[communityID], userID)
+	return nil
+}
+
+func (s *CommunityStore) GetCommunityFollowers(communityID int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	userIDs := make([]int64, 0, len(s.followers[communityID]))
+	for id := range s.followers[communityID] {
+		userIDs = append(userIDs, id)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	return userIDs, nil
+}
+
+func (s *CommunityStore) GetFollowedCommunities(userID int64) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var communityIDs []int64
+	for id, users := range s.followers {
+		if users[userID] {
+			communityIDs = append(communityIDs, id)
+		}
+	}
+	sort.Slice(communityIDs, func(i, j int) bool { return communityIDs[i] < communityIDs[j] })
+	return communityIDs, nil
+}
+
+func (s *CommunityStore) GetCommunityTags(communityID int64) ([]*models.CommunityTag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tags []*models.CommunityTag
+	for _, t := range s.tags[communityID] {
+		tag := *t
+		tags = append(tags, &tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *CommunityStore) CreateCommunityTag(tag *models.CommunityTag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tags[tag.CommunityID][tag.Name]; ok {
+		return mysql.ErrorTagExist
+	}
+	if s.tags[tag.CommunityID] == nil {
+		s.tags[tag.CommunityID] = make(map[string]*models.CommunityTag)
+	}
+	t := *tag
+	s.tags[tag.CommunityID][tag.Name] = &t
+	return nil
+}
+
+func (s *CommunityStore) DeleteCommunityTag(communityID int64, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tags[communityID][name]; !ok {
+		return mysql.ErrorTagNotExist
+	}
+	delete(s.tags[communityID], name)
+	return nil
+}
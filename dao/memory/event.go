@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"bluebell/models"
+	"sync"
+)
+
+// EventStore 实时事件的内存实现, 只记录发布过的事件
+type EventStore struct {
+	mu     sync.Mutex
+	events []*models.Event
+}
+
+func NewEventStore() *EventStore {
+	return &EventStore{}
+}
+
+func (s *EventStore) PublishEvent(e *models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+// Events 返回发布过的事件, 方便测试
+func (s *EventStore) Events() []*models.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.Event(nil), s.events...)
+}
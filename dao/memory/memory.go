@@ -0,0 +1,78 @@
+// Package memory 提供logic中各个存储接口的内存实现, 用于单元测试和本地调试
+// 返回的错误与dao/mysql、dao/redis保持一致, 上层的错误处理不需要区分实现
+package memory
+
+import (
+	"bluebell/logic"
+	"sort"
+	"time"
+)
+
+var (
+	_ logic.UserStore         = (*UserStore)(nil)
+	_ logic.PostStore         = (*PostStore)(nil)
+	_ logic.CommunityStore    = (*CommunityStore)(nil)
+	_ logic.VoteStore         = (*VoteStore)(nil)
+	_ logic.AuthStore         = (*AuthStore)(nil)
+	_ logic.AuditStore        = (*AuditStore)(nil)
+	_ logic.EventPublisher    = (*EventStore)(nil)
+	_ logic.NotificationStore = (*NotificationStore)(nil)
+)
+
+// paginate 计算分页的起止下标
+func paginate(total int, page, size int64) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		return 0, 0
+	}
+	start := int((page - 1) * size)
+	if start > total {
+		start = total
+	}
+	end := start + int(size)
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// zset 简单的有序集合, 只实现用到的操作
+type zset map[string]float64
+
+// revRange 按分数从高到低排序, 分数相同时按成员倒序, 与redis的ZREVRANGE一致
+func (z zset) revRange() []string {
+	members := make([]string, 0, len(z))
+	for m := range z {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if z[members[i]] != z[members[j]] {
+			return z[members[i]] > z[members[j]]
+		}
+		return members[i] > members[j]
+	})
+	return members
+}
+
+// intersect 保留同时在other中的成员, 分数使用z中的分数
+func (z zset) intersect(other zset) zset {
+	inter := make(zset)
+	for m, score := range z {
+		if _, ok := other[m]; ok {
+			inter[m] = score
+		}
+	}
+	return inter
+}
+
+// expiring 带过期时间的值
+type expiring struct {
+	value    int64
+	expireAt time.Time // 零值表示永不过期
+}
+
+func (e expiring) alive(now time.Time) bool {
+	return e.expireAt.IsZero() || now.Before(e.expireAt)
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NotificationStore 站内通知和通知设置的内存实现
+type NotificationStore struct {
+	mu            sync.RWMutex
+	notifications []*models.Notification
+	prefs         map[int64]map[string]bool
+}
+
+func NewNotificationStore() *NotificationStore {
+	return &NotificationStore{prefs: make(map[int64]map[string]bool)}
+}
+
+func (s *NotificationStore) InsertNotifications(ns []*models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range ns {
+		if n.DedupeKey != nil && s.findDedupe(n.UserID, *n.DedupeKey) {
+			continue
+		}
+		notification := *n
+		notification.ID = int64(len(s.notifications) + 1)
+		notification.CreateTime = time.Now()
+		s.notifications = append(s.notifications, &notification)
+	}
+	return nil
+}
+
+func (s *NotificationStore) findDedupe(userID int64, key string) bool {
+	for _, n := range s.notifications {
+		if n.UserID == userID && n.DedupeKey != nil && *n.DedupeKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *NotificationStore) GetNotifications(userID int64, unreadOnly bool, page, size int64) ([]*models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.Notification
+	for _, n := range s.notifications {
+		if n.UserID == userID && (!unreadOnly || !n.IsRead) {
+			notification := *n
+			matched = append(matched, &notification)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	start, end := paginate(len(matched), page, size)
+	return matched[start:end], nil
+}
+
+func (s *NotificationStore) CountUnreadNotifications(userID int64) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n int64
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && !notification.IsRead {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *NotificationStore) MarkNotificationRead(userID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.notifications {
+		if n.ID == id && n.UserID == userID {
+			n.IsRead = true
+			return nil
+		}
+	}
+	return mysql.ErrorNotificationNotExist
+}
+
+func (s *NotificationStore) MarkAllNotificationsRead(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.notifications {
+		if n.UserID == userID {
+			n.IsRead = true
+		}
+	}
+	return nil
+}
+
+func (s *NotificationStore) GetNotificationPrefs(userID int64) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prefs := make(map[string]bool, len(s.prefs[userID]))
+	for typ, enabled := range s.prefs[userID] {
+		prefs[typ] = enabled
+	}
+	return prefs, nil
+}
+
+func (s *NotificationStore) SetNotificationPrefs(userID int64, prefs map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prefs[userID] == nil {
+		s.prefs[userID] = make(map[string]bool)
+	}
+	for typ, enabled := range prefs {
+		s.prefs[userID][typ] = enabled
+	}
+	return nil
+}
+
+func (s *NotificationStore) GetNotificationDisabledUsers(typ string, userIDs []int64) (map[int64]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	disabled := make(map[int64]bool)
+	for _, id := range userIDs {
+		if enabled, ok := s.prefs[id][typ]; ok && !enabled {
+			disabled[id] = true
+		}
+	}
+	return disabled, nil
+}
+
+func (s *NotificationStore) GetNotificationEnabledUsers(typ string) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var userIDs []int64
+	for id, prefs := range s.prefs {
+		if prefs[typ] {
+			userIDs = append(userIDs, id)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	return userIDs, nil
+}
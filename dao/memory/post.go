@@ -0,0 +1,242 @@
+package memory
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PostStore 帖子数据的内存实现
+type PostStore struct {
+	mu      sync.RWMutex
+	posts   map[int64]*models.Post
+	order   []int64 // 插入顺序
+	ballots map[int64][]*models.Ballot
+}
+
+func NewPostStore() *PostStore {
+	return &PostStore{
+		posts:   make(map[int64]*models.Post),
+		ballots: make(map[int64][]*models.Ballot),
+	}
+}
+
+func (s *PostStore) CreatePost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post := *p
+	post.Tags = append([]string{}, p.Tags...)
+	if p.Poll != nil {
+		poll := *p.Poll
+		poll.Options = append([]string{}, p.Poll.Options...)
+		post.Poll = &poll
+	}
+	if p.Decision != nil {
+		d := *p.Decision
+		d.Options = append([]string{}, p.Decision.Options...)
+		post.Decision = &d
+	}
+	s.posts[p.ID] = &post
+	s.order = append(s.order, p.ID)
+	return nil
+}
+
+func (s *PostStore) SetPostTags(postID int64, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.posts[postID]; ok {
+		p.Tags = append([]string{}, tags...)
+	}
+	return nil
+}
+
+func (s *PostStore) GetPostByID(id int64) (*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.posts[id]
+	if !ok {
+		return nil, mysql.ErrorPostNotExist
+	}
+	post := *p
+	return &post, nil
+}
+
+func (s *PostStore) GetPostList(page, size int64) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var published []*models.Post
+	for _, id := range s.order {
+		if p := s.posts[id]; p.Published() {
+			post := *p
+			published = append(published, &post)
+		}
+	}
+	start, end := paginate(len(published), page, size)
+	return published[start:end], nil
+}
+
+// GetPostListsByIDs 按ids的顺序返回, 不存在的id直接跳过
+func (s *PostStore) GetPostListsByIDs(ids []string) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	posts := make([]*models.Post, 0, len(ids))
+	for _, idStr := range ids {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		if p, ok := s.posts[id]; ok {
+			post := *p
+			posts = append(posts, &post)
+		}
+	}
+	return posts, nil
+}
+
+func (s *PostStore) GetPostListByAuthor(authorID, page, size int64) ([]*models.Post, error) {
+	return s.listByAuthor(authorID, true, page, size), nil
+}
+
+func (s *PostStore) GetDraftsByAuthor(authorID, page, size int64) ([]*models.Post, error) {
+	return s.listByAuthor(authorID, false, page, size), nil
+}
+
+// listByAuthor 按时间倒序列出作者已发布或未发布的帖子
+func (s *PostStore) listByAuthor(authorID int64, published bool, page, size int64) []*models.Post {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.Post
+	for _, id := range s.order {
+		if p := s.posts[id]; p.AuthorID == authorID && p.Published() == published {
+			post := *p
+			matched = append(matched, &post)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreateTime.After(matched[j].CreateTime)
+	})
+	start, end := paginate(len(matched), page, size)
+	return matched[start:end]
+}
+
+func (s *PostStore) UnpublishPost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.posts[p.ID]
+	if !ok || !old.Published() {
+		return nil
+	}
+	post := *old
+	post.Status = p.Status
+	post.CreateTime = p.CreateTime
+	s.posts[p.ID] = &post
+	return nil
+}
+
+// GetDuePosts 按发布时间顺序返回到期的定时帖子
+func (s *PostStore) GetDuePosts(now time.Time) ([]*models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var due []*models.Post
+	for _, id := range s.order {
+		if p := s.posts[id]; p.Status == models.PostStatusScheduled && !p.PublishTime.After(now) {
+			post := *p
+			due = append(due, &post)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].PublishTime.Before(*due[j].PublishTime)
+	})
+	return due, nil
+}
+
+// UpdateDraft 替换成新的对象, 不修改已经返回给调用方的帖子
+func (s *PostStore) UpdateDraft(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.posts[p.ID]
+	if !ok {
+		return mysql.ErrorPostNotExist
+	}
+	if old.Published() {
+		return mysql.ErrorPostPublished
+	}
+	post := *old
+	post.Status = p.Status
+	post.PublishTime = p.PublishTime
+	post.Title = p.Title
+	post.Content = p.Content
+	post.ContentHTML = p.ContentHTML
+	post.Tags = append([]string{}, p.Tags...)
+	post.CreateTime = p.CreateTime
+	s.posts[p.ID] = &post
+	return nil
+}
+
+func (s *PostStore) PublishPost(postID int64, publishTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.posts[postID]
+	if !ok || old.Published() {
+		return mysql.ErrorPostPublished
+	}
+	post := *old
+	post.Status = models.PostStatusPublished
+	post.CreateTime = publishTime
+	s.posts[postID] = &post
+	return nil
+}
+
+// CloseDecision 替换成新的对象, 不修改已经返回给调用方的帖子
+func (s *PostStore) CloseDecision(postID int64, closeTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[postID]
+	if !ok || p.Decision == nil {
+		return mysql.ErrorPostNotExist
+	}
+	d := *p.Decision
+	d.CloseTime = &closeTime
+	p.Decision = &d
+	return nil
+}
+
+func (s *PostStore) InsertBallot(b *models.Ballot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.ballots[b.PostID] {
+		if old.Voter == b.Voter {
+			return mysql.ErrorBallotExist
+		}
+	}
+	ballot := *b
+	ballot.Choices = append([]int{}, b.Choices...)
+	s.ballots[b.PostID] = append(s.ballots[b.PostID], &ballot)
+	return nil
+}
+
+func (s *PostStore) GetBallots(postID int64) ([]*models.Ballot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ballots := make([]*models.Ballot, len(s.ballots[postID]))
+	for i, b := range s.ballots[postID] {
+		ballot := *b
+		ballots[i] = &ballot
+	}
+	return ballots, nil
+}
+
+func (s *PostStore) GetBallot(postID int64, voter string) (*models.Ballot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, b := range s.ballots[postID] {
+		if b.Voter == voter {
+			ballot := *b
+			return &ballot, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type userRecord struct {
+	user    models.User
+	profile models.UserProfile
+	email   models.UserEmail
+}
+
+// UserStore 用户数据的内存实现
+type UserStore struct {
+	mu            sync.RWMutex
+	users         map[int64]*userRecord
+	byName        map[string]int64
+	byEmail       map[string]int64
+	attempts      []*models.LoginAttempt
+	recoveryCodes map[int64]map[string]bool // 用户id -> 恢复码哈希 -> 是否已使用
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users:         make(map[int64]*userRecord),
+		byName:        make(map[string]int64),
+		byEmail:       make(map[string]int64),
+		recoveryCodes: make(map[int64]map[string]bool),
+	}
+}
+
+func (s *UserStore) CheckUserExist(username string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.byName[username]; ok {
+		return mysql.ErrorUserExist
+	}
+	return nil
+}
+
+func (s *UserStore) InsertUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byName[user.Username]; ok {
+		return mysql.ErrorUserExist
+	}
+	s.users[user.UserID] = &userRecord{
+		user: *user,
+		profile: models.UserProfile{
+			UserID:     user.UserID,
+			Username:   user.Username,
+			CreateTime: time.Now(),
+		},
+	}
+	s.byName[user.Username] = user.UserID
+	return nil
+}
+
+func (s *UserStore) Login(user *models.User) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.byName[user.Username]
+	if !ok {
+		return mysql.ErrorUserNotExist
+	}
+	stored := s.users[id].user
+	if stored.Password != user.Password {
+		return mysql.ErrorInvalidPassword
+	}
+	*user = stored
+	return nil
+}
+
+func (s *UserStore) GetUserByID(id int64) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.users[id]
+	if !ok {
+		return nil, mysql.ErrorUserNotExist
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (s *UserStore) GetUserByUsername(username string) (*models.User, error) {
+	s.mu.RLock()
+	id, ok := s.byName[username]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, mysql.ErrorUserNotExist
+	}
+	return s.GetUserByID(id)
+}
+
+func (s *UserStore) GetUserProfile(id int64) (*models.UserProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.users[id]
+	if !ok {
+		return nil, mysql.ErrorUserNotExist
+	}
+	profile := r.profile
+	return &profile, nil
+}
+
+func (s *UserStore) UpdateUserProfile(id int64, p *models.ParamUpdateProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.users[id]
+	if !ok {
+		return nil // 与update语句一致, 没有匹配的行不报错
+	}
+	if p.DisplayName != nil {
+		r.profile.DisplayName = *p.DisplayName
+	}
+	if p.Bio != nil {
+		r.profile.Bio = *p.Bio
+	}
+	if p.Avatar != nil {
+		r.profile.Avatar = *p.Avatar
+	}
+	return nil
+}
+
+func (s *UserStore) SetUserRole(id int64, role int8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.users[id]
+	if !ok {
+		return mysql.ErrorUserNotExist
+	}
+	r.user.Role = role
+	return nil
+}
+
+func (s *UserStore) InsertLoginAttempt(a *models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt := *a
+	attempt.ID = int64(len(s.attempts) + 1)
+	attempt.CreateTime = time.Now()
+	s.attempts = append(s.attempts, &attempt)
+	return nil
+}
+
+func (s *UserStore) GetLoginAttempts(username string, page, size int64) ([]*models.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var matched []*models.LoginAttempt
+	for _, a := range s.attempts {
+		if a.Username == username {
+			matched = append(matched, a)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	start, end := paginate(len(matched), page, size)
+	return matched[start:end], nil
+}
+
+func (s *UserStore) SetTOTPSecret(userID int64, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.users[userID]; ok {
+		r.user.TOTPSecret = secret
+		r.user.TOTPEnabled = false
+	}
+	return nil
+}
+
+func (s *UserStore) EnableTOTP(userID int64, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.users[userID]; ok {
+		r.user.TOTPEnabled = true
+	}
+	codes := make(map[string]bool, len(codeHashes))
+	for _, h := range codeHashes {
+		codes[h] = false
+	}
+	s.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (s *UserStore) DisableTOTP(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.users[userID]; ok {
+		r.user.TOTPSecret = ""
+		r.user.TOTPEnabled = false
+	}
+	delete(s.recoveryCodes, userID)
+	return nil
+}
+
+func (s *UserStore) UseRecoveryCode(userID int64, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	used, ok := s.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return mysql.ErrorRecoveryCodeInvalid
+	}
+	s.recoveryCodes[userID][codeHash] = true
+	return nil
+}
+
+func (s *UserStore) GetUserEmail(id int64) (*models.UserEmail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.users[id]
+	if !ok {
+		return nil, mysql.ErrorUserNotExist
+	}
+	email := r.email
+	return &email, nil
+}
+
+func (s *UserStore) GetUserByEmail(email string) (*models.User, error) {
+	s.mu.RLock()
+	id, ok := s.byEmail[email]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, mysql.ErrorUserNotExist
+	}
+	return s.GetUserByID(id)
+}
+
+func (s *UserStore) SetUserEmail(id int64, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if owner, ok := s.byEmail[email]; ok && owner != id {
+		return mysql.ErrorEmailExist
+	}
+	r, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	delete(s.byEmail, r.email.Email)
+	r.email = models.UserEmail{Email: email}
+	s.byEmail[email] = id
+	return nil
+}
+
+func (s *UserStore) SetEmailVerified(id int64, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.users[id]
+	if !ok || r.email.Email != email {
+		return mysql.ErrorUserNotExist
+	}
+	r.email.Verified = true
+	return nil
+}
+
+func (s *UserStore) UpdatePassword(id int64, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.users[id]; ok {
+		r.user.Password = password
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VoteStore 帖子排序、投票以及karma的内存实现
+type VoteStore struct {
+	mu          sync.RWMutex
+	postTime    zset
+	postScore   zset
+	communities map[int64]zset
+	tags        map[string]zset // 标签 -> 帖子id
+	tagCounts   map[int64]zset  // 社区id -> 标签 -> 帖子数
+	voted       map[string]zset // 帖子id -> 用户id -> 投票方向
+	userVoted   map[string]zset // 用户id -> 帖子id -> 投票时间
+	karma       zset
+	ballots     map[int64]map[string][]int // 投票帖id -> 用户id或化名 -> 选项
+
+	// Now 当前时间, 测试中可以替换来模拟投票期过期
+	Now func() time.Time
+}
+
+func NewVoteStore() *VoteStore {
+	return &VoteStore{
+		postTime:    make(zset),
+		postScore:   make(zset),
+		communities: make(map[int64]zset),
+		tags:        make(map[string]zset),
+		tagCounts:   make(map[int64]zset),
+		voted:       make(map[string]zset),
+		userVoted:   make(map[string]zset),
+		karma:       make(zset),
+		ballots:     make(map[int64]map[string][]int),
+		Now:         time.Now,
+	}
+}
+
+func (s *VoteStore) CreatePost(p *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(p.ID, 10)
+	created := float64(p.CreateTime.Unix())
+	s.postScore[id] = created
+	s.postTime[id] = created
+	if s.communities[p.CommunityID] == nil {
+		s.communities[p.CommunityID] = make(zset)
+	}
+	s.communities[p.CommunityID][id] = 1
+	s.addPostTags(id, p.CommunityID, p.Tags)
+	return nil
+}
+
+func (s *VoteStore) addPostTags(postID string, communityID int64, tags []string) {
+	if s.tagCounts[communityID] == nil {
+		s.tagCounts[communityID] = make(zset)
+	}
+	for _, tag := range tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(zset)
+		}
+		s.tags[tag][postID] = 1
+		s.tagCounts[communityID][tag]++
+	}
+}
+
+func (s *VoteStore) UpdatePostTags(p *models.Post, oldTags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.FormatInt(p.ID, 10)
+	var added []string
+	for _, tag := range p.Tags {
+		if !slices.Contains(oldTags, tag) {
+			added = append(added, tag)
+		}
+	}
+	s.addPostTags(id, p.CommunityID, added)
+	for _, tag := range oldTags {
+		if slices.Contains(p.Tags, tag) {
+			continue
+		}
+		delete(s.tags[tag], id)
+		if s.tagCounts[p.CommunityID][tag]--; s.tagCounts[p.CommunityID][tag] <= 0 {
+			delete(s.tagCounts[p.CommunityID], tag)
+		}
+	}
+	return nil
+}
+
+func (s *VoteStore) GetCommunityTagCounts(communityID int64) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int64, len(s.tagCounts[communityID]))
+	for tag, n := range s.tagCounts[communityID] {
+		counts[tag] = int64(n)
+	}
+	return counts, nil
+}
+
+func (s *VoteStore) GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	order := s.postScore
+	if p.Order == models.OrderTime {
+		order = s.postTime
+	}
+	if p.Tag != "" {
+		order = order.intersect(s.tags[p.Tag])
+	}
+	ids := order.revRange()
+	start, end := paginate(len(ids), p.Page, p.Size)
+	return ids[start:end], nil
+}
+
+func (s *VoteStore) GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	order := s.postTime
+	if p.Order == models.OrderScore {
+		order = s.postScore
+	}
+	inter := order.intersect(s.communities[p.CommunityID])
+	if p.Tag != "" {
+		inter = inter.intersect(s.tags[p.Tag])
+	}
+	ids := inter.revRange()
+	start, end := paginate(len(ids), p.Page, p.Size)
+	return ids[start:end], nil
+}
+
+func (s *VoteStore) GetPostVoteList(ids []string) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		var n int64
+		for _, dir := range s.voted[id] {
+			switch dir {
+			case 1:
+				n++
+			case -1:
+				n--
+			}
+		}
+		data = append(data, n)
+	}
+	return data, nil
+}
+
+func (s *VoteStore) VoteForPost(voter models.Voter, post *models.Post, dir float64, rule models.VoteRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	postID := strconv.FormatInt(post.ID, 10)
+	authorID := strconv.FormatInt(post.AuthorID, 10)
+	userID := voter.ID
+	if float64(s.Now().Unix())-s.postTime[postID] > rule.Window.Seconds() {
+		return redis.ErrVoteTimeExpire
+	}
+
+	odir := s.voted[postID][userID]
+	if odir == dir {
+		return redis.ErrVoteRepeated
+	}
+	var op float64
+	if dir > odir {
+		op = 1
+	} else {
+		op = -1
+	}
+	s.postScore[postID] += op * math.Abs(dir-odir) * rule.ScorePerVote
+
+	if !voter.Self {
+		s.karma[authorID] += dir - odir
+	}
+
+	if s.voted[postID] == nil {
+		s.voted[postID] = make(zset)
+	}
+	if dir == 0 {
+		delete(s.voted[postID], userID)
+	} else {
+		s.voted[postID][userID] = dir
+	}
+	// 秘密投票不维护用户的投票索引
+	if post.SecretBallot {
+		return nil
+	}
+	if s.userVoted[userID] == nil {
+		s.userVoted[userID] = make(zset)
+	}
+	if dir == 0 {
+		delete(s.userVoted[userID], postID)
+	} else {
+		s.userVoted[userID][postID] = float64(s.Now().Unix())
+	}
+	return nil
+}
+
+func (s *VoteStore) GetPostVoteForUser(userID, postID string) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.voted[postID][userID], nil
+}
+
+func (s *VoteStore) GetPostVotesForUser(userIDs, postIDs []string) ([]float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data := make([]float64, 0, len(postIDs))
+	for i, id := range postIDs {
+		data = append(data, s.voted[id][userIDs[i]])
+	}
+	return data, nil
+}
+
+func (s *VoteStore) GetUserVotedPostIDs(userID string, page, size int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := s.userVoted[userID].revRange()
+	start, end := paginate(len(ids), page, size)
+	return ids[start:end], nil
+}
+
+func (s *VoteStore) GetUserKarma(userID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(s.karma[userID]), nil
+}
+
+func (s *VoteStore) GetUserKarmaList(userIDs []string) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		data = append(data, int64(s.karma[id]))
+	}
+	return data, nil
+}
+
+// GetPostScore 查询帖子的分数, 方便测试排序
+func (s *VoteStore) GetPostScore(postID string) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.postScore[postID]
+}
+
+func (s *VoteStore) NullifyVote(userID, postID, authorID string, scorePerVote float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir, ok := s.voted[postID][userID]
+	if !ok {
+		return nil
+	}
+	delete(s.voted[postID], userID)
+	delete(s.userVoted[userID], postID)
+
+	var net float64
+	for _, d := range s.voted[postID] {
+		net += d
+	}
+	s.postScore[postID] = s.postTime[postID] + net*scorePerVote
+	if authorID != userID {
+		s.karma[authorID] -= dir
+	}
+	return nil
+}
+
+func (s *VoteStore) CastPollBallot(postID int64, voter string, choices []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ballots[postID] == nil {
+		s.ballots[postID] = make(map[string][]int)
+	}
+	if _, ok := s.ballots[postID][voter]; ok {
+		return redis.ErrPollVoted
+	}
+	s.ballots[postID][voter] = append([]int{}, choices...)
+	return nil
+}
+
+func (s *VoteStore) GetPollTally(postID int64, options int) ([]int64, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make([]int64, options)
+	for _, choices := range s.ballots[postID] {
+		for _, c := range choices {
+			if c >= 0 && c < options {
+				counts[c]++
+			}
+		}
+	}
+	return counts, int64(len(s.ballots[postID])), nil
+}
+
+func (s *VoteStore) GetPollBallot(postID int64, voter string) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	choices, ok := s.ballots[postID][voter]
+	if !ok {
+		return nil, nil
+	}
+	return append([]int{}, choices...), nil
+}
@@ -7,28 +7,93 @@ import (
 	"go.uber.org/zap"
 )
 
-func GetCommunityList() (communityList []*models.Community, err error) {
+func (s *Store) GetCommunityList() (communityList []*models.Community, err error) {
 	sqlStr := `select community_id,community_name from community`
 
-	if err = db.Select(&communityList, sqlStr); err != nil {
+	if err = s.db.Select(&communityList, sqlStr); err != nil {
 		if err == sql.ErrNoRows {
-			zap.L().Warn("there is no community", zap.Error(err))
+			s.log.Warn("there is no community", zap.Error(err))
 			err = nil
 		}
 	}
 	return
 }
 
-func GetCommunityDetail(id int64) (communityDetail *models.CommunityDetail, err error) {
-	sqlStr := `select community_id,community_name,introduction, create_time
+func (s *Store) GetCommunityDetail(id int64) (communityDetail *models.CommunityDetail, err error) {
+	sqlStr := `select community_id,community_name,introduction, create_time,
+				vote_window, score_per_vote, downvote_disabled, min_account_age, min_karma, secret_ballot
 				from community
 				where community_id = ?`
 
 	communityDetail = new(models.CommunityDetail) // 需要分配内存
-	if err = db.Get(communityDetail, sqlStr, int64(id)); err != nil {
+	if err = s.db.Get(communityDetail, sqlStr, int64(id)); err != nil {
 		if err == sql.ErrNoRows {
 			err = ErrorInvalidID
 		}
 	}
 	return communityDetail, err
 }
+
+// CreateCommunity 创建社区, 社区id在现有最大id的基础上加1
+func (s *Store) CreateCommunity(name, introduction string) (communityDetail *models.CommunityDetail, err error) {
+	var count int64
+	if err = s.db.Get(&count, `select count(community_id) from community where community_name = ?`, name); err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrorCommunityExist
+	}
+
+	sqlStr := `insert into community(community_id, community_name, introduction)
+				select coalesce(max(community_id), 0) + 1, ?, ? from community`
+	if _, err = s.db.Exec(sqlStr, name, introduction); err != nil {
+		return nil, err
+	}
+
+	communityDetail = new(models.CommunityDetail)
+	err = s.db.Get(communityDetail, `select community_id,community_name,introduction, create_time,
+				vote_window, score_per_vote, downvote_disabled, min_account_age, min_karma, secret_ballot
+				from community
+				where community_name = ?`, name)
+	return
+}
+
+// FollowCommunity 关注社区, 重复关注不报错
+func (s *Store) FollowCommunity(userID, communityID int64) error {
+	_, err := s.db.Exec(`insert ignore into community_follow(user_id, community_id) values(?,?)`, userID, communityID)
+	return err
+}
+
+// UnfollowCommunity 取消关注社区
+func (s *Store) UnfollowCommunity(userID, communityID int64) error {
+	_, err := s.db.Exec(`delete from community_follow where user_id = ? and community_id = ?`, userID, communityID)
+	return err
+}
+
+// GetCommunityFollowers 查询关注社区的全部用户
+func (s *Store) GetCommunityFollowers(communityID int64) (userIDs []int64, err error) {
+	err = s.db.Select(&userIDs, `select user_id from community_follow where community_id = ?`, communityID)
+	return
+}
+
+// GetFollowedCommunities 查询用户关注的全部社区id
+func (s *Store) GetFollowedCommunities(userID int64) (communityIDs []int64, err error) {
+	err = s.db.Select(&communityIDs, `select community_id from community_follow where user_id = ? order by community_id`, userID)
+	return
+}
+
+// UpdateCommunityVoteRule 修改社区的投票规则
+func (s *Store) UpdateCommunityVoteRule(id int64, rule *models.CommunityVoteRule) error {
+	sqlStr := `update community set
+				vote_window = ?, score_per_vote = ?, downvote_disabled = ?, min_account_age = ?, min_karma = ?, secret_ballot = ?
+				where community_id = ?`
+	res, err := s.db.Exec(sqlStr, rule.VoteWindow, rule.ScorePerVote, rule.DownvoteDisabled, rule.MinAccountAge, rule.MinKarma, rule.SecretBallot, id)
+	if err != nil {
+		return err
+	}
+	// 规则没有变化时影响的行数也是0, 需要再确认社区是否存在
+	if n, _ := res.RowsAffected(); n == 0 {
+		_, err = s.GetCommunityDetail(id)
+	}
+	return err
+}
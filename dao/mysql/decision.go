@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// insertDecision 保存决策帖的规则和选项
+func insertDecision(tx *sqlx.Tx, postID int64, d *models.Decision) error {
+	sqlStr := `insert into decision(post_id, method, secret, close_time) values(?,?,?,?)`
+	if _, err := tx.Exec(sqlStr, postID, d.Method, d.Secret, d.CloseTime); err != nil {
+		return err
+	}
+	for i, text := range d.Options {
+		if _, err := tx.Exec(`insert into decision_option(post_id, option_id, text) values(?,?,?)`, postID, i, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillPostDecisions 批量查询决策帖的规则和选项
+func (s *Store) fillPostDecisions(posts []*models.Post) error {
+	var ids []int64
+	for _, p := range posts {
+		if p.Type == models.PostTypeDecision {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`select post_id, method, secret, close_time from decision where post_id in (?)`, ids)
+	if err != nil {
+		return err
+	}
+	var decisions []struct {
+		PostID    int64      `db:"post_id"`
+		Method    string     `db:"method"`
+		Secret    bool       `db:"secret"`
+		CloseTime *time.Time `db:"close_time"`
+	}
+	if err := s.db.Select(&decisions, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	query, args, err = sqlx.In(`select post_id, text from decision_option where post_id in (?) order by post_id, option_id`, ids)
+	if err != nil {
+		return err
+	}
+	var options []struct {
+		PostID int64  `db:"post_id"`
+		Text   string `db:"text"`
+	}
+	if err := s.db.Select(&options, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	data := make(map[int64]*models.Decision, len(decisions))
+	for _, d := range decisions {
+		data[d.PostID] = &models.Decision{
+			Method:    d.Method,
+			Secret:    d.Secret,
+			CloseTime: d.CloseTime,
+		}
+	}
+	for _, o := range options {
+		if d, ok := data[o.PostID]; ok {
+			d.Options = append(d.Options, o.Text)
+		}
+	}
+	for _, p := range posts {
+		if p.Type == models.PostTypeDecision {
+			p.Decision = data[p.ID]
+		}
+	}
+	return nil
+}
+
+// CloseDecision 提前截止决策帖的投票
+func (s *Store) CloseDecision(postID int64, closeTime time.Time) error {
+	res, err := s.db.Exec(`update decision set close_time = ? where post_id = ?`, closeTime, postID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorPostNotExist
+	}
+	return nil
+}
+
+// ballotRow 选票在数据库中的一行, 选项用逗号分隔
+type ballotRow struct {
+	PostID     int64     `db:"post_id"`
+	Voter      string    `db:"voter"`
+	Choices    string    `db:"choices"`
+	CreateTime time.Time `db:"create_time"`
+}
+
+func (r *ballotRow) ballot() *models.Ballot {
+	b := &models.Ballot{PostID: r.PostID, Voter: r.Voter, CreateTime: r.CreateTime}
+	for _, f := range strings.Split(r.Choices, ",") {
+		if c, err := strconv.Atoi(f); err == nil {
+			b.Choices = append(b.Choices, c)
+		}
+	}
+	return b
+}
+
+// InsertBallot 保存决策帖的选票, 每个投票人只能投一次, 重复投票返回ErrorBallotExist
+func (s *Store) InsertBallot(b *models.Ballot) error {
+	fields := make([]string, len(b.Choices))
+	for i, c := range b.Choices {
+		fields[i] = strconv.Itoa(c)
+	}
+	sqlStr := `insert into decision_ballot(post_id, voter, choices, create_time) values(?,?,?,?)`
+	_, err := s.db.Exec(sqlStr, b.PostID, b.Voter, strings.Join(fields, ","), b.CreateTime)
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return ErrorBallotExist
+	}
+	return err
+}
+
+// GetBallots 按投票时间查询决策帖的全部选票
+func (s *Store) GetBallots(postID int64) ([]*models.Ballot, error) {
+	sqlStr := `select post_id, voter, choices, create_time from decision_ballot
+				where post_id = ?
+				order by create_time, voter`
+	var rows []*ballotRow
+	if err := s.db.Select(&rows, sqlStr, postID); err != nil {
+		return nil, err
+	}
+	ballots := make([]*models.Ballot, len(rows))
+	for i, r := range rows {
+		ballots[i] = r.ballot()
+	}
+	return ballots, nil
+}
+
+// GetBallot 查询投票人在决策帖中的选票, 没有投票时返回nil
+func (s *Store) GetBallot(postID int64, voter string) (*models.Ballot, error) {
+	sqlStr := `select post_id, voter, choices, create_time from decision_ballot where post_id = ? and voter = ?`
+	row := new(ballotRow)
+	err := s.db.Get(row, sqlStr, postID, voter)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.ballot(), nil
+}
@@ -3,8 +3,17 @@ package mysql
 import "errors"
 
 var (
-	ErrorUserExist       = errors.New("用户已存在")
-	ErrorUserNotExist    = errors.New("用户不存在")
-	ErrorInvalidPassword = errors.New("用户名或密码错误")
-	ErrorInvalidID       = errors.New("无效的ID")
+	ErrorUserExist            = errors.New("用户已存在")
+	ErrorUserNotExist         = errors.New("用户不存在")
+	ErrorInvalidPassword      = errors.New("用户名或密码错误")
+	ErrorInvalidID            = errors.New("无效的ID")
+	ErrorPostNotExist         = errors.New("帖子不存在")
+	ErrorCommunityExist       = errors.New("社区已存在")
+	ErrorVoteFlagNotExist     = errors.New("审核记录不存在")
+	ErrorNotificationNotExist = errors.New("通知不存在")
+	ErrorEmailExist           = errors.New("邮箱已被使用")
+	ErrorTagExist             = errors.New("标签已存在")
+	ErrorTagNotExist          = errors.New("标签不存在")
+	ErrorBallotExist          = errors.New("已经投过票了")
+	ErrorPostPublished        = errors.New("帖子已经发布")
 )
@@ -0,0 +1,21 @@
+package mysql
+
+import "bluebell/models"
+
+// InsertLoginAttempt 记录一次登录尝试
+func (s *Store) InsertLoginAttempt(a *models.LoginAttempt) error {
+	sqlStr := `insert into login_attempt(user_id, username, ip, success, reason) values(?,?,?,?,?)`
+	_, err := s.db.Exec(sqlStr, a.UserID, a.Username, a.IP, a.Success, a.Reason)
+	return err
+}
+
+// GetLoginAttempts 按时间倒序查询某个用户名的登录记录
+func (s *Store) GetLoginAttempts(username string, page, size int64) (attempts []*models.LoginAttempt, err error) {
+	sqlStr := `select id, user_id, username, ip, success, reason, create_time
+				from login_attempt
+				where username = ?
+				order by id desc
+				limit ?,?`
+	err = s.db.Select(&attempts, sqlStr, username, (page-1)*size, size)
+	return
+}
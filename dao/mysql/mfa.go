@@ -0,0 +1,79 @@
+package mysql
+
+import "errors"
+
+// ErrorRecoveryCodeInvalid 恢复码不存在或已经使用
+var ErrorRecoveryCodeInvalid = errors.New("恢复码无效")
+
+// SetTOTPSecret 保存待激活的两步验证密钥
+func (s *Store) SetTOTPSecret(userID int64, secret string) error {
+	sqlStr := `update user set totp_secret = ?, totp_enabled = 0 where user_id = ?`
+	_, err := s.db.Exec(sqlStr, secret, userID)
+	return err
+}
+
+// EnableTOTP 开启两步验证并替换全部恢复码
+func (s *Store) EnableTOTP(userID int64, codeHashes []string) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(`update user set totp_enabled = 1 where user_id = ?`, userID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from recovery_code where user_id = ?`, userID); err != nil {
+		return
+	}
+	for _, h := range codeHashes {
+		if _, err = tx.Exec(`insert into recovery_code(user_id, code_hash) values(?,?)`, userID, h); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// DisableTOTP 关闭两步验证, 同时清除密钥和恢复码
+func (s *Store) DisableTOTP(userID int64) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(`update user set totp_secret = '', totp_enabled = 0 where user_id = ?`, userID); err != nil {
+		return
+	}
+	_, err = tx.Exec(`delete from recovery_code where user_id = ?`, userID)
+	return
+}
+
+// UseRecoveryCode 使用一个恢复码, 每个恢复码只能使用一次
+func (s *Store) UseRecoveryCode(userID int64, codeHash string) error {
+	sqlStr := `update recovery_code set used = 1 where user_id = ? and code_hash = ? and used = 0`
+	ret, err := s.db.Exec(sqlStr, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrorRecoveryCodeInvalid
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package mysql
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 迁移文件命名规则: <版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// ErrorSchemaOutdated 数据库版本落后于程序
+var ErrorSchemaOutdated = errors.New("数据库版本过旧, 请先执行 migrate up")
+
+// Migration 一个版本的数据库迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// loadMigrations 读取内嵌的迁移文件, 按版本号升序返回
+func loadMigrations() ([]*Migration, error) {
+	entries, err := migrationFS.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", name, err)
+		}
+		content, err := migrationFS.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ensureMigrationTable 创建记录迁移版本的表
+func (s *Store) ensureMigrationTable() error {
+	sqlStr := "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+		"`version` bigint(20) NOT NULL," +
+		"`name` varchar(128) COLLATE utf8mb4_general_ci NOT NULL," +
+		"`applied_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+		"PRIMARY KEY (`version`)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci"
+	_, err := s.db.Exec(sqlStr)
+	return err
+}
+
+// appliedMigrations 查询已经执行过的版本及执行时间
+func (s *Store) appliedMigrations() (map[int64]time.Time, error) {
+	if err := s.ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Version   int64     `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := s.db.Select(&rows, `select version, applied_at from schema_migrations`); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// MigrateUp 按顺序执行所有未执行的迁移, 返回本次执行的迁移
+func (s *Store) MigrateUp() ([]*Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := s.execStatements(m.Up); err != nil {
+			return done, fmt.Errorf("migrate up %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := s.db.Exec(`insert into schema_migrations(version, name) values(?,?)`, m.Version, m.Name); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown 回滚最近执行的steps个迁移
+func (s *Store) MigrateDown(steps int) ([]*Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %04d_%s has no down file", m.Version, m.Name)
+		}
+		if err := s.execStatements(m.Down); err != nil {
+			return done, fmt.Errorf("migrate down %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := s.db.Exec(`delete from schema_migrations where version = ?`, m.Version); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// GetMigrationStatus 查询每个迁移的执行状态
+func (s *Store) GetMigrationStatus() ([]*MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]*MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := applied[m.Version]
+		status = append(status, &MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+	return status, nil
+}
+
+// CheckSchemaVersion 检查数据库是否执行了程序内嵌的全部迁移
+func (s *Store) CheckSchemaVersion() error {
+	status, err := s.GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, st := range status {
+		if !st.Applied {
+			return fmt.Errorf("%w: missing %04d_%s", ErrorSchemaOutdated, st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// execStatements 逐条执行sql, 驱动默认不支持一次执行多条语句
+func (s *Store) execStatements(content string) error {
+	for _, stmt := range splitStatements(content) {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 按行尾的分号拆分sql语句, 忽略 -- 开头的注释行
+func splitStatements(content string) []string {
+	var (
+		stmts []string
+		buf   strings.Builder
+	)
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(buf.String()), ";"))
+			buf.Reset()
+		}
+	}
+	if s := strings.TrimSpace(buf.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
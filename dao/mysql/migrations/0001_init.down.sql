@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `post`;
+DROP TABLE IF EXISTS `community`;
+DROP TABLE IF EXISTS `user`;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `login_attempt`;
+ALTER TABLE `user` DROP COLUMN `role`;
@@ -0,0 +1,14 @@
+ALTER TABLE `user` ADD COLUMN `role` tinyint(4) NOT NULL DEFAULT '0' COMMENT '角色 0普通用户 1版主 2管理员' AFTER `gender`;
+
+CREATE TABLE `login_attempt` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '用户id, 用户不存在时为0',
+                        `username` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '尝试登录的用户名',
+                        `ip` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '客户端ip',
+                        `success` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否登录成功',
+                        `reason` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '结果原因',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                        PRIMARY KEY (`id`),
+                        KEY `idx_username` (`username`),
+                        KEY `idx_ip` (`ip`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `recovery_code`;
+ALTER TABLE `user` DROP COLUMN `totp_enabled`, DROP COLUMN `totp_secret`;
@@ -0,0 +1,14 @@
+ALTER TABLE `user`
+    ADD COLUMN `totp_secret` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '两步验证密钥' AFTER `role`,
+    ADD COLUMN `totp_enabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否开启两步验证' AFTER `totp_secret`;
+
+CREATE TABLE `recovery_code` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL COMMENT '用户id',
+                        `code_hash` char(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '恢复码的sha256',
+                        `used` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已使用',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                        `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_user_code` (`user_id`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
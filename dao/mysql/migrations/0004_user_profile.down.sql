@@ -0,0 +1 @@
+ALTER TABLE `user` DROP COLUMN `avatar`, DROP COLUMN `bio`, DROP COLUMN `display_name`;
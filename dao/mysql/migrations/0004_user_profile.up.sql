@@ -0,0 +1,4 @@
+ALTER TABLE `user`
+    ADD COLUMN `display_name` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '昵称' AFTER `gender`,
+    ADD COLUMN `bio` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '个人简介' AFTER `display_name`,
+    ADD COLUMN `avatar` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '头像地址' AFTER `bio`;
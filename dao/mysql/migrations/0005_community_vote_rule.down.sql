@@ -0,0 +1,6 @@
+ALTER TABLE `community`
+    DROP COLUMN `min_karma`,
+    DROP COLUMN `min_account_age`,
+    DROP COLUMN `downvote_disabled`,
+    DROP COLUMN `score_per_vote`,
+    DROP COLUMN `vote_window`;
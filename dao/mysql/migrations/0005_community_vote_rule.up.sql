@@ -0,0 +1,6 @@
+ALTER TABLE `community`
+    ADD COLUMN `vote_window` int NOT NULL DEFAULT 0 COMMENT '发帖后可以投票的时间(秒), 0表示使用全局配置',
+    ADD COLUMN `score_per_vote` double NOT NULL DEFAULT 0 COMMENT '每一票增加的分数, 0表示使用全局配置',
+    ADD COLUMN `downvote_disabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否禁止反对票',
+    ADD COLUMN `min_account_age` int NOT NULL DEFAULT 0 COMMENT '注册多久之后才能投票(秒)',
+    ADD COLUMN `min_karma` int NOT NULL DEFAULT 0 COMMENT '投票需要的最低karma';
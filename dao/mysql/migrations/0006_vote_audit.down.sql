@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `vote_flag`;
+DROP TABLE IF EXISTS `vote_record`;
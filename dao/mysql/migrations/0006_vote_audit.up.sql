@@ -0,0 +1,28 @@
+CREATE TABLE `vote_record` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `author_id` bigint(20) NOT NULL COMMENT '帖子作者id',
+                        `user_id` bigint(20) NOT NULL COMMENT '投票用户id',
+                        `direction` tinyint(4) NOT NULL COMMENT '投票方向 1赞成 -1反对 0取消',
+                        `ip` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '投票时的客户端ip',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '投票时间',
+                        PRIMARY KEY (`id`),
+                        KEY `idx_create_time` (`create_time`),
+                        KEY `idx_post_user` (`post_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `vote_flag` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `author_id` bigint(20) NOT NULL COMMENT '帖子作者id',
+                        `user_id` bigint(20) NOT NULL COMMENT '投票用户id',
+                        `reason` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '可疑原因',
+                        `detail` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '说明',
+                        `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '状态 0待审核 1已作废 2已忽略',
+                        `reviewer_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '审核的管理员id',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                        `review_time` timestamp NULL DEFAULT NULL COMMENT '审核时间',
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_vote_reason` (`post_id`, `user_id`, `reason`),
+                        KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `community_follow`;
+DROP TABLE IF EXISTS `notification_pref`;
+DROP TABLE IF EXISTS `notification`;
@@ -0,0 +1,30 @@
+CREATE TABLE `notification` (
+                        `id` bigint(20) NOT NULL AUTO_INCREMENT,
+                        `user_id` bigint(20) NOT NULL COMMENT '接收通知的用户id',
+                        `type` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '通知类型',
+                        `actor_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '触发通知的用户id, 系统通知为0',
+                        `post_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '相关的帖子id',
+                        `community_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '相关的社区id',
+                        `content` varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '通知内容',
+                        `dedupe_key` varchar(128) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT '去重标识, 同一用户相同的标识只通知一次',
+                        `is_read` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已读',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                        PRIMARY KEY (`id`),
+                        UNIQUE KEY `idx_user_dedupe` (`user_id`, `dedupe_key`),
+                        KEY `idx_user_read` (`user_id`, `is_read`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `notification_pref` (
+                        `user_id` bigint(20) NOT NULL COMMENT '用户id',
+                        `type` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '通知类型',
+                        `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否接收, 没有记录时默认接收',
+                        PRIMARY KEY (`user_id`, `type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `community_follow` (
+                        `user_id` bigint(20) NOT NULL COMMENT '用户id',
+                        `community_id` int(10) unsigned NOT NULL COMMENT '社区id',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关注时间',
+                        PRIMARY KEY (`user_id`, `community_id`),
+                        KEY `idx_community` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,4 @@
+UPDATE `user` SET `email` = NULL WHERE `email` = '';
+ALTER TABLE `user`
+    ADD COLUMN `email_verified` tinyint(1) NOT NULL DEFAULT '0' COMMENT '邮箱是否已验证' AFTER `email`,
+    ADD UNIQUE KEY `idx_email` (`email`);
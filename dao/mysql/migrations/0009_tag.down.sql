@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `community_tag`;
+DROP TABLE IF EXISTS `post_tag`;
@@ -0,0 +1,15 @@
+CREATE TABLE `post_tag` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `tag` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标签',
+                        PRIMARY KEY (`post_id`, `tag`),
+                        KEY `idx_tag` (`tag`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `community_tag` (
+                        `community_id` int(10) unsigned NOT NULL COMMENT '社区id',
+                        `name` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '标签名',
+                        `color` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '展示颜色, 例如#ff6600',
+                        `description` varchar(128) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '标签说明',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+                        PRIMARY KEY (`community_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
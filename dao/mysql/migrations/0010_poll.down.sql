@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `poll_option`;
+DROP TABLE IF EXISTS `poll`;
+ALTER TABLE `post` DROP COLUMN `type`;
@@ -0,0 +1,17 @@
+ALTER TABLE `post`
+    ADD COLUMN `type` tinyint(4) NOT NULL DEFAULT 0 COMMENT '帖子类型, 0普通帖子 1投票帖' AFTER `status`;
+
+CREATE TABLE `poll` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `multiple` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否可以多选',
+                        `hide_results` tinyint(1) NOT NULL DEFAULT 0 COMMENT '截止之前是否隐藏结果',
+                        `close_time` timestamp NULL DEFAULT NULL COMMENT '截止时间, 为空表示不截止',
+                        PRIMARY KEY (`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `poll_option` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `option_id` tinyint(4) NOT NULL COMMENT '选项序号, 从0开始',
+                        `text` varchar(128) COLLATE utf8mb4_general_ci NOT NULL COMMENT '选项内容',
+                        PRIMARY KEY (`post_id`, `option_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
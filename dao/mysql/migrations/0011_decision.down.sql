@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `decision_ballot`;
+DROP TABLE IF EXISTS `decision_option`;
+DROP TABLE IF EXISTS `decision`;
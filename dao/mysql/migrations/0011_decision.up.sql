@@ -0,0 +1,22 @@
+CREATE TABLE `decision` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `method` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT '计票方式, approval或irv',
+                        `secret` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否秘密投票, 审计记录中不公开投票人',
+                        `close_time` timestamp NULL DEFAULT NULL COMMENT '截止时间, 为空表示不截止',
+                        PRIMARY KEY (`post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `decision_option` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `option_id` tinyint(4) NOT NULL COMMENT '选项序号, 从0开始',
+                        `text` varchar(128) COLLATE utf8mb4_general_ci NOT NULL COMMENT '选项内容',
+                        PRIMARY KEY (`post_id`, `option_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `decision_ballot` (
+                        `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+                        `user_id` bigint(20) NOT NULL COMMENT '投票人',
+                        `choices` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT '选项序号, 逗号分隔; 排序投票按偏好顺序',
+                        `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '投票时间',
+                        PRIMARY KEY (`post_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
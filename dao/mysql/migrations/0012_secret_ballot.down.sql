@@ -0,0 +1,2 @@
+ALTER TABLE `community` DROP COLUMN `secret_ballot`;
+ALTER TABLE `post` DROP COLUMN `secret_ballot`;
@@ -0,0 +1,5 @@
+ALTER TABLE `post`
+    ADD COLUMN `secret_ballot` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否秘密投票, 投票记录只保存投票人的化名' AFTER `type`;
+
+ALTER TABLE `community`
+    ADD COLUMN `secret_ballot` tinyint(1) NOT NULL DEFAULT 0 COMMENT '社区的新帖子是否默认秘密投票';
@@ -0,0 +1,4 @@
+ALTER TABLE `post`
+    DROP KEY `idx_status_publish_time`,
+    DROP COLUMN `publish_time`,
+    MODIFY COLUMN `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态';
@@ -0,0 +1,4 @@
+ALTER TABLE `post`
+    MODIFY COLUMN `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态, 0草稿 1已发布 2定时发布',
+    ADD COLUMN `publish_time` timestamp NULL DEFAULT NULL COMMENT '定时发布的时间' AFTER `status`,
+    ADD KEY `idx_status_publish_time` (`status`, `publish_time`);
@@ -0,0 +1,3 @@
+ALTER TABLE `post`
+    DROP COLUMN `content_html`,
+    MODIFY COLUMN `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容';
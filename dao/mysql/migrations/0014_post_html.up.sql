@@ -0,0 +1,3 @@
+ALTER TABLE `post`
+    MODIFY COLUMN `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容, Markdown原文',
+    ADD COLUMN `content_html` mediumtext COLLATE utf8mb4_general_ci NOT NULL COMMENT '渲染并过滤之后的HTML, 为空时读取时渲染' AFTER `content`;
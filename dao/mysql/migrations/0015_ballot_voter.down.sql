@@ -0,0 +1,12 @@
+-- 秘密投票的选票无法还原成用户id, 回滚时删除
+DELETE FROM `decision_ballot` WHERE `voter` LIKE 's:%';
+
+ALTER TABLE `decision_ballot`
+    ADD COLUMN `user_id` bigint(20) NOT NULL DEFAULT 0 COMMENT '投票人' AFTER `post_id`;
+
+UPDATE `decision_ballot` SET `user_id` = CAST(`voter` AS UNSIGNED);
+
+ALTER TABLE `decision_ballot`
+    DROP PRIMARY KEY,
+    DROP COLUMN `voter`,
+    ADD PRIMARY KEY (`post_id`, `user_id`);
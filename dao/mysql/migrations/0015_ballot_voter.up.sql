@@ -0,0 +1,9 @@
+ALTER TABLE `decision_ballot`
+    ADD COLUMN `voter` varchar(40) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '投票人, 公开投票为用户id, 秘密投票为化名' AFTER `post_id`;
+
+UPDATE `decision_ballot` SET `voter` = CAST(`user_id` AS CHAR);
+
+ALTER TABLE `decision_ballot`
+    DROP PRIMARY KEY,
+    DROP COLUMN `user_id`,
+    ADD PRIMARY KEY (`post_id`, `voter`);
@@ -1,36 +1,46 @@
 package mysql
 
 import (
+	"bluebell/setting"
 	"fmt"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
-	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
-var db *sqlx.DB
+// Store MySQL数据访问对象, 持有自己的连接池
+type Store struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+// NewStore 使用已有的连接创建Store
+func NewStore(db *sqlx.DB, log *zap.Logger) *Store {
+	return &Store{db: db, log: log}
+}
 
 // Init 初始化MySQL连接
-func Init() (err error) {
+func Init(cfg setting.MySQLConfig, log *zap.Logger) (s *Store, err error) {
 	// "user:password@tcp(host:port)/dbname"
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&loc=Local",
-		viper.GetString("mysql.user"),
-		viper.GetString("mysql.password"),
-		viper.GetString("mysql.host"),
-		viper.GetInt("mysql.port"),
-		viper.GetString("mysql.db_name"),
+		cfg.User,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		cfg.DBName,
 	)
 
-	db, err = sqlx.Connect("mysql", dsn)
+	db, err := sqlx.Connect("mysql", dsn)
 	if err != nil {
 		return
 	}
-	db.SetMaxOpenConns(viper.GetInt("mysql.max_open_conns"))
-	db.SetMaxIdleConns(viper.GetInt("mysql.max_idle_conns"))
-	return
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	return NewStore(db, log), nil
 }
 
 // Close 关闭MySQL连接
-func Close() {
-	_ = db.Close()
+func (s *Store) Close() {
+	_ = s.db.Close()
 }
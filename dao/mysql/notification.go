@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"bluebell/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// InsertNotifications 批量写入通知, 去重标识重复的通知会被忽略
+func (s *Store) InsertNotifications(ns []*models.Notification) error {
+	if len(ns) == 0 {
+		return nil
+	}
+	sqlStr := `insert ignore into notification(user_id, type, actor_id, post_id, community_id, content, dedupe_key)
+				values(:user_id, :type, :actor_id, :post_id, :community_id, :content, :dedupe_key)`
+	_, err := s.db.NamedExec(sqlStr, ns)
+	return err
+}
+
+// GetNotifications 按时间倒序查询用户的通知
+func (s *Store) GetNotifications(userID int64, unreadOnly bool, page, size int64) (ns []*models.Notification, err error) {
+	sqlStr := `select id, user_id, type, actor_id, post_id, community_id, content, is_read, create_time
+				from notification
+				where user_id = ? and (? = 0 or is_read = 0)
+				order by id desc
+				limit ?,?`
+	err = s.db.Select(&ns, sqlStr, userID, unreadOnly, (page-1)*size, size)
+	return
+}
+
+// CountUnreadNotifications 查询用户的未读通知数量
+func (s *Store) CountUnreadNotifications(userID int64) (n int64, err error) {
+	err = s.db.Get(&n, `select count(id) from notification where user_id = ? and is_read = 0`, userID)
+	return
+}
+
+// MarkNotificationRead 把用户的一条通知标记为已读
+func (s *Store) MarkNotificationRead(userID, id int64) error {
+	var count int64
+	if err := s.db.Get(&count, `select count(id) from notification where id = ? and user_id = ?`, id, userID); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrorNotificationNotExist
+	}
+	_, err := s.db.Exec(`update notification set is_read = 1 where id = ? and user_id = ?`, id, userID)
+	return err
+}
+
+// MarkAllNotificationsRead 把用户的全部通知标记为已读
+func (s *Store) MarkAllNotificationsRead(userID int64) error {
+	_, err := s.db.Exec(`update notification set is_read = 1 where user_id = ? and is_read = 0`, userID)
+	return err
+}
+
+// GetNotificationPrefs 查询用户修改过的通知设置, 没有记录的类型默认接收
+func (s *Store) GetNotificationPrefs(userID int64) (map[string]bool, error) {
+	var rows []struct {
+		Type    string `db:"type"`
+		Enabled bool   `db:"enabled"`
+	}
+	if err := s.db.Select(&rows, `select type, enabled from notification_pref where user_id = ?`, userID); err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		prefs[r.Type] = r.Enabled
+	}
+	return prefs, nil
+}
+
+// SetNotificationPrefs 修改用户的通知设置
+func (s *Store) SetNotificationPrefs(userID int64, prefs map[string]bool) error {
+	sqlStr := `insert into notification_pref(user_id, type, enabled) values(?,?,?)
+				on duplicate key update enabled = values(enabled)`
+	for typ, enabled := range prefs {
+		if _, err := s.db.Exec(sqlStr, userID, typ, enabled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotificationDisabledUsers 在userIDs中找出关闭了某类通知的用户
+func (s *Store) GetNotificationDisabledUsers(typ string, userIDs []int64) (map[int64]bool, error) {
+	disabled := make(map[int64]bool)
+	if len(userIDs) == 0 {
+		return disabled, nil
+	}
+	query, args, err := sqlx.In(`select user_id from notification_pref where type = ? and enabled = 0 and user_id in (?)`, typ, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	if err := s.db.Select(&ids, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		disabled[id] = true
+	}
+	return disabled, nil
+}
+
+// GetNotificationEnabledUsers 查询主动开启了某类通知的全部用户, 用于默认关闭的通知类型
+func (s *Store) GetNotificationEnabledUsers(typ string) (userIDs []int64, err error) {
+	err = s.db.Select(&userIDs, `select user_id from notification_pref where type = ? and enabled = 1 order by user_id`, typ)
+	return
+}
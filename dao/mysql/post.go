@@ -2,38 +2,226 @@ package mysql
 
 import (
 	"bluebell/models"
+	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-func CreatePost(p *models.Post) error {
-	sqlStr := `insert into post(post_id, title, content, author_id, community_id, create_time) values(?,?,?,?,?,?)`
-	_, err := db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID, p.CreateTime)
-	return err
+// CreatePost 保存帖子及其标签, 投票帖和决策帖同时保存选项
+func (s *Store) CreatePost(p *models.Post) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	sqlStr := `insert into post(post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time) values(?,?,?,?,?,?,?,?,?,?,?)`
+	if _, err = tx.Exec(sqlStr, p.ID, p.Status, p.PublishTime, p.Type, p.SecretBallot, p.Title, p.Content, p.ContentHTML, p.AuthorID, p.CommunityID, p.CreateTime); err != nil {
+		return
+	}
+	for _, tag := range p.Tags {
+		if _, err = tx.Exec(`insert into post_tag(post_id, tag) values(?,?)`, p.ID, tag); err != nil {
+			return
+		}
+	}
+	if p.Poll != nil {
+		if err = insertPoll(tx, p.ID, p.Poll); err != nil {
+			return
+		}
+	}
+	if p.Decision != nil {
+		err = insertDecision(tx, p.ID, p.Decision)
+	}
+	return
+}
+
+// insertPoll 保存投票帖的规则和选项
+func insertPoll(tx *sqlx.Tx, postID int64, poll *models.Poll) error {
+	sqlStr := `insert into poll(post_id, multiple, hide_results, close_time) values(?,?,?,?)`
+	if _, err := tx.Exec(sqlStr, postID, poll.Multiple, poll.HideResults, poll.CloseTime); err != nil {
+		return err
+	}
+	for i, text := range poll.Options {
+		if _, err := tx.Exec(`insert into poll_option(post_id, option_id, text) values(?,?,?)`, postID, i, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPostTags 替换帖子的全部标签
+func (s *Store) SetPostTags(postID int64, tags []string) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err = tx.Exec(`delete from post_tag where post_id = ?`, postID); err != nil {
+		return
+	}
+	for _, tag := range tags {
+		if _, err = tx.Exec(`insert into post_tag(post_id, tag) values(?,?)`, postID, tag); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// fillPostTags 批量查询帖子的标签
+func (s *Store) fillPostTags(posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	query, args, err := sqlx.In(`select post_id, tag from post_tag where post_id in (?) order by tag`, ids)
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		PostID int64  `db:"post_id"`
+		Tag    string `db:"tag"`
+	}
+	if err := s.db.Select(&rows, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	tags := make(map[int64][]string, len(posts))
+	for _, r := range rows {
+		tags[r.PostID] = append(tags[r.PostID], r.Tag)
+	}
+	for _, p := range posts {
+		p.Tags = tags[p.ID]
+		if p.Tags == nil {
+			p.Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// fillPostPolls 批量查询投票帖的规则和选项
+func (s *Store) fillPostPolls(posts []*models.Post) error {
+	var ids []int64
+	for _, p := range posts {
+		if p.Type == models.PostTypePoll {
+			ids = append(ids, p.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`select post_id, multiple, hide_results, close_time from poll where post_id in (?)`, ids)
+	if err != nil {
+		return err
+	}
+	var polls []struct {
+		PostID      int64      `db:"post_id"`
+		Multiple    bool       `db:"multiple"`
+		HideResults bool       `db:"hide_results"`
+		CloseTime   *time.Time `db:"close_time"`
+	}
+	if err := s.db.Select(&polls, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	query, args, err = sqlx.In(`select post_id, text from poll_option where post_id in (?) order by post_id, option_id`, ids)
+	if err != nil {
+		return err
+	}
+	var options []struct {
+		PostID int64  `db:"post_id"`
+		Text   string `db:"text"`
+	}
+	if err := s.db.Select(&options, s.db.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	data := make(map[int64]*models.Poll, len(polls))
+	for _, p := range polls {
+		data[p.PostID] = &models.Poll{
+			Multiple:    p.Multiple,
+			HideResults: p.HideResults,
+			CloseTime:   p.CloseTime,
+		}
+	}
+	for _, o := range options {
+		if poll, ok := data[o.PostID]; ok {
+			poll.Options = append(poll.Options, o.Text)
+		}
+	}
+	for _, p := range posts {
+		if p.Type == models.PostTypePoll {
+			p.Poll = data[p.ID]
+		}
+	}
+	return nil
+}
+
+// fillPosts 批量填充帖子的标签和投票选项
+func (s *Store) fillPosts(posts []*models.Post) error {
+	if err := s.fillPostTags(posts); err != nil {
+		return err
+	}
+	if err := s.fillPostPolls(posts); err != nil {
+		return err
+	}
+	return s.fillPostDecisions(posts)
 }
 
 // GetPostByID 根据帖子id到数据库里面查找帖子的详细信息
-func GetPostByID(id int64) (data *models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time 
+func (s *Store) GetPostByID(id int64) (data *models.Post, err error) {
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time 
 				from post
 				where post_id = ?`
 	data = new(models.Post)
-	err = db.Get(data, sqlStr, id)
+	err = s.db.Get(data, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorPostNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = s.fillPosts([]*models.Post{data})
 	return
 }
 
-// GetPostList 获取所有帖子列表mysql
-func GetPostList(page int64, size int64) (posts []*models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time  from post
+// GetPostList 获取所有已发布的帖子列表mysql, 按post_id排序保证分页稳定, 重建缓存时不会漏掉或重复
+func (s *Store) GetPostList(page int64, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time  from post
+				where status = ?
+				order by post_id
 				limit ?,?`
-	err = db.Select(&posts, sqlStr, (page-1)*size, size)
+	if err = s.db.Select(&posts, sqlStr, models.PostStatusPublished, (page-1)*size, size); err != nil {
+		return nil, err
+	}
+	err = s.fillPosts(posts)
 	return
 }
 
 // GetPostListsByIDs 通过dis查询相应的帖子详情
-func GetPostListsByIDs(ids []string) (posts []*models.Post, err error) {
-	sqlStr := `select post_id, title, content, author_id, community_id, create_time  
+func (s *Store) GetPostListsByIDs(ids []string) (posts []*models.Post, err error) {
+	// 按标签筛选时经常没有结果, in查询不能传空列表
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time  
 				from post
 				where post_id in(?)
 				order by FIND_IN_SET(post_id, ?)`
@@ -42,7 +230,118 @@ func GetPostListsByIDs(ids []string) (posts []*models.Post, err error) {
 	if err != nil {
 		return nil, err
 	}
-	query = db.Rebind(query)
-	err = db.Select(&posts, query, args...)
+	query = s.db.Rebind(query)
+	if err = s.db.Select(&posts, query, args...); err != nil {
+		return nil, err
+	}
+	err = s.fillPosts(posts)
+	return
+}
+
+// GetPostListByAuthor 按发帖时间倒序查询某个用户已发布的帖子
+func (s *Store) GetPostListByAuthor(authorID, page, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time
+				from post
+				where author_id = ? and status = ?
+				order by create_time desc
+				limit ?,?`
+	if err = s.db.Select(&posts, sqlStr, authorID, models.PostStatusPublished, (page-1)*size, size); err != nil {
+		return nil, err
+	}
+	err = s.fillPosts(posts)
+	return
+}
+
+// GetDraftsByAuthor 按保存时间倒序查询用户的草稿和定时发布的帖子
+func (s *Store) GetDraftsByAuthor(authorID, page, size int64) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time
+				from post
+				where author_id = ? and status <> ?
+				order by create_time desc
+				limit ?,?`
+	if err = s.db.Select(&posts, sqlStr, authorID, models.PostStatusPublished, (page-1)*size, size); err != nil {
+		return nil, err
+	}
+	err = s.fillPosts(posts)
+	return
+}
+
+// GetDuePosts 查询发布时间已到的定时帖子
+func (s *Store) GetDuePosts(now time.Time) (posts []*models.Post, err error) {
+	sqlStr := `select post_id, status, publish_time, type, secret_ballot, title, content, content_html, author_id, community_id, create_time
+				from post
+				where status = ? and publish_time <= ?
+				order by publish_time`
+	if err = s.db.Select(&posts, sqlStr, models.PostStatusScheduled, now); err != nil {
+		return nil, err
+	}
+	err = s.fillPosts(posts)
+	return
+}
+
+// UpdateDraft 修改草稿的标题、内容、标签和发布计划, 帖子已经发布时返回ErrorPostPublished
+func (s *Store) UpdateDraft(p *models.Post) (err error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// 锁住帖子, 避免和定时发布同时修改
+	var status int32
+	err = tx.Get(&status, `select status from post where post_id = ? for update`, p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorPostNotExist
+	}
+	if err != nil {
+		return
+	}
+	if status == models.PostStatusPublished {
+		return ErrorPostPublished
+	}
+
+	sqlStr := `update post set status = ?, publish_time = ?, title = ?, content = ?, content_html = ?, create_time = ? where post_id = ?`
+	if _, err = tx.Exec(sqlStr, p.Status, p.PublishTime, p.Title, p.Content, p.ContentHTML, p.CreateTime, p.ID); err != nil {
+		return
+	}
+	if _, err = tx.Exec(`delete from post_tag where post_id = ?`, p.ID); err != nil {
+		return
+	}
+	for _, tag := range p.Tags {
+		if _, err = tx.Exec(`insert into post_tag(post_id, tag) values(?,?)`, p.ID, tag); err != nil {
+			return
+		}
+	}
 	return
 }
+
+// PublishPost 发布草稿或定时帖子, 发帖时间改为发布的时间
+// 只有一个调用方能发布成功, 帖子已经发布时返回ErrorPostPublished
+func (s *Store) PublishPost(postID int64, publishTime time.Time) error {
+	sqlStr := `update post set status = ?, create_time = ? where post_id = ? and status <> ?`
+	ret, err := s.db.Exec(sqlStr, models.PostStatusPublished, publishTime, postID, models.PostStatusPublished)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrorPostPublished
+	}
+	return nil
+}
+
+// UnpublishPost 发布失败时恢复帖子原来的状态和保存时间
+func (s *Store) UnpublishPost(p *models.Post) error {
+	sqlStr := `update post set status = ?, create_time = ? where post_id = ? and status = ?`
+	_, err := s.db.Exec(sqlStr, p.Status, p.CreateTime, p.ID, models.PostStatusPublished)
+	return err
+}
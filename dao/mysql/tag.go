@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"errors"
+
+	driver "github.com/go-sql-driver/mysql"
+)
+
+// GetCommunityTags 查询社区定义的全部标签
+func (s *Store) GetCommunityTags(communityID int64) (tags []*models.CommunityTag, err error) {
+	sqlStr := `select community_id, name, color, description from community_tag where community_id = ? order by name`
+	err = s.db.Select(&tags, sqlStr, communityID)
+	return
+}
+
+// CreateCommunityTag 给社区定义一个标签
+func (s *Store) CreateCommunityTag(tag *models.CommunityTag) error {
+	sqlStr := `insert into community_tag(community_id, name, color, description) values(?,?,?,?)`
+	_, err := s.db.Exec(sqlStr, tag.CommunityID, tag.Name, tag.Color, tag.Description)
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return ErrorTagExist
+	}
+	return err
+}
+
+// DeleteCommunityTag 删除社区定义的标签, 已经使用该标签的帖子不受影响
+func (s *Store) DeleteCommunityTag(communityID int64, name string) error {
+	res, err := s.db.Exec(`delete from community_tag where community_id = ? and name = ?`, communityID, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorTagNotExist
+	}
+	return nil
+}
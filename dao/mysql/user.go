@@ -7,17 +7,21 @@ import (
 	"encoding/hex"
 	"errors"
 
+	driver "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 )
 
 const secret = "khy"
 
-func CheckUserExist(username string) error {
+// errDupEntry 违反唯一索引时MySQL返回的错误码
+const errDupEntry = 1062
+
+func (s *Store) CheckUserExist(username string) error {
 	sqlStr := `select count(user_id) from user where username = ?`
 
 	var count int64
 
-	if err := db.Get(&count, sqlStr, username); err != nil {
+	if err := s.db.Get(&count, sqlStr, username); err != nil {
 		// 数据库查询错误, 返回
 		return err
 	}
@@ -29,25 +33,25 @@ func CheckUserExist(username string) error {
 }
 
 // InsertUser把注册的用户信息插入到数据库当中去
-func InsertUser(user *models.User) error {
+func (s *Store) InsertUser(user *models.User) error {
 	// 1 首先对用户密码加密
 	user.Password = encryptPassword(user.Password)
 
 	// 2 执行sql语句将user插入到数据库
 	sqlStr := `insert into user (user_id, username, password) values (?, ?, ?)`
-	_, err := db.Exec(sqlStr, user.UserID, user.Username, user.Password)
+	_, err := s.db.Exec(sqlStr, user.UserID, user.Username, user.Password)
 	return err
 
 }
 
 // Login检测用户输入的用户名和密码是否正确
-func Login(user *models.User) error {
+func (s *Store) Login(user *models.User) error {
 	oPassword := user.Password // 记录一下原始密码,与后面的数据库密码进行比较
 	Password := encryptPassword(oPassword)
 
-	sqlStr := `select user_id, username, password from user where username = ?`
-	if err := db.Get(user, sqlStr, user.Username); err != nil {
-		zap.L().Error("mysql.Query fail", zap.Error(err))
+	sqlStr := `select user_id, username, password, role, totp_secret, totp_enabled from user where username = ?`
+	if err := s.db.Get(user, sqlStr, user.Username); err != nil {
+		s.log.Error("mysql.Query fail", zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrorUserNotExist
 		}
@@ -63,10 +67,24 @@ func Login(user *models.User) error {
 }
 
 // GetUserByID 根据userID查询user
-func GetUserByID(id int64) (user *models.User, err error) {
-	sqlStr := `select user_id, username, password from user where user_id = ?`
+func (s *Store) GetUserByID(id int64) (user *models.User, err error) {
+	sqlStr := `select user_id, username, password, role, totp_secret, totp_enabled from user where user_id = ?`
+	user = new(models.User)
+	err = s.db.Get(user, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrorUserNotExist
+	}
+	return
+}
+
+// GetUserByUsername 根据用户名查询user
+func (s *Store) GetUserByUsername(username string) (user *models.User, err error) {
+	sqlStr := `select user_id, username, password, role, totp_secret, totp_enabled from user where username = ?`
 	user = new(models.User)
-	err = db.Get(user, sqlStr, id)
+	err = s.db.Get(user, sqlStr, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrorUserNotExist
+	}
 	return
 }
 
@@ -76,3 +94,97 @@ func encryptPassword(oPassword string) string {
 	h.Write([]byte(secret))
 	return hex.EncodeToString(h.Sum([]byte(oPassword)))
 }
+
+// GetUserProfile 根据userID查询用户的公开资料
+func (s *Store) GetUserProfile(id int64) (profile *models.UserProfile, err error) {
+	sqlStr := `select user_id, username, display_name, bio, avatar, create_time from user where user_id = ?`
+	profile = new(models.UserProfile)
+	err = s.db.Get(profile, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrorUserNotExist
+	}
+	return
+}
+
+// UpdateUserProfile 修改用户资料, 参数为nil的字段保持不变
+func (s *Store) UpdateUserProfile(id int64, p *models.ParamUpdateProfile) error {
+	sqlStr := `update user set
+				display_name = coalesce(?, display_name),
+				bio = coalesce(?, bio),
+				avatar = coalesce(?, avatar)
+				where user_id = ?`
+	_, err := s.db.Exec(sqlStr, p.DisplayName, p.Bio, p.Avatar, id)
+	return err
+}
+
+// SetUserRole 修改用户角色
+func (s *Store) SetUserRole(id int64, role int8) error {
+	sqlStr := `update user set role = ? where user_id = ?`
+	res, err := s.db.Exec(sqlStr, role, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorUserNotExist
+	}
+	return nil
+}
+
+// GetUserEmail 查询用户的邮箱及验证状态
+func (s *Store) GetUserEmail(id int64) (*models.UserEmail, error) {
+	sqlStr := `select coalesce(email, '') as email, email_verified from user where user_id = ?`
+	email := new(models.UserEmail)
+	err := s.db.Get(email, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorUserNotExist
+	}
+	return email, err
+}
+
+// GetUserByEmail 根据邮箱查询user
+func (s *Store) GetUserByEmail(email string) (user *models.User, err error) {
+	sqlStr := `select user_id, username, password, role, totp_secret, totp_enabled from user where email = ?`
+	user = new(models.User)
+	err = s.db.Get(user, sqlStr, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrorUserNotExist
+	}
+	return
+}
+
+// SetUserEmail 修改用户的邮箱, 修改后需要重新验证
+func (s *Store) SetUserEmail(id int64, email string) error {
+	sqlStr := `update user set email = ?, email_verified = 0 where user_id = ?`
+	_, err := s.db.Exec(sqlStr, email, id)
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return ErrorEmailExist
+	}
+	return err
+}
+
+// SetEmailVerified 把邮箱标记为已验证, 用户的邮箱已经改成其他邮箱时返回ErrorUserNotExist
+func (s *Store) SetEmailVerified(id int64, email string) error {
+	sqlStr := `update user set email_verified = 1 where user_id = ? and email = ?`
+	res, err := s.db.Exec(sqlStr, id, email)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// 已经验证过时affected rows也是0, 再确认一次
+		var verified bool
+		err := s.db.Get(&verified, `select email_verified from user where user_id = ? and email = ?`, id, email)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorUserNotExist
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdatePassword 修改用户密码
+func (s *Store) UpdatePassword(id int64, password string) error {
+	sqlStr := `update user set password = ? where user_id = ?`
+	_, err := s.db.Exec(sqlStr, encryptPassword(password), id)
+	return err
+}
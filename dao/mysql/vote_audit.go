@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// InsertVoteRecord 追加一条投票记录
+func (s *Store) InsertVoteRecord(r *models.VoteRecord) error {
+	sqlStr := `insert into vote_record(post_id, author_id, user_id, direction, ip, create_time) values(?,?,?,?,?,?)`
+	_, err := s.db.Exec(sqlStr, r.PostID, r.AuthorID, r.UserID, r.Direction, r.IP, r.CreateTime)
+	return err
+}
+
+// GetVoteRecordsSince 按时间顺序查询since之后的投票记录
+func (s *Store) GetVoteRecordsSince(since time.Time) (records []*models.VoteRecord, err error) {
+	sqlStr := `select id, post_id, author_id, user_id, direction, ip, create_time
+				from vote_record
+				where create_time >= ?
+				order by id`
+	err = s.db.Select(&records, sqlStr, since)
+	return
+}
+
+// InsertVoteFlags 批量写入可疑投票, 同一投票同一原因已经存在时忽略, 返回新写入的数量
+func (s *Store) InsertVoteFlags(flags []*models.VoteFlag) (n int64, err error) {
+	sqlStr := `insert ignore into vote_flag(post_id, author_id, user_id, reason, detail) values(?,?,?,?,?)`
+	for _, f := range flags {
+		ret, err := s.db.Exec(sqlStr, f.PostID, f.AuthorID, f.UserID, f.Reason, f.Detail)
+		if err != nil {
+			return n, err
+		}
+		affected, err := ret.RowsAffected()
+		if err != nil {
+			return n, err
+		}
+		n += affected
+	}
+	return n, nil
+}
+
+// GetVoteFlags 按时间倒序查询某个状态的可疑投票
+func (s *Store) GetVoteFlags(status int8, page, size int64) (flags []*models.VoteFlag, err error) {
+	sqlStr := `select id, post_id, author_id, user_id, reason, detail, status, reviewer_id, create_time, review_time
+				from vote_flag
+				where status = ?
+				order by id desc
+				limit ?,?`
+	err = s.db.Select(&flags, sqlStr, status, (page-1)*size, size)
+	return
+}
+
+// GetVoteFlagByID 根据id查询可疑投票
+func (s *Store) GetVoteFlagByID(id int64) (*models.VoteFlag, error) {
+	flag := new(models.VoteFlag)
+	sqlStr := `select id, post_id, author_id, user_id, reason, detail, status, reviewer_id, create_time, review_time
+				from vote_flag
+				where id = ?`
+	err := s.db.Get(flag, sqlStr, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrorVoteFlagNotExist
+	}
+	return flag, err
+}
+
+// ReviewVoteFlags 审核一次投票的全部待审核记录, 同一投票可能因为多个原因被标记
+func (s *Store) ReviewVoteFlags(postID, userID int64, status int8, reviewerID int64) error {
+	sqlStr := `update vote_flag set status = ?, reviewer_id = ?, review_time = now()
+				where post_id = ? and user_id = ? and status = ?`
+	_, err := s.db.Exec(sqlStr, status, reviewerID, postID, userID, models.VoteFlagPending)
+	return err
+}
+
+// IsVoteNullified 用户对帖子的投票是否被管理员作废过
+func (s *Store) IsVoteNullified(postID, userID int64) (bool, error) {
+	sqlStr := `select count(*) from vote_flag where post_id = ? and user_id = ? and status = ?`
+	var count int64
+	if err := s.db.Get(&count, sqlStr, postID, userID, models.VoteFlagNullified); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"bluebell/models"
+	"strconv"
+
+	"github.com/go-redis/redis"
+)
+
+// ClearPostCache 删除帖子排序、社区帖子、标签、karma和用户投票索引的缓存, 投票记录是唯一的数据来源需要保留
+func (s *Store) ClearPostCache() error {
+	keys := []string{
+		getRedisKey(KeyPostTimeZSet),
+		getRedisKey(KeyPostScoreZSet),
+		getRedisKey(KeyUserKarmaZSet),
+	}
+	for _, pattern := range []string{KeyCommunitySetPF, KeyUserVotedZSetPF, KeyTagSetPF} {
+		iter := s.client.Scan(0, getRedisKey(pattern)+"*", 100).Iterator()
+		for iter.Next() {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+	}
+	return s.client.Del(keys...).Err()
+}
+
+// RebuildPostCache 根据MySQL中的帖子和redis中的投票记录重建帖子排序、社区帖子、标签、作者的karma和用户投票索引
+// scorePerVote返回社区每一票的分数, authorVoter返回作者在帖子投票记录中的身份, 秘密投票时是作者的化名
+// 投票时间没有保存在投票记录中, 用户投票索引使用发帖时间代替, 秘密投票的帖子不建立用户投票索引
+func (s *Store) RebuildPostCache(posts []*models.Post, scorePerVote func(communityID int64) float64, authorVoter func(p *models.Post) string) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	// 1 读取每个帖子的投票记录
+	pipe := s.client.Pipeline()
+	votes := make([]*redis.ZSliceCmd, len(posts))
+	for i, p := range posts {
+		votes[i] = pipe.ZRangeWithScores(getRedisKey(KeyPostVotedZSetPF+strconv.FormatInt(p.ID, 10)), 0, -1)
+	}
+	if _, err := pipe.Exec(); err != nil && err != Nil {
+		return err
+	}
+
+	// 2 重新计算分数和karma, 给自己的帖子投票不计入karma
+	tx := s.client.TxPipeline()
+	karma := make(map[string]float64)
+	for i, p := range posts {
+		id := strconv.FormatInt(p.ID, 10)
+		authorID := strconv.FormatInt(p.AuthorID, 10)
+		author := authorVoter(p)
+		created := float64(p.CreateTime.Unix())
+
+		var net float64
+		for _, z := range votes[i].Val() {
+			net += z.Score
+			if z.Member.(string) != author {
+				karma[authorID] += z.Score
+			}
+			if !p.SecretBallot {
+				tx.ZAdd(getRedisKey(KeyUserVotedZSetPF+z.Member.(string)), redis.Z{Score: created, Member: id})
+			}
+		}
+		tx.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{Score: created, Member: id})
+		tx.ZAdd(getRedisKey(KeyPostScoreZSet), redis.Z{Score: created + net*scorePerVote(p.CommunityID), Member: id})
+		tx.ZAdd(getRedisKey(KeyCommunitySetPF+strconv.FormatInt(p.CommunityID, 10)), redis.Z{Score: 1, Member: id})
+		addPostTags(tx, p.ID, p.CommunityID, p.Tags)
+	}
+	for authorID, k := range karma {
+		tx.ZIncrBy(getRedisKey(KeyUserKarmaZSet), k, authorID)
+	}
+	_, err := tx.Exec()
+	return err
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"bluebell/models"
+	"encoding/json"
+
+	"go.uber.org/zap"
+)
+
+// PublishEvent 发布实时事件, 所有实例都会收到
+func (s *Store) PublishEvent(e *models.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(getRedisKey(KeyEventChannel), data).Err()
+}
+
+// SubscribeEvents 订阅实时事件, 调用返回的函数取消订阅之后事件的channel会被关闭
+func (s *Store) SubscribeEvents() (<-chan *models.Event, func() error, error) {
+	ps := s.client.Subscribe(getRedisKey(KeyEventChannel))
+	// 等待订阅成功, 之后发布的事件不会丢失
+	if _, err := ps.Receive(); err != nil {
+		ps.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan *models.Event, 100)
+	go func() {
+		defer close(ch)
+		for msg := range ps.Channel() {
+			e := new(models.Event)
+			if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+				s.log.Warn("invalid event", zap.String("payload", msg.Payload), zap.Error(err))
+				continue
+			}
+			ch <- e
+		}
+	}()
+	return ch, ps.Close, nil
+}
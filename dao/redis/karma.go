@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"github.com/go-redis/redis"
+)
+
+// GetUserKarma 获取用户的karma, 没有被投过票的用户为0
+func (s *Store) GetUserKarma(userID string) (int64, error) {
+	karma, err := s.client.ZScore(getRedisKey(KeyUserKarmaZSet), userID).Result()
+	if err == Nil {
+		return 0, nil
+	}
+	return int64(karma), err
+}
+
+// GetUserKarmaList 批量获取用户的karma
+func (s *Store) GetUserKarmaList(userIDs []string) (data []int64, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	pipe := s.client.Pipeline()
+	for _, id := range userIDs {
+		pipe.ZScore(getRedisKey(KeyUserKarmaZSet), id)
+	}
+	// 不存在的成员返回redis.Nil, 按0处理
+	cmders, err := pipe.Exec()
+	if err != nil && err != Nil {
+		return nil, err
+	}
+
+	data = make([]int64, 0, len(userIDs))
+	for _, cmder := range cmders {
+		data = append(data, int64(cmder.(*redis.FloatCmd).Val()))
+	}
+	return data, nil
+}
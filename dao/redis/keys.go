@@ -8,9 +8,31 @@ const (
 	Prefix             = "govote:"     // 项目key前缀
 	KeyPostTimeZSet    = "post:time"   // zset;贴子及发帖时间
 	KeyPostScoreZSet   = "post:score"  // zset;贴子及投票的分数
-	KeyPostVotedZSetPF = "post:voted:" // zset;记录用户及投票类型;参数是post id
+	KeyPostVotedZSetPF = "post:voted:" // zset;记录用户及投票类型, 秘密投票时成员是用户的化名;参数是post id
 
-	KeyCommunitySetPF = "community:" // zset;保存每个分区下帖子的id
+	KeyCommunitySetPF     = "community:"      // zset;保存每个分区下帖子的id
+	KeyCommunityTagZSetPF = "community:tags:" // zset;社区中每个标签的帖子数;参数是社区id
+	KeyTagSetPF           = "tag:"            // zset;带有该标签的帖子id, 分数固定为1;参数是标签
+
+	KeyPollBallotPF = "poll:ballot:" // hash;投票帖每个用户选择的选项, 逗号分隔, 秘密投票时字段是用户的化名;参数是post id
+	KeyPollTallyPF  = "poll:tally:"  // hash;投票帖每个选项的票数;参数是post id
+
+	KeyUserKarmaZSet   = "user:karma"  // zset;用户及其karma
+	KeyUserVotedZSetPF = "user:voted:" // zset;用户投过票的帖子及投票时间;参数是user id
+
+	KeyRateLimitPF = "ratelimit:" // zset;限流滑动窗口;参数是规则名和用户标识
+
+	KeyLoginFailAccountPF = "login:fail:account:" // string;账号登录失败次数;参数是用户名
+	KeyLoginFailIPPF      = "login:fail:ip:"      // string;ip登录失败次数;参数是ip
+	KeyLoginLockAccountPF = "login:lock:account:" // string;账号锁定标记;参数是用户名
+	KeyLoginLockIPPF      = "login:lock:ip:"      // string;ip锁定标记;参数是ip
+
+	KeyMailTokenPF = "mail:token:" // string;邮件链接中的一次性token, 值为token对应的数据;参数是用途和token的哈希
+
+	KeyEventChannel = "events" // pub/sub频道;帖子投票和新帖子的实时事件
+
+	KeyMFAUsedPF                  = "mfa:used:"                     // string;已使用的验证码时间步;参数是用户id和时间步
+	KeySettingRequireMFAModerator = "setting:require_mfa:moderator" // string;版主是否必须开启两步验证
 )
 
 // 给redis key加上前缀
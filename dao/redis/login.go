@@ -0,0 +1,91 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+// GetLoginLockTTL 查询账号或ip的锁定剩余时间, 未锁定时返回0
+func (s *Store) GetLoginLockTTL(username, ip string) (time.Duration, error) {
+	pipe := s.client.Pipeline()
+	accountTTL := pipe.TTL(getRedisKey(KeyLoginLockAccountPF + username))
+	ipTTL := pipe.TTL(getRedisKey(KeyLoginLockIPPF + ip))
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+
+	// key不存在时TTL返回负数
+	ttl := accountTTL.Val()
+	if ipTTL.Val() > ttl {
+		ttl = ipTTL.Val()
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, nil
+}
+
+// GetLoginFailures 获取账号和ip在窗口期内登录失败的次数
+func (s *Store) GetLoginFailures(username, ip string) (accountFails, ipFails int64, err error) {
+	vals, err := s.client.MGet(getRedisKey(KeyLoginFailAccountPF+username), getRedisKey(KeyLoginFailIPPF+ip)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	counts := make([]int64, len(vals))
+	for i, v := range vals {
+		// 不存在的key为nil
+		if str, ok := v.(string); ok {
+			if counts[i], err = strconv.ParseInt(str, 10, 64); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return counts[0], counts[1], nil
+}
+
+// IncrLoginFailures 记录一次登录失败, 返回账号和ip当前的失败次数
+func (s *Store) IncrLoginFailures(username, ip string, window time.Duration) (accountFails, ipFails int64, err error) {
+	accountKey := getRedisKey(KeyLoginFailAccountPF + username)
+	ipKey := getRedisKey(KeyLoginFailIPPF + ip)
+
+	pipe := s.client.TxPipeline()
+	accountIncr := pipe.Incr(accountKey)
+	pipe.Expire(accountKey, window)
+	ipIncr := pipe.Incr(ipKey)
+	pipe.Expire(ipKey, window)
+	if _, err = pipe.Exec(); err != nil {
+		return
+	}
+	return accountIncr.Val(), ipIncr.Val(), nil
+}
+
+// LockAccount 锁定账号一段时间
+func (s *Store) LockAccount(username string, d time.Duration) error {
+	return s.client.Set(getRedisKey(KeyLoginLockAccountPF+username), 1, d).Err()
+}
+
+// LockIP 锁定ip一段时间, 该ip在锁定期间无法登录任何账号
+func (s *Store) LockIP(ip string, d time.Duration) error {
+	return s.client.Set(getRedisKey(KeyLoginLockIPPF+ip), 1, d).Err()
+}
+
+// ClearLoginFailures 登录成功后清空账号的失败记录
+func (s *Store) ClearLoginFailures(username string) error {
+	return s.client.Del(getRedisKey(KeyLoginFailAccountPF + username)).Err()
+}
+
+// UnlockAccount 解除账号锁定并清空失败记录
+func (s *Store) UnlockAccount(username string) error {
+	return s.client.Del(
+		getRedisKey(KeyLoginLockAccountPF+username),
+		getRedisKey(KeyLoginFailAccountPF+username),
+	).Err()
+}
+
+// UnlockIP 解除ip锁定并清空失败记录
+func (s *Store) UnlockIP(ip string) error {
+	return s.client.Del(
+		getRedisKey(KeyLoginLockIPPF+ip),
+		getRedisKey(KeyLoginFailIPPF+ip),
+	).Err()
+}
@@ -0,0 +1,25 @@
+package redis
+
+import (
+	"time"
+)
+
+// SaveMailToken 保存邮件链接中的一次性token, 只保存token的哈希
+func (s *Store) SaveMailToken(purpose, tokenHash, value string, ttl time.Duration) error {
+	return s.client.Set(getRedisKey(KeyMailTokenPF+purpose+":"+tokenHash), value, ttl).Err()
+}
+
+// ConsumeMailToken 取出并删除token对应的数据, token不存在或已过期时返回空字符串
+func (s *Store) ConsumeMailToken(purpose, tokenHash string) (string, error) {
+	key := getRedisKey(KeyMailTokenPF + purpose + ":" + tokenHash)
+	pipe := s.client.TxPipeline()
+	get := pipe.Get(key)
+	pipe.Del(key)
+	if _, err := pipe.Exec(); err != nil && err != Nil {
+		return "", err
+	}
+	if get.Err() == Nil {
+		return "", nil
+	}
+	return get.Val(), get.Err()
+}
@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+// MarkTOTPStepUsed 标记某个时间步的验证码已经使用过, 防止验证码被重放
+// 返回false说明该验证码已经被使用
+func (s *Store) MarkTOTPStepUsed(userID, step int64, ttl time.Duration) (bool, error) {
+	key := getRedisKey(KeyMFAUsedPF + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(step, 10))
+	return s.client.SetNX(key, 1, ttl).Result()
+}
+
+// GetRequireMFAForModerators 查询是否要求版主必须开启两步验证
+func (s *Store) GetRequireMFAForModerators() (bool, error) {
+	v, err := s.client.Get(getRedisKey(KeySettingRequireMFAModerator)).Result()
+	if err == Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return v == "1", nil
+}
+
+// SetRequireMFAForModerators 设置是否要求版主必须开启两步验证
+func (s *Store) SetRequireMFAForModerators(require bool) error {
+	v := "0"
+	if require {
+		v = "1"
+	}
+	return s.client.Set(getRedisKey(KeySettingRequireMFAModerator), v, 0).Err()
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+// ErrPollVoted 每个用户只能给投票帖投一次票
+var ErrPollVoted = errors.New("已经投过票了")
+
+// pollBallotScript 记录选票并更新票数, 保证同一用户只能投一次
+// 返回0表示已经投过票
+var pollBallotScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+for i = 3, #ARGV do
+	redis.call('HINCRBY', KEYS[2], ARGV[i], 1)
+end
+return 1
+`)
+
+// CastPollBallot 给投票帖投票, choices是选项下标, 秘密投票时voter是投票人的化名
+func (s *Store) CastPollBallot(postID int64, voter string, choices []int) error {
+	id := strconv.FormatInt(postID, 10)
+	fields := make([]string, len(choices))
+	for i, c := range choices {
+		fields[i] = strconv.Itoa(c)
+	}
+	args := []interface{}{voter, strings.Join(fields, ",")}
+	for _, f := range fields {
+		args = append(args, f)
+	}
+
+	ok, err := pollBallotScript.Run(s.client, []string{
+		getRedisKey(KeyPollBallotPF + id),
+		getRedisKey(KeyPollTallyPF + id),
+	}, args...).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrPollVoted
+	}
+	return nil
+}
+
+// GetPollTally 查询投票帖每个选项的票数和投票人数
+func (s *Store) GetPollTally(postID int64, options int) ([]int64, int64, error) {
+	id := strconv.FormatInt(postID, 10)
+	pipe := s.client.Pipeline()
+	tally := pipe.HGetAll(getRedisKey(KeyPollTallyPF + id))
+	voters := pipe.HLen(getRedisKey(KeyPollBallotPF + id))
+	if _, err := pipe.Exec(); err != nil {
+		return nil, 0, err
+	}
+	counts := make([]int64, options)
+	for field, val := range tally.Val() {
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 0 || i >= options {
+			continue
+		}
+		counts[i], _ = strconv.ParseInt(val, 10, 64)
+	}
+	return counts, voters.Val(), nil
+}
+
+// GetPollBallot 查询用户在投票帖中选择的选项, 没有投票时返回nil
+func (s *Store) GetPollBallot(postID int64, voter string) ([]int, error) {
+	val, err := s.client.HGet(getRedisKey(KeyPollBallotPF+strconv.FormatInt(postID, 10)), voter).Result()
+	if err == Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseChoices(val), nil
+}
+
+// parseChoices 解析逗号分隔的选项下标
+func parseChoices(val string) []int {
+	var choices []int
+	for _, f := range strings.Split(val, ",") {
+		if c, err := strconv.Atoi(f); err == nil {
+			choices = append(choices, c)
+		}
+	}
+	return choices
+}
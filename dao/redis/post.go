@@ -2,6 +2,7 @@ package redis
 
 import (
 	"bluebell/models"
+	"slices"
 	"strconv"
 	"time"
 
@@ -9,26 +10,26 @@ import (
 )
 
 // GetIDsFromKey 根据key获得ids
-func GetIDsFromKey(key string, page, size int64) ([]string, error) {
+func (s *Store) GetIDsFromKey(key string, page, size int64) ([]string, error) {
 	start := (page - 1) * size
 	end := start + size - 1
 
-	return client.ZRevRange(key, start, end).Result()
+	return s.client.ZRevRange(key, start, end).Result()
 }
 
 // CreatePost 初始化redis中的帖子
-func CreatePost(p *models.Post) error {
+func (s *Store) CreatePost(p *models.Post) error {
 	// 封转成一个事务来做
-	pipe := client.TxPipeline()
+	pipe := s.client.TxPipeline()
 	// 初始化分数
 	pipe.ZAdd(getRedisKey(KeyPostScoreZSet), redis.Z{
 		Member: p.ID,
-		Score:  float64(time.Now().Unix()),
+		Score:  float64(p.CreateTime.Unix()),
 	})
 	// 初始化时间
 	pipe.ZAdd(getRedisKey(KeyPostTimeZSet), redis.Z{
 		Member: p.ID,
-		Score:  float64(time.Now().Unix()),
+		Score:  float64(p.CreateTime.Unix()),
 	})
 
 	// 把帖子的社区id加入到redis中去
@@ -36,23 +37,92 @@ func CreatePost(p *models.Post) error {
 		Member: p.ID,
 		Score:  1,
 	})
+	addPostTags(pipe, p.ID, p.CommunityID, p.Tags)
+	_, err := pipe.Exec()
+	return err
+}
+
+// UpdatePostTags 修改帖子的标签, oldTags为修改前的标签
+func (s *Store) UpdatePostTags(p *models.Post, oldTags []string) error {
+	var added, removed []string
+	for _, tag := range p.Tags {
+		if !slices.Contains(oldTags, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range oldTags {
+		if !slices.Contains(p.Tags, tag) {
+			removed = append(removed, tag)
+		}
+	}
+
+	pipe := s.client.TxPipeline()
+	addPostTags(pipe, p.ID, p.CommunityID, added)
+	countKey := getRedisKey(KeyCommunityTagZSetPF + strconv.FormatInt(p.CommunityID, 10))
+	for _, tag := range removed {
+		pipe.ZRem(getRedisKey(KeyTagSetPF+tag), p.ID)
+		pipe.ZIncrBy(countKey, -1, tag)
+	}
+	// 帖子数减到0的标签不再出现在统计中
+	pipe.ZRemRangeByScore(countKey, "-inf", "0")
+	_, err := pipe.Exec()
+	return err
+}
+
+// addPostTags 把帖子加入标签的集合并增加社区的标签计数
+func addPostTags(pipe redis.Pipeliner, postID, communityID int64, tags []string) {
+	countKey := getRedisKey(KeyCommunityTagZSetPF + strconv.FormatInt(communityID, 10))
+	for _, tag := range tags {
+		pipe.ZAdd(getRedisKey(KeyTagSetPF+tag), redis.Z{Member: postID, Score: 1})
+		pipe.ZIncrBy(countKey, 1, tag)
+	}
+}
+
+// GetCommunityTagCounts 查询社区中每个标签的帖子数
+func (s *Store) GetCommunityTagCounts(communityID int64) (map[string]int64, error) {
+	zs, err := s.client.ZRangeWithScores(getRedisKey(KeyCommunityTagZSetPF+strconv.FormatInt(communityID, 10)), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(zs))
+	for _, z := range zs {
+		counts[z.Member.(string)] = int64(z.Score)
+	}
+	return counts, nil
+}
+
+// intersectOrder 把排序的zset与社区、标签的集合取交集, 结果缓存60秒
+func (s *Store) intersectOrder(key string, keys ...string) error {
+	pipe := s.client.Pipeline()
+	pipe.ZInterStore(key, redis.ZStore{
+		Aggregate: "MAX",
+	}, keys...)
+	pipe.Expire(key, 60*time.Second)
 	_, err := pipe.Exec()
 	return err
 }
 
 // GetPostIDsInOrder根据指定顺序获取帖子列表
-func GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+func (s *Store) GetPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 	key := getRedisKey(KeyPostScoreZSet)
 	if p.Order == models.OrderTime {
 		key = getRedisKey(KeyPostTimeZSet)
 	}
 
-	return GetIDsFromKey(key, p.Page, p.Size)
+	// 按标签筛选
+	if p.Tag != "" {
+		orderKey := key
+		key = orderKey + ":tag:" + p.Tag
+		if err := s.intersectOrder(key, getRedisKey(KeyTagSetPF+p.Tag), orderKey); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetIDsFromKey(key, p.Page, p.Size)
 }
 
 // GetPostVoteList获取帖子的赞成票数
-func GetPostVoteList(ids []string) (data []int64, err error) {
-	pipe := client.Pipeline()
+func (s *Store) GetPostVoteList(ids []string) (data []int64, err error) {
+	pipe := s.client.Pipeline()
 
 	for _, id := range ids {
 		key := getRedisKey(KeyPostVotedZSetPF + id)
@@ -74,7 +144,7 @@ func GetPostVoteList(ids []string) (data []int64, err error) {
 }
 
 // GetCommunityPostIDsInOrder按社区获取帖子的ids
-func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+func (s *Store) GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 	orderKey := getRedisKey(KeyPostTimeZSet)
 	if p.Order == models.OrderScore {
 		orderKey = getRedisKey(KeyPostScoreZSet)
@@ -82,16 +152,15 @@ func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
 
 	cKey := getRedisKey(KeyCommunitySetPF + strconv.Itoa(int(p.CommunityID)))
 	key := orderKey + ":" + strconv.Itoa(int(p.CommunityID))
+	keys := []string{cKey, orderKey}
+	// 按标签筛选
+	if p.Tag != "" {
+		key += ":tag:" + p.Tag
+		keys = append(keys, getRedisKey(KeyTagSetPF+p.Tag))
+	}
 
-	pipe := client.Pipeline()
-	pipe.ZInterStore(key, redis.ZStore{
-		Aggregate: "MAX",
-	}, cKey, orderKey)
-	pipe.Expire(key, 60*time.Second)
-	_, err := pipe.Exec()
-	if err != nil {
+	if err := s.intersectOrder(key, keys...); err != nil {
 		return nil, err
 	}
-
-	return GetIDsFromKey(key, p.Page, p.Size)
+	return s.GetIDsFromKey(key, p.Page, p.Size)
 }
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// slidingWindowScript 滑动窗口限流脚本, 保证 清理-计数-写入 的原子性
+// 返回0表示放行, 否则返回还需要等待的毫秒数
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+if redis.call('ZCARD', key) < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local wait = tonumber(oldest[2]) + window - now
+if wait < 1 then
+	wait = 1
+end
+return wait
+`)
+
+// AllowRequest 判断某个标识在窗口期内的请求次数是否超过限制
+// 超过限制时返回需要等待的时间
+func (s *Store) AllowRequest(rule, id string, limit int64, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now().UnixMilli()
+	key := getRedisKey(KeyRateLimitPF + rule + ":" + id)
+	member := fmt.Sprintf("%d-%d", now, rand.Int63())
+
+	wait, err := slidingWindowScript.Run(s.client, []string{key},
+		now, window.Milliseconds(), limit, member).Int64()
+	if err != nil {
+		return false, 0, err
+	}
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
@@ -1,34 +1,45 @@
 package redis
 
 import (
+	"bluebell/setting"
 	"fmt"
 
 	"github.com/go-redis/redis"
-	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 var (
-	client *redis.Client
-	Nil    = redis.Nil
+	Nil = redis.Nil
 )
 
+// Store Redis数据访问对象, 持有自己的客户端
+type Store struct {
+	client *redis.Client
+	log    *zap.Logger
+}
+
+// NewStore 使用已有的客户端创建Store
+func NewStore(client *redis.Client, log *zap.Logger) *Store {
+	return &Store{client: client, log: log}
+}
+
 // Init 初始化连接
-func Init() (err error) {
-	client = redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", viper.GetString("redis.host"), viper.GetInt("redis.port")),
-		Password:     viper.GetString("redis.password"), // no password set
-		DB:           viper.GetInt("redis.db_name"),     // use default DB
-		PoolSize:     viper.GetInt("redis.pool_size"),
-		MinIdleConns: viper.GetInt("redis.min_idle_conns"),
+func Init(cfg setting.RedisConfig, log *zap.Logger) (*Store, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password:     cfg.Password, // no password set
+		DB:           cfg.DB,       // use default DB
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
 	})
 
-	_, err = client.Ping().Result()
-	if err != nil {
-		return err
+	if _, err := client.Ping().Result(); err != nil {
+		return nil, err
 	}
-	return nil
+	return NewStore(client, log), nil
 }
 
-func Close() {
-	_ = client.Close()
+// Close 关闭Redis连接
+func (s *Store) Close() {
+	_ = s.client.Close()
 }
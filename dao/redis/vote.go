@@ -1,35 +1,37 @@
 package redis
 
 import (
+	"bluebell/models"
 	"errors"
 	"math"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis"
 	"go.uber.org/zap"
 )
 
-const (
-	oneWeekInSeconds = 7 * 24 * 3600
-	scorePerVote     = 432 // 每一票值多少分
-)
-
 var (
 	ErrVoteTimeExpire = errors.New("投票时间已过")
 	ErrVoteRepeated   = errors.New("不允许重复投票")
 )
 
-// VoteForPost 为帖子投票
-func VoteForPost(userID, postID string, dir float64) error {
-	// 1 判断帖子投票限制,帖子一周之内才能投票
+// VoteForPost 为帖子投票, 同时更新帖子作者的karma, 成功后发布投票事件
+// 秘密投票的帖子只用化名记录投票, 不维护用户的投票索引
+func (s *Store) VoteForPost(voter models.Voter, post *models.Post, dir float64, rule models.VoteRule) error {
+	postID := strconv.FormatInt(post.ID, 10)
+	authorID := strconv.FormatInt(post.AuthorID, 10)
+	userID := voter.ID
 
-	PostTime := client.ZScore(getRedisKey(KeyPostTimeZSet), postID).Val()
-	if float64(time.Now().Unix())-PostTime > oneWeekInSeconds {
+	// 1 判断帖子投票限制,帖子发布一段时间之内才能投票
+
+	PostTime := s.client.ZScore(getRedisKey(KeyPostTimeZSet), postID).Val()
+	if float64(time.Now().Unix())-PostTime > rule.Window.Seconds() {
 		return ErrVoteTimeExpire
 	}
 
 	// 投票分数的计算
-	odir := client.ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Val()
+	odir := s.client.ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Val()
 	// 投票重复,返回错误
 	if odir == dir {
 		return ErrVoteRepeated
@@ -45,27 +47,123 @@ func VoteForPost(userID, postID string, dir float64) error {
 	// 2 记录投票数据
 	// 3 更新帖子分数
 	// 2和 3需要放到一个事物当中去执行
-	pipe := client.TxPipeline()
+	pipe := s.client.TxPipeline()
 	// 更新分数
-	pipe.ZIncrBy(getRedisKey(KeyPostScoreZSet), op*diff*scorePerVote, postID)
-	zap.L().Info("", zap.Float64("op", op), zap.Float64("diff", diff), zap.Float64("odir", odir),
-		zap.Float64("score", op*diff*scorePerVote),
+	score := pipe.ZIncrBy(getRedisKey(KeyPostScoreZSet), op*diff*rule.ScorePerVote, postID)
+	s.log.Info("", zap.Float64("op", op), zap.Float64("diff", diff), zap.Float64("odir", odir),
+		zap.Float64("score", op*diff*rule.ScorePerVote),
 	)
 
-	//更新投票情况
+	// 更新作者的karma, 给自己的帖子投票不计入
+	if !voter.Self {
+		pipe.ZIncrBy(getRedisKey(KeyUserKarmaZSet), dir-odir, authorID)
+	}
+
+	//更新投票情况, 同时维护用户投票记录的反向索引
 	if dir == 0 {
 		pipe.ZRem(getRedisKey(KeyPostVotedZSetPF+postID), userID)
+		if !post.SecretBallot {
+			pipe.ZRem(getRedisKey(KeyUserVotedZSetPF+userID), postID)
+		}
 	} else {
 		pipe.ZAdd(getRedisKey(KeyPostVotedZSetPF+postID), redis.Z{
 			Member: userID,
 			Score:  dir,
 		})
+		if !post.SecretBallot {
+			pipe.ZAdd(getRedisKey(KeyUserVotedZSetPF+userID), redis.Z{
+				Member: postID,
+				Score:  float64(time.Now().Unix()),
+			})
+		}
 	}
-	_, err := pipe.Exec()
-	return err
+	// 事务中的命令按顺序执行, 这里统计的是更新之后的票数
+	up := pipe.ZCount(getRedisKey(KeyPostVotedZSetPF+postID), "1", "1")
+	down := pipe.ZCount(getRedisKey(KeyPostVotedZSetPF+postID), "-1", "-1")
+	if _, err := pipe.Exec(); err != nil {
+		return err
+	}
+
+	// 投票已经成功, 发布失败只记录日志
+	err := s.PublishEvent(&models.Event{
+		Type:        models.EventVote,
+		PostID:      post.ID,
+		CommunityID: post.CommunityID,
+		VoteNum:     up.Val() - down.Val(),
+		Score:       score.Val(),
+	})
+	if err != nil {
+		s.log.Error("publish vote event failed", zap.Error(err))
+	}
+	return nil
+}
+
+// GetPostVoteForUser 获取用户对帖子的投票记录, 没有投过票时返回0
+func (s *Store) GetPostVoteForUser(userID, postID string) (float64, error) {
+	dir, err := s.client.ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Result()
+	if err == Nil {
+		return 0, nil
+	}
+	return dir, err
+}
+
+// GetPostVotesForUser 批量获取用户对帖子的投票记录, 没有投过票的帖子为0
+// userIDs与postIDs一一对应, 秘密投票的帖子使用用户在该帖子中的化名
+func (s *Store) GetPostVotesForUser(userIDs, postIDs []string) ([]float64, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(postIDs))
+	for i, id := range postIDs {
+		cmds[i] = pipe.ZScore(getRedisKey(KeyPostVotedZSetPF+id), userIDs[i])
+	}
+	if _, err := pipe.Exec(); err != nil && err != Nil {
+		return nil, err
+	}
+	data := make([]float64, len(postIDs))
+	for i, cmd := range cmds {
+		data[i] = cmd.Val()
+	}
+	return data, nil
 }
 
-// GetPostVoteForUser 获取用户对帖子的投票记录
-func GetPostVoteForUser(userID, postID string) (float64, error) {
-	return client.ZScore(getRedisKey(KeyPostVotedZSetPF+postID), userID).Result()
+// GetUserVotedPostIDs 按投票时间从新到旧分页获取用户投过票的帖子id
+func (s *Store) GetUserVotedPostIDs(userID string, page, size int64) ([]string, error) {
+	return s.GetIDsFromKey(getRedisKey(KeyUserVotedZSetPF+userID), page, size)
+}
+
+// NullifyVote 作废用户的投票, 不受投票窗口期限制
+// 删除投票记录后根据剩余的投票重新计算帖子分数, 同时撤销作者因这一票获得的karma
+func (s *Store) NullifyVote(userID, postID, authorID string, scorePerVote float64) error {
+	votedKey := getRedisKey(KeyPostVotedZSetPF + postID)
+	dir, err := s.client.ZScore(votedKey, userID).Result()
+	if err == Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	created, err := s.client.ZScore(getRedisKey(KeyPostTimeZSet), postID).Result()
+	if err != nil && err != Nil {
+		return err
+	}
+	votes, err := s.client.ZRangeWithScores(votedKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	var net float64
+	for _, z := range votes {
+		if z.Member.(string) != userID {
+			net += z.Score
+		}
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(votedKey, userID)
+	pipe.ZRem(getRedisKey(KeyUserVotedZSetPF+userID), postID)
+	pipe.ZAdd(getRedisKey(KeyPostScoreZSet), redis.Z{Score: created + net*scorePerVote, Member: postID})
+	if authorID != userID {
+		pipe.ZIncrBy(getRedisKey(KeyUserKarmaZSet), -dir, authorID)
+	}
+	_, err = pipe.Exec()
+	return err
 }
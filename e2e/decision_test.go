@@ -0,0 +1,70 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestDecisionPost(t *testing.T) {
+	h := newHarness(t)
+	aliceID, alice := h.signUpAndLogin("alice")
+	bobID, bob := h.signUpAndLogin("bob")
+	_, carol := h.signUpAndLogin("carol")
+
+	if _, resp := h.do(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "bad", "content": "bad", "community_id": 1,
+		"decision": map[string]interface{}{"method": "borda", "options": []string{"a", "b"}},
+	}); resp.Code != 1001 {
+		t.Fatalf("create decision with unknown method code = %d, want 1001", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "offsite", "content": "where to go", "community_id": 1,
+		"decision": map[string]interface{}{"method": "irv", "options": []string{"beach", "hills", "city"}},
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	base := "/api/v1/post/" + posts[0].ID
+
+	h.mustOK(http.MethodPost, base+"/ballot", alice, map[string][]int{"choices": {2, 1}}, nil)
+	h.mustOK(http.MethodPost, base+"/ballot", bob, map[string][]int{"choices": {1}}, nil)
+	h.mustOK(http.MethodPost, base+"/ballot", carol, map[string][]int{"choices": {0, 2}}, nil)
+	if _, resp := h.do(http.MethodPost, base+"/ballot", bob, map[string][]int{"choices": {0}}); resp.Code != 1036 {
+		t.Fatalf("second ballot code = %d, want 1036", resp.Code)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/post/"+posts[0].ID+"/poll", bob, map[string][]int{"choices": {0}}); resp.Code != 1034 {
+		t.Fatalf("poll vote on decision code = %d, want 1034", resp.Code)
+	}
+
+	// 第一轮三个选项各一票并列, 全部并列时不再淘汰
+	var tally models.ApiDecisionTally
+	h.mustOK(http.MethodGet, base+"/tally", bob, nil, &tally)
+	if tally.Ballots != 3 || len(tally.Rounds) != 1 || len(tally.Winners) != 3 || !slices.Equal(tally.MyBallot, []int{1}) {
+		t.Fatalf("tally = %+v", tally)
+	}
+
+	if _, resp := h.do(http.MethodGet, base+"/audit", "", nil); resp.Code != 1038 {
+		t.Fatalf("audit before close code = %d, want 1038", resp.Code)
+	}
+	if _, resp := h.do(http.MethodPost, base+"/close", bob, nil); resp.Code != 1012 {
+		t.Fatalf("close by other user code = %d, want 1012", resp.Code)
+	}
+	h.mustOK(http.MethodPost, base+"/close", alice, nil, nil)
+
+	w, _ := h.do(http.MethodGet, base+"/audit?download=1", "", nil)
+	if w.Header().Get("Content-Disposition") == "" {
+		t.Fatalf("audit download without Content-Disposition")
+	}
+	var audit models.ApiDecisionAudit
+	h.mustOK(http.MethodGet, base+"/audit", "", nil, &audit)
+	// 公开投票时审计记录包含投票人
+	var voters []string
+	for _, b := range audit.Ballots {
+		voters = append(voters, strconv.FormatInt(b.UserID, 10))
+	}
+	if len(audit.Ballots) != 3 || !slices.Contains(voters, aliceID) || !slices.Contains(voters, bobID) || audit.Digest == "" {
+		t.Fatalf("audit = %+v", audit)
+	}
+}
@@ -0,0 +1,123 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDraftAndScheduledPost(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "draft", "content": "draft", "community_id": 1, "draft": true,
+	}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "later", "content": "later", "community_id": 1,
+		"publish_time": time.Now().Add(time.Hour).Format(time.RFC3339),
+	}, nil)
+
+	var drafts []struct {
+		ID     string `json:"id"`
+		Title  string `json:"title"`
+		Status int32  `json:"status"`
+	}
+	h.mustOK(http.MethodGet, "/api/v1/me/drafts", alice, nil, &drafts)
+	if len(drafts) != 2 {
+		t.Fatalf("drafts = %+v", drafts)
+	}
+	var statuses []int32
+	for _, d := range drafts {
+		statuses = append(statuses, d.Status)
+	}
+	slices.Sort(statuses)
+	if !slices.Equal(statuses, []int32{models.PostStatusDraft, models.PostStatusScheduled}) {
+		t.Fatalf("draft statuses = %v", statuses)
+	}
+	var id string
+	for _, d := range drafts {
+		if d.Title == "draft" {
+			id = d.ID
+		}
+	}
+
+	// 未发布的帖子不在redis中, 其他用户看不到
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	if len(posts) != 0 {
+		t.Fatalf("posts before publish = %+v", posts)
+	}
+	if h.redis.Exists("govote:post:time") {
+		t.Fatal("unpublished post written to post:time")
+	}
+	if _, resp := h.do(http.MethodGet, "/api/v1/post/"+id, bob, nil); resp.Code != 1007 {
+		t.Fatalf("other user get draft code = %d, want 1007", resp.Code)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/post/"+id, alice, nil, nil)
+
+	if _, resp := h.do(http.MethodPut, "/api/v1/post/"+id+"/draft", alice, map[string]interface{}{
+		"title": "edited", "content": "edited", "publish_time": time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}); resp.Code != 1001 {
+		t.Fatalf("schedule in the past code = %d, want 1001", resp.Code)
+	}
+	h.mustOK(http.MethodPut, "/api/v1/post/"+id+"/draft", alice, map[string]interface{}{
+		"title": "edited", "content": "edited",
+	}, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/post/"+id+"/publish", bob, nil); resp.Code != 1007 {
+		t.Fatalf("other user publish code = %d, want 1007", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post/"+id+"/publish", alice, nil, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/post/"+id+"/publish", alice, nil); resp.Code != 1039 {
+		t.Fatalf("publish twice code = %d, want 1039", resp.Code)
+	}
+
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	if len(posts) != 1 || posts[0].ID != id || posts[0].Title != "edited" {
+		t.Fatalf("posts after publish = %+v", posts)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{"post_id": id, "direction": 1}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/me/drafts", alice, nil, &drafts)
+	if len(drafts) != 1 || drafts[0].Title != "later" {
+		t.Fatalf("drafts after publish = %+v", drafts)
+	}
+}
+
+func TestPublishDraftRedisFailure(t *testing.T) {
+	h := newHarness(t)
+	aliceID, alice := h.signUpAndLogin("alice")
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "draft", "content": "draft", "community_id": 1, "draft": true,
+	}, nil)
+	var drafts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/me/drafts", alice, nil, &drafts)
+	postID, _ := strconv.ParseInt(drafts[0].ID, 10, 64)
+	userID, _ := strconv.ParseInt(aliceID, 10, 64)
+
+	// post:time类型不对时写入redis失败, 帖子恢复成草稿, 之后可以再次发布
+	if err := h.redis.Set("govote:post:time", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.app.Service.PublishPost(postID, userID); err == nil {
+		t.Fatal("PublishPost succeeded while redis write failed")
+	}
+	h.redis.Del("govote:post:time")
+	post, err := h.posts.GetPostByID(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Status != models.PostStatusDraft {
+		t.Fatalf("post status after failed publish = %d, want draft", post.Status)
+	}
+
+	h.mustOK(http.MethodPost, "/api/v1/post/"+drafts[0].ID+"/publish", alice, nil, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	if len(posts) != 1 || posts[0].ID != drafts[0].ID {
+		t.Fatalf("posts after retry = %+v", posts)
+	}
+}
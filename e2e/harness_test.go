@@ -0,0 +1,165 @@
+// Package e2e 端到端的HTTP测试
+// 路由和controller使用真实实现, redis使用miniredis, mysql部分使用dao/memory的内存实现
+package e2e
+
+import (
+	"bluebell/app"
+	"bluebell/dao/memory"
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/router"
+	"bluebell/setting"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis"
+	"go.uber.org/zap"
+)
+
+// testConfig 测试使用config.test.yaml中的配置, 默认关闭限流
+func testConfig(t *testing.T) *setting.Config {
+	t.Helper()
+	cfg, err := setting.Load("../config/config.yaml", "test")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return cfg
+}
+
+// response 与controller.ResponseData对应, data保留原始json方便断言
+type response struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+type harness struct {
+	t       *testing.T
+	app     *app.App
+	handler http.Handler
+	redis   *miniredis.Miniredis
+	rds     *redis.Store
+	users   *memory.UserStore
+	posts   *memory.PostStore
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	return newHarnessWithConfig(t, testConfig(t))
+}
+
+// newHarnessWithConfig 使用指定配置创建一个独立的服务实例
+func newHarnessWithConfig(t *testing.T, cfg *setting.Config) *harness {
+	t.Helper()
+	log := zap.NewNop()
+	mr := miniredis.RunT(t)
+	rds := redis.NewStore(goredis.NewClient(&goredis.Options{Addr: mr.Addr()}), log)
+
+	users := memory.NewUserStore()
+	communities := memory.NewCommunityStore(
+		&models.CommunityDetail{ID: 1, Name: "Go", Introduction: "Golang"},
+		&models.CommunityDetail{ID: 2, Name: "leetcode", Introduction: "刷题刷题刷题"},
+	)
+	posts := memory.NewPostStore()
+	stores := logic.Stores{
+		Users:         users,
+		Posts:         posts,
+		Communities:   communities,
+		Votes:         rds,
+		Auth:          rds,
+		Audit:         memory.NewAuditStore(),
+		Events:        rds,
+		Notifications: memory.NewNotificationStore(),
+	}
+	a, err := app.NewWithStores(cfg, log, rds, stores)
+	if err != nil {
+		t.Fatalf("app.NewWithStores failed: %v", err)
+	}
+	t.Cleanup(a.Close)
+	stopEvents, err := a.ListenEvents()
+	if err != nil {
+		t.Fatalf("ListenEvents failed: %v", err)
+	}
+	t.Cleanup(stopEvents)
+
+	return &harness{
+		t:       t,
+		app:     a,
+		handler: router.SetupRouter(a),
+		redis:   mr,
+		rds:     rds,
+		users:   users,
+		posts:   posts,
+	}
+}
+
+// do 发送请求, body不为nil时编码成json, token不为空时带上Authorization头
+func (h *harness) do(method, path, token string, body interface{}) (*httptest.ResponseRecorder, *response) {
+	h.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	h.handler.ServeHTTP(w, req)
+
+	resp := new(response)
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		h.t.Fatalf("%s %s: invalid json %q: %v", method, path, w.Body.String(), err)
+	}
+	return w, resp
+}
+
+// mustOK 请求必须返回code 1000, 并把data解码到out
+func (h *harness) mustOK(method, path, token string, body, out interface{}) {
+	h.t.Helper()
+	w, resp := h.do(method, path, token, body)
+	if w.Code != http.StatusOK || resp.Code != 1000 {
+		h.t.Fatalf("%s %s: status %d, code %d, msg %q", method, path, w.Code, resp.Code, resp.Msg)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			h.t.Fatalf("%s %s: decode data %s: %v", method, path, resp.Data, err)
+		}
+	}
+}
+
+// signUpAndLogin 注册并登录, 返回user_id和token
+func (h *harness) signUpAndLogin(username string) (string, string) {
+	h.t.Helper()
+	h.mustOK(http.MethodPost, "/api/v1/signup", "", map[string]string{
+		"username":    username,
+		"password":    "123456",
+		"re_password": "123456",
+	}, nil)
+
+	var login struct {
+		UserID   string `json:"user_id"`
+		Username string `json:"username"`
+		Token    string `json:"token"`
+	}
+	h.mustOK(http.MethodPost, "/api/v1/login", "", map[string]string{
+		"username": username,
+		"password": "123456",
+	}, &login)
+	if login.UserID == "" || login.Token == "" || login.Username != username {
+		h.t.Fatalf("login %s returned %+v", username, login)
+	}
+	return login.UserID, login.Token
+}
@@ -0,0 +1,99 @@
+package e2e
+
+import (
+	"bluebell/loadgen"
+	"bluebell/seeder"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSeedAndLoadGen 生成模拟数据之后回放混合流量
+func TestSeedAndLoadGen(t *testing.T) {
+	h := newHarness(t)
+	svc := h.app.Service
+	res, err := seeder.New(svc.Stores, h.app.IDs, svc.CommunityVoteRule, time.Now).Run(seeder.Options{
+		Users:         5,
+		Posts:         30,
+		VotesPerPost:  2,
+		DownvoteRatio: 0.2,
+		TimeDist:      seeder.TimeRecent,
+		VoteDist:      seeder.VoteZipf,
+		Window:        svc.VoteRule().Window,
+		Seed:          1,
+	})
+	if err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if res.Users != 5 || res.Posts != 30 || res.Votes+res.SkippedVotes != 60 || res.Votes == 0 {
+		t.Fatalf("seed result = %+v", res)
+	}
+
+	// MySQL和Redis两边的数据一致, 通过接口能查到全部帖子和投票
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=score&size=100", "", nil, &posts)
+	if len(posts) != 30 {
+		t.Fatalf("got %d posts, want 30", len(posts))
+	}
+	var votes int64
+	for _, p := range posts {
+		votes += p.VoteNum
+	}
+	if votes == 0 {
+		t.Fatal("seeded posts have no upvotes")
+	}
+
+	// 每张票都有投票记录, 时间落在帖子的投票窗口期内
+	records, err := svc.Audit.GetVoteRecordsSince(time.Time{})
+	if err != nil {
+		t.Fatalf("GetVoteRecordsSince failed: %v", err)
+	}
+	if len(records) != res.Votes {
+		t.Fatalf("got %d vote records, want %d", len(records), res.Votes)
+	}
+	for _, r := range records {
+		post, err := h.posts.GetPostByID(r.PostID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.CreateTime.Before(post.CreateTime) || r.CreateTime.Sub(post.CreateTime) > svc.VoteRule().Window || r.CreateTime.After(time.Now()) {
+			t.Fatalf("vote record at %v outside window of post created at %v", r.CreateTime, post.CreateTime)
+		}
+	}
+
+	srv := httptest.NewServer(h.handler)
+	defer srv.Close()
+	usernames := make([]string, res.Users)
+	for i := range usernames {
+		usernames[i] = res.Prefix + strconv.Itoa(i)
+	}
+	report, err := loadgen.Run(loadgen.Options{
+		Target:      srv.URL,
+		Usernames:   usernames,
+		Password:    seeder.Password,
+		Concurrency: 4,
+		Requests:    200,
+		VoteRatio:   0.3,
+		Seed:        1,
+	})
+	if err != nil {
+		t.Fatalf("loadgen failed: %v", err)
+	}
+
+	total := 0
+	for _, op := range []string{loadgen.OpList, loadgen.OpDetail, loadgen.OpVote} {
+		s := report.Ops[op]
+		if s == nil || s.Count == 0 {
+			t.Fatalf("no %s request in report", op)
+		}
+		if s.Errors != 0 || s.P50 > s.P99 || s.P99 > s.Max {
+			t.Fatalf("%s stats = %+v", op, s)
+		}
+		total += s.Count
+	}
+	if total != 200 {
+		t.Fatalf("sent %d requests, want 200", total)
+	}
+}
@@ -0,0 +1,179 @@
+package e2e
+
+import (
+	"bluebell/pkg/mailer"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var tokenRe = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// newMailHarness 邮件发送到本地的捕获服务
+func newMailHarness(t *testing.T) (*harness, *mailer.CaptureServer) {
+	t.Helper()
+	srv, err := mailer.NewCaptureServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("NewCaptureServer failed: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	cfg := testConfig(t)
+	cfg.Mail.Enable = true
+	cfg.Mail.Host = "127.0.0.1"
+	cfg.Mail.Port = srv.Addr().Port
+	return newHarnessWithConfig(t, cfg), srv
+}
+
+// lastMail 最后一封发给to的邮件, 没有时测试失败
+func lastMail(t *testing.T, srv *mailer.CaptureServer, to string) *mailer.Message {
+	t.Helper()
+	msgs := srv.Messages()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if len(msgs[i].To) == 1 && msgs[i].To[0] == to {
+			msg, err := mailer.Parse(msgs[i].Data)
+			if err != nil {
+				t.Fatalf("parse mail: %v", err)
+			}
+			return msg
+		}
+	}
+	t.Fatalf("no mail sent to %s", to)
+	return nil
+}
+
+// mailToken 从邮件的链接中取出token
+func mailToken(t *testing.T, msg *mailer.Message) string {
+	t.Helper()
+	m := tokenRe.FindStringSubmatch(msg.Text)
+	if m == nil || !strings.Contains(msg.HTML, m[1]) {
+		t.Fatalf("no token link in mail %+v", msg)
+	}
+	return m[1]
+}
+
+func TestEmailVerification(t *testing.T) {
+	h, srv := newMailHarness(t)
+
+	h.mustOK(http.MethodPost, "/api/v1/signup", "", map[string]string{
+		"username": "alice", "password": "123456", "re_password": "123456", "email": "alice@example.com",
+	}, nil)
+	msg := lastMail(t, srv, "alice@example.com")
+	if msg.Subject != "验证你的邮箱" || !strings.Contains(msg.Text, "alice") {
+		t.Fatalf("verify mail = %+v", msg)
+	}
+	token := mailToken(t, msg)
+
+	// 同一个邮箱不能再注册
+	_, resp := h.do(http.MethodPost, "/api/v1/signup", "", map[string]string{
+		"username": "alice2", "password": "123456", "re_password": "123456", "email": "alice@example.com",
+	})
+	if resp.Code != 1027 {
+		t.Fatalf("signup with used email code = %d, want 1027", resp.Code)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	h.mustOK(http.MethodPost, "/api/v1/login", "", map[string]string{"username": "alice", "password": "123456"}, &login)
+	var me struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	h.mustOK(http.MethodGet, "/api/v1/me", login.Token, nil, &me)
+	if me.Email != "alice@example.com" || me.EmailVerified {
+		t.Fatalf("me before verify = %+v", me)
+	}
+
+	// 验证链接只能使用一次
+	h.mustOK(http.MethodPost, "/api/v1/email/verify", "", map[string]string{"token": token}, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/email/verify", "", map[string]string{"token": token}); resp.Code != 1028 {
+		t.Fatalf("reuse verify token code = %d, want 1028", resp.Code)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/me", login.Token, nil, &me)
+	if !me.EmailVerified {
+		t.Fatalf("me after verify = %+v", me)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/email/resend", login.Token, nil); resp.Code != 1030 {
+		t.Fatalf("resend for verified email code = %d, want 1030", resp.Code)
+	}
+
+	// 修改邮箱后需要重新验证
+	h.mustOK(http.MethodPut, "/api/v1/me/email", login.Token, map[string]string{"email": "alice@example.org"}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/me", login.Token, nil, &me)
+	if me.Email != "alice@example.org" || me.EmailVerified {
+		t.Fatalf("me after email change = %+v", me)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/email/verify", "", map[string]string{"token": mailToken(t, lastMail(t, srv, "alice@example.org"))}, nil)
+}
+
+func TestPasswordReset(t *testing.T) {
+	h, srv := newMailHarness(t)
+	_, token := h.signUpAndLogin("bob")
+	h.mustOK(http.MethodPut, "/api/v1/me/email", token, map[string]string{"email": "bob@example.com"}, nil)
+
+	// 未验证的邮箱和不存在的邮箱都返回成功, 但不发送邮件
+	before := len(srv.Messages())
+	h.mustOK(http.MethodPost, "/api/v1/password/forgot", "", map[string]string{"email": "bob@example.com"}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/password/forgot", "", map[string]string{"email": "nobody@example.com"}, nil)
+	h.app.Service.WaitMails()
+	if n := len(srv.Messages()); n != before {
+		t.Fatalf("%d mails sent for unverified or unknown email", n-before)
+	}
+
+	h.mustOK(http.MethodPost, "/api/v1/email/verify", "", map[string]string{"token": mailToken(t, lastMail(t, srv, "bob@example.com"))}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/password/forgot", "", map[string]string{"email": "bob@example.com"}, nil)
+	h.app.Service.WaitMails()
+	msg := lastMail(t, srv, "bob@example.com")
+	if msg.Subject != "重置密码" {
+		t.Fatalf("reset mail subject = %q", msg.Subject)
+	}
+	reset := mailToken(t, msg)
+
+	body := map[string]string{"token": reset, "password": "654321", "re_password": "654321"}
+	h.mustOK(http.MethodPost, "/api/v1/password/reset", "", body, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/password/reset", "", body); resp.Code != 1028 {
+		t.Fatalf("reuse reset token code = %d, want 1028", resp.Code)
+	}
+
+	if _, resp := h.do(http.MethodPost, "/api/v1/login", "", map[string]string{"username": "bob", "password": "123456"}); resp.Code != 1004 {
+		t.Fatalf("login with old password code = %d, want 1004", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/login", "", map[string]string{"username": "bob", "password": "654321"}, nil)
+}
+
+func TestEmailDigest(t *testing.T) {
+	h, srv := newMailHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+	h.mustOK(http.MethodPut, "/api/v1/me/email", alice, map[string]string{"email": "alice@example.com"}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/email/verify", "", map[string]string{"token": mailToken(t, lastMail(t, srv, "alice@example.com"))}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/community/1/follow", alice, nil, nil)
+
+	h.mustOK(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "go generics", "content": "hello", "community_id": 1,
+	}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "two sum", "content": "hello", "community_id": 2,
+	}, nil)
+
+	// 每周摘要默认关闭
+	var prefs map[string]bool
+	h.mustOK(http.MethodGet, "/api/v1/notifications/preferences", alice, nil, &prefs)
+	if prefs["email_digest"] {
+		t.Fatalf("email_digest enabled by default: %v", prefs)
+	}
+	if n, err := h.app.Service.SendDigest(); err != nil || n != 0 {
+		t.Fatalf("SendDigest before opt-in = %d, %v", n, err)
+	}
+
+	h.mustOK(http.MethodPut, "/api/v1/notifications/preferences", alice, map[string]bool{"email_digest": true}, nil)
+	if n, err := h.app.Service.SendDigest(); err != nil || n != 1 {
+		t.Fatalf("SendDigest = %d, %v, want 1 mail", n, err)
+	}
+	msg := lastMail(t, srv, "alice@example.com")
+	if !strings.Contains(msg.Text, "go generics") || strings.Contains(msg.Text, "two sum") || !strings.Contains(msg.HTML, "go generics") {
+		t.Fatalf("digest only contains followed communities, got %+v", msg)
+	}
+}
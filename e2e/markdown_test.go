@@ -0,0 +1,45 @@
+package e2e
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPostMarkdownFormat(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+
+	source := "# title\n\n[home](https://govote.test) <img src=x onerror=alert(1)>"
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "markdown", "content": source, "community_id": 1,
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	path := "/api/v1/post/" + posts[0].ID
+
+	var detail apiPost
+	h.mustOK(http.MethodGet, path, "", nil, &detail)
+	if detail.Content != source {
+		t.Fatalf("default format content = %q, want markdown source", detail.Content)
+	}
+	h.mustOK(http.MethodGet, path+"?format=html", "", nil, &detail)
+	if !strings.Contains(detail.Content, "<h1>title</h1>") || !strings.Contains(detail.Content, `rel="nofollow`) ||
+		strings.Contains(detail.Content, "onerror") {
+		t.Fatalf("html content = %q", detail.Content)
+	}
+	if _, resp := h.do(http.MethodGet, path+"?format=rtf", "", nil); resp.Code != 1001 {
+		t.Fatalf("unknown format code = %d, want 1001", resp.Code)
+	}
+
+	var preview struct {
+		ContentHTML string `json:"content_html"`
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post/preview", alice, map[string]string{"content": source}, &preview)
+	if preview.ContentHTML != detail.Content {
+		t.Fatalf("preview = %q, want %q", preview.ContentHTML, detail.Content)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/post/preview", "", map[string]string{"content": source}); resp.Code == 1000 {
+		t.Fatal("preview without login succeeded")
+	}
+}
@@ -0,0 +1,40 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/totp"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRequireMFAExistingToken 开启强制两步验证之后, 之前登录的版主必须先绑定才能继续使用
+func TestRequireMFAExistingToken(t *testing.T) {
+	h := newHarness(t)
+	modID, mod := h.signUpAndLogin("mod")
+	id, _ := strconv.ParseInt(modID, 10, 64)
+	if err := h.users.SetUserRole(id, models.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	_, alice := h.signUpAndLogin("alice")
+	h.mustOK(http.MethodGet, "/api/v1/me", mod, nil, nil)
+
+	if err := h.app.Service.SetRequireMFAForModerators(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := h.do(http.MethodGet, "/api/v1/me", mod, nil); resp.Code != 1016 {
+		t.Fatalf("moderator without 2fa code = %d, want 1016", resp.Code)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/me", alice, nil, nil)
+
+	// 旧token仍然可以绑定, 绑定之后恢复访问
+	var enroll models.ApiMFAEnroll
+	h.mustOK(http.MethodPost, "/api/v1/2fa/enroll", mod, nil, &enroll)
+	code, err := totp.Code(enroll.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/2fa/activate", mod, map[string]string{"code": code}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/me", mod, nil, nil)
+}
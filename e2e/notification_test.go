@@ -0,0 +1,89 @@
+package e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// apiNotifications 通知列表接口的json结构
+type apiNotifications struct {
+	Unread int64 `json:"unread"`
+	List   []struct {
+		ID      int64  `json:"id"`
+		Type    string `json:"type"`
+		ActorID string `json:"actor_id"`
+		PostID  string `json:"post_id"`
+		Content string `json:"content"`
+		IsRead  bool   `json:"is_read"`
+	} `json:"list"`
+}
+
+func TestNotifications(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	bobID, bob := h.signUpAndLogin("bob")
+	_, carol := h.signUpAndLogin("carol")
+
+	// alice和carol关注社区1
+	for _, token := range []string{alice, carol} {
+		h.mustOK(http.MethodPost, "/api/v1/community/1/follow", token, nil, nil)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/community/42/follow", alice, nil); resp.Code != 1001 {
+		t.Fatalf("follow missing community code = %d, want 1001", resp.Code)
+	}
+
+	// bob发帖@alice, alice只收到@通知, carol收到关注社区的通知
+	h.mustOK(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "hello", "content": "hi @alice", "community_id": 1,
+	}, nil)
+
+	var data apiNotifications
+	h.mustOK(http.MethodGet, "/api/v1/notifications", alice, nil, &data)
+	if data.Unread != 1 || len(data.List) != 1 || data.List[0].Type != "mention" || data.List[0].ActorID != bobID {
+		t.Fatalf("alice notifications = %+v", data)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/notifications", carol, nil, &data)
+	if data.Unread != 1 || len(data.List) != 1 || data.List[0].Type != "community_post" {
+		t.Fatalf("carol notifications = %+v", data)
+	}
+
+	// 标记已读, 不能标记别人的通知
+	id := strconv.FormatInt(data.List[0].ID, 10)
+	if _, resp := h.do(http.MethodPost, "/api/v1/notifications/"+id+"/read", alice, nil); resp.Code != 1026 {
+		t.Fatalf("read other's notification code = %d, want 1026", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/notifications/"+id+"/read", carol, nil, nil)
+	h.mustOK(http.MethodGet, "/api/v1/notifications?unread=true", carol, nil, &data)
+	if data.Unread != 0 || len(data.List) != 0 {
+		t.Fatalf("carol unread notifications = %+v", data)
+	}
+
+	// carol关闭关注社区的通知
+	var prefs map[string]bool
+	h.mustOK(http.MethodPut, "/api/v1/notifications/preferences", carol, map[string]bool{"community_post": false}, &prefs)
+	if prefs["community_post"] || !prefs["mention"] {
+		t.Fatalf("prefs = %v", prefs)
+	}
+	if _, resp := h.do(http.MethodPut, "/api/v1/notifications/preferences", carol, map[string]bool{"nope": true}); resp.Code != 1001 {
+		t.Fatalf("unknown pref code = %d, want 1001", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "second", "content": "second content", "community_id": 1,
+	}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/notifications", carol, nil, &data)
+	if data.Unread != 0 || len(data.List) != 1 {
+		t.Fatalf("carol notifications after disabling = %+v", data)
+	}
+
+	// alice全部标记已读
+	h.mustOK(http.MethodGet, "/api/v1/notifications", alice, nil, &data)
+	if data.Unread != 2 {
+		t.Fatalf("alice unread = %d, want 2", data.Unread)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/notifications/read_all", alice, nil, nil)
+	h.mustOK(http.MethodGet, "/api/v1/notifications", alice, nil, &data)
+	if data.Unread != 0 || !data.List[0].IsRead {
+		t.Fatalf("alice notifications after read all = %+v", data)
+	}
+}
@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPollPost(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+
+	if _, resp := h.do(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "bad", "content": "bad", "community_id": 1,
+		"poll": map[string]interface{}{"options": []string{"only"}},
+	}); resp.Code != 1001 {
+		t.Fatalf("create poll with one option code = %d, want 1001", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "editor", "content": "which editor", "community_id": 1,
+		"poll": map[string]interface{}{"options": []string{"vim", "emacs", "vscode"}},
+	}, nil)
+
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	if len(posts) != 1 {
+		t.Fatalf("posts = %+v", posts)
+	}
+	path := "/api/v1/post/" + posts[0].ID + "/poll"
+
+	// 多个用户同时投票, 每人只有一票生效
+	tokens := make([]string, 4)
+	for i := range tokens {
+		_, tokens[i] = h.signUpAndLogin("voter" + string(rune('a'+i)))
+	}
+	var wg sync.WaitGroup
+	codes := make([]int, 2*len(tokens))
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, resp := h.do(http.MethodPost, path, tokens[i/2], map[string][]int{"choices": {i % 3}})
+			codes[i] = resp.Code
+		}(i)
+	}
+	wg.Wait()
+	var ok, repeated int
+	for _, code := range codes {
+		switch code {
+		case 1000:
+			ok++
+		case 1036:
+			repeated++
+		}
+	}
+	if ok != len(tokens) || repeated != len(tokens) {
+		t.Fatalf("concurrent ballot codes = %v", codes)
+	}
+
+	var res models.ApiPollResult
+	h.mustOK(http.MethodGet, path, tokens[0], nil, &res)
+	var total int64
+	for _, c := range res.Counts {
+		total += c
+	}
+	if res.Voters != 4 || total != 4 || len(res.MyChoices) != 1 {
+		t.Fatalf("poll result = %+v", res)
+	}
+
+	var detail struct {
+		Type       int8                  `json:"type"`
+		Poll       *models.Poll          `json:"poll"`
+		PollResult *models.ApiPollResult `json:"poll_result"`
+	}
+	h.mustOK(http.MethodGet, "/api/v1/post/"+posts[0].ID, "", nil, &detail)
+	if detail.Type != models.PostTypePoll || detail.Poll == nil || !slices.Equal(detail.Poll.Options, []string{"vim", "emacs", "vscode"}) ||
+		detail.PollResult == nil || detail.PollResult.Voters != 4 {
+		t.Fatalf("poll post detail = %+v", detail)
+	}
+
+	if _, resp := h.do(http.MethodPost, path, alice, map[string][]int{"choices": {0, 1}}); resp.Code != 1001 {
+		t.Fatalf("two choices in single poll code = %d, want 1001", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "plain", "content": "plain", "community_id": 1,
+	}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	if _, resp := h.do(http.MethodGet, "/api/v1/post/"+posts[0].ID+"/poll", "", nil); resp.Code != 1034 {
+		t.Fatalf("plain post poll code = %d, want 1034", resp.Code)
+	}
+}
@@ -0,0 +1,341 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"bluebell/setting"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// apiPost 帖子列表和详情接口的json结构
+type apiPost struct {
+	ID          string   `json:"id"`
+	AuthorID    string   `json:"author_id"`
+	AuthorName  string   `json:"author_name"`
+	AuthorKarma int64    `json:"author_karma"`
+	CommunityID int64    `json:"community_id"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+	VoteNum     int64    `json:"vote_num"`
+	VoteStatus  int32    `json:"vote_status"`
+	Community   struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"community"`
+}
+
+func TestPostAndVoteScenario(t *testing.T) {
+	h := newHarness(t)
+	aliceID, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+
+	// 1 alice发两个帖子
+	for _, title := range []string{"older", "newer"} {
+		h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+			"title":        title,
+			"content":      title + " content",
+			"community_id": 1,
+		}, nil)
+	}
+
+	var byTime []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=time", "", nil, &byTime)
+	if len(byTime) != 2 || byTime[0].Title != "newer" || byTime[1].Title != "older" {
+		t.Fatalf("posts by time = %+v", byTime)
+	}
+	older := byTime[1]
+	if older.AuthorID != aliceID || older.AuthorName != "alice" || older.Community.Name != "Go" {
+		t.Fatalf("post contract = %+v", older)
+	}
+
+	// 2 bob给旧帖子投赞成票, 旧帖子按分数排到前面
+	h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{
+		"post_id":   older.ID,
+		"direction": 1,
+	}, nil)
+
+	var byScore []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=score", "", nil, &byScore)
+	if len(byScore) != 2 || byScore[0].ID != older.ID || byScore[0].VoteNum != 1 || byScore[0].AuthorKarma != 1 {
+		t.Fatalf("posts by score = %+v", byScore)
+	}
+
+	// 3 重复投票
+	_, resp := h.do(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{
+		"post_id":   older.ID,
+		"direction": 1,
+	})
+	if resp.Code != 1005 {
+		t.Fatalf("repeated vote code = %d, want 1005", resp.Code)
+	}
+
+	// 4 详情中带上当前用户的投票状态
+	var detail apiPost
+	h.mustOK(http.MethodGet, "/api/v1/post/"+older.ID, bob, nil, &detail)
+	if detail.VoteStatus != 1 || detail.VoteNum != 1 {
+		t.Fatalf("post detail = %+v", detail)
+	}
+
+	// 5 用户主页
+	var profile struct {
+		UserID   string    `json:"user_id"`
+		Username string    `json:"username"`
+		Karma    int64     `json:"karma"`
+		Posts    []apiPost `json:"posts"`
+	}
+	h.mustOK(http.MethodGet, "/api/v1/user/"+aliceID, "", nil, &profile)
+	if profile.Username != "alice" || profile.Karma != 1 || len(profile.Posts) != 2 {
+		t.Fatalf("user detail = %+v", profile)
+	}
+}
+
+func TestMyVotes(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+
+	for _, title := range []string{"first", "second", "third"} {
+		h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+			"title":        title,
+			"content":      title + " content",
+			"community_id": 1,
+		}, nil)
+	}
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=time", "", nil, &posts)
+	third, second, first := posts[0], posts[1], posts[2]
+
+	// bob赞成first, 反对second, 给third投票后又取消
+	for _, v := range []struct {
+		id  string
+		dir int
+	}{{first.ID, 1}, {second.ID, -1}, {third.ID, 1}, {third.ID, 0}} {
+		h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{
+			"post_id":   v.id,
+			"direction": v.dir,
+		}, nil)
+	}
+
+	var votes []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/me/votes", bob, nil, &votes)
+	if len(votes) != 2 {
+		t.Fatalf("my votes = %+v", votes)
+	}
+	status := map[string]int32{}
+	for _, p := range votes {
+		status[p.ID] = p.VoteStatus
+	}
+	if status[first.ID] != 1 || status[second.ID] != -1 {
+		t.Fatalf("my votes status = %v", status)
+	}
+
+	// 分页
+	h.mustOK(http.MethodGet, "/api/v1/me/votes?page=2&size=1", bob, nil, &votes)
+	if len(votes) != 1 {
+		t.Fatalf("my votes page 2 = %+v", votes)
+	}
+
+	// 列表接口带token时返回投票状态, 不带token时为0
+	for _, path := range []string{"/api/v1/posts2?order=time", "/api/v1/posts"} {
+		h.mustOK(http.MethodGet, path, bob, nil, &posts)
+		status = map[string]int32{}
+		for _, p := range posts {
+			status[p.ID] = p.VoteStatus
+		}
+		if status[first.ID] != 1 || status[second.ID] != -1 || status[third.ID] != 0 {
+			t.Fatalf("%s vote status = %v", path, status)
+		}
+		h.mustOK(http.MethodGet, path, "", nil, &posts)
+		for _, p := range posts {
+			if p.VoteStatus != 0 {
+				t.Fatalf("%s anonymous vote status = %+v", path, p)
+			}
+		}
+	}
+
+	// 未登录
+	_, resp := h.do(http.MethodGet, "/api/v1/me/votes", "", nil)
+	if resp.Code != 1008 {
+		t.Fatalf("my votes without token code = %d, want 1008", resp.Code)
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	h := newHarness(t)
+	h.signUpAndLogin("alice")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		code   int
+	}{
+		{"wrong password", http.MethodPost, "/api/v1/login", "", map[string]string{"username": "alice", "password": "bad"}, 1004},
+		{"unknown user looks the same", http.MethodPost, "/api/v1/login", "", map[string]string{"username": "nobody", "password": "bad"}, 1004},
+		{"invalid param", http.MethodPost, "/api/v1/login", "", map[string]string{"username": "alice"}, 1001},
+		{"need login", http.MethodPost, "/api/v1/post", "", map[string]interface{}{"title": "t", "content": "c", "community_id": 1}, 1008},
+		{"invalid token", http.MethodPost, "/api/v1/vote", "not-a-token", map[string]interface{}{"post_id": "1", "direction": 1}, 1009},
+		{"post not exist", http.MethodGet, "/api/v1/post/42", "", nil, 1007},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := h.do(tt.method, tt.path, tt.token, tt.body)
+			if resp.Code != tt.code {
+				t.Fatalf("code = %d (%s), want %d", resp.Code, resp.Msg, tt.code)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RateLimit = setting.RateLimitConfig{
+		Enable: true,
+		Rules: map[string]setting.RateLimitRule{
+			"login": {Limit: 2, Window: 60, Key: "ip"},
+		},
+	}
+
+	h := newHarnessWithConfig(t, cfg)
+	body := map[string]string{"username": "nobody", "password": "bad"}
+	for i := 0; i < 2; i++ {
+		if w, _ := h.do(http.MethodPost, "/api/v1/login", "", body); w.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d", i, w.Code)
+		}
+	}
+
+	w, resp := h.do(http.MethodPost, "/api/v1/login", "", body)
+	if w.Code != http.StatusTooManyRequests || resp.Code != 1010 || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("throttled response: status %d, code %d, Retry-After %q", w.Code, resp.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 没有配置可信代理时伪造X-Forwarded-For不能绕过限流
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"username":"nobody","password":"bad"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w = httptest.NewRecorder()
+	h.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For status = %d, want 429", w.Code)
+	}
+}
+
+// TestIsolatedInstances 同一进程中的两个实例不共享任何状态
+func TestIsolatedInstances(t *testing.T) {
+	cfgA := testConfig(t)
+	cfgA.RateLimit = setting.RateLimitConfig{
+		Enable: true,
+		Rules: map[string]setting.RateLimitRule{
+			"signup": {Limit: 1, Window: 60, Key: "ip"},
+		},
+	}
+	cfgB := testConfig(t)
+	cfgB.Auth.JWTSecret = "another secret"
+
+	a := newHarnessWithConfig(t, cfgA)
+	b := newHarnessWithConfig(t, cfgB)
+
+	// 用户和token只在A中有效
+	_, tokenA := a.signUpAndLogin("alice")
+	if _, resp := b.do(http.MethodGet, "/api/v1/me", tokenA, nil); resp.Code != 1009 {
+		t.Fatalf("token of A on B: code = %d, want 1009", resp.Code)
+	}
+	if _, resp := b.do(http.MethodPost, "/api/v1/login", "", map[string]string{"username": "alice", "password": "123456"}); resp.Code != 1004 {
+		t.Fatalf("login alice on B: code = %d, want 1004", resp.Code)
+	}
+
+	// A的限流配置不影响B
+	signUp := map[string]string{"username": "bob", "password": "123456", "re_password": "123456"}
+	if w, _ := a.do(http.MethodPost, "/api/v1/signup", "", signUp); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second signup on A: status = %d, want 429", w.Code)
+	}
+	b.signUpAndLogin("bob")
+	b.signUpAndLogin("carol")
+}
+
+// TestReloadConfig 热更新的配置立即生效, 不合法的配置不会生效
+func TestReloadConfig(t *testing.T) {
+	h := newHarness(t)
+	_, token := h.signUpAndLogin("alice")
+
+	next := *h.app.Config.Get()
+	next.Maintenance = setting.MaintenanceConfig{Enable: true, Message: "升级中"}
+	next.Features = map[string]bool{"signup": false}
+	if err := h.app.Reload(&next); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/post", token, map[string]interface{}{
+		"community_id": 1, "title": "t", "content": "c",
+	}); resp.Code != 1019 || resp.Msg != "升级中" {
+		t.Fatalf("post in maintenance: code %d msg %q", resp.Code, resp.Msg)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/community", "", nil, nil)
+
+	// 关闭维护模式, 注册功能仍然关闭
+	next.Maintenance.Enable = false
+	if err := h.app.Reload(&next); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	signUp := map[string]string{"username": "bob", "password": "123456", "re_password": "123456"}
+	if _, resp := h.do(http.MethodPost, "/api/v1/signup", "", signUp); resp.Code != 1020 {
+		t.Fatalf("signup disabled: code %d, want 1020", resp.Code)
+	}
+
+	// 不合法的配置保留当前配置
+	bad := next
+	bad.Vote.Window = 0
+	bad.Features = nil
+	if err := h.app.Reload(&bad); err == nil {
+		t.Fatal("Reload with invalid config succeeded")
+	}
+	if h.app.Config.Get().FeatureEnabled("signup") {
+		t.Fatal("invalid config was applied")
+	}
+}
+
+// TestRebuildCache 清空缓存后根据帖子和投票记录重建, 排序和karma不变
+func TestRebuildCache(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+	for _, title := range []string{"older", "newer"} {
+		h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+			"title":        title,
+			"content":      title + " content",
+			"community_id": 2,
+		}, nil)
+	}
+	var before []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=time", "", nil, &before)
+	h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{"post_id": before[1].ID, "direction": 1}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=score&community_id=2", "", nil, &before)
+
+	if err := h.rds.ClearPostCache(); err != nil {
+		t.Fatalf("ClearPostCache failed: %v", err)
+	}
+	var empty []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=score", "", nil, &empty)
+	if len(empty) != 0 {
+		t.Fatalf("posts after clear = %+v", empty)
+	}
+
+	posts, err := h.posts.GetPostList(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weight := func(int64) float64 { return h.app.Config.Get().Vote.ScorePerVote }
+	authorVoter := func(p *models.Post) string { return h.app.Service.VoterID(p, p.AuthorID) }
+	if err := h.rds.RebuildPostCache(posts, weight, authorVoter); err != nil {
+		t.Fatalf("RebuildPostCache failed: %v", err)
+	}
+	var after []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=score&community_id=2", "", nil, &after)
+	if len(after) != 2 || after[0].ID != before[0].ID || after[0].VoteNum != 1 || after[0].AuthorKarma != 1 {
+		t.Fatalf("posts after rebuild = %+v, want %+v", after, before)
+	}
+}
@@ -0,0 +1,106 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSecretBallot(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	bobID, bob := h.signUpAndLogin("bob")
+
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "secret", "content": "secret", "community_id": 1, "secret_ballot": true,
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	id := posts[0].ID
+
+	h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{"post_id": id, "direction": 1}, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{"post_id": id, "direction": 1}); resp.Code != 1005 {
+		t.Fatalf("repeated secret vote code = %d, want 1005", resp.Code)
+	}
+
+	// redis中只有化名, 没有用户的投票索引
+	members, err := h.redis.ZMembers("govote:post:voted:" + id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] == bobID || !strings.HasPrefix(members[0], "s:") {
+		t.Fatalf("post:voted members = %v", members)
+	}
+	if h.redis.Exists("govote:user:voted:" + bobID) {
+		t.Fatal("user:voted index written for secret ballot")
+	}
+
+	var voted []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/me/votes", bob, nil, &voted)
+	if len(voted) != 0 {
+		t.Fatalf("my votes = %+v", voted)
+	}
+	var detail apiPost
+	h.mustOK(http.MethodGet, "/api/v1/post/"+id, bob, nil, &detail)
+	if detail.VoteNum != 1 || detail.VoteStatus != 1 {
+		t.Fatalf("secret post detail = %+v", detail)
+	}
+}
+
+func TestSecretBallotDecision(t *testing.T) {
+	h := newHarness(t)
+	adminID, admin := h.signUpAndLogin("admin")
+	id, _ := strconv.ParseInt(adminID, 10, 64)
+	if err := h.users.SetUserRole(id, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	_, alice := h.signUpAndLogin("alice")
+	bobID, bob := h.signUpAndLogin("bob")
+	carolID, carol := h.signUpAndLogin("carol")
+
+	// 社区开启秘密投票, 决策帖本身没有设置secret
+	h.mustOK(http.MethodPut, "/api/v1/admin/community/2/vote_rule", admin, map[string]interface{}{"secret_ballot": true}, nil)
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "lunch", "content": "where to eat", "community_id": 2,
+		"decision": map[string]interface{}{"method": "approval", "options": []string{"noodles", "rice"}},
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2", "", nil, &posts)
+	base := "/api/v1/post/" + posts[0].ID
+
+	h.mustOK(http.MethodPost, base+"/ballot", bob, map[string][]int{"choices": {0}}, nil)
+	h.mustOK(http.MethodPost, base+"/ballot", carol, map[string][]int{"choices": {1, 0}}, nil)
+	if _, resp := h.do(http.MethodPost, base+"/ballot", bob, map[string][]int{"choices": {1}}); resp.Code != 1036 {
+		t.Fatalf("second secret ballot code = %d, want 1036", resp.Code)
+	}
+	var tally models.ApiDecisionTally
+	h.mustOK(http.MethodGet, base+"/tally", bob, nil, &tally)
+	if tally.Ballots != 2 || len(tally.MyBallot) != 1 || tally.MyBallot[0] != 0 {
+		t.Fatalf("tally = %+v", tally)
+	}
+
+	// 数据库中只有化名
+	postID, _ := strconv.ParseInt(posts[0].ID, 10, 64)
+	ballots, err := h.posts.GetBallots(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range ballots {
+		if !strings.HasPrefix(b.Voter, "s:") || b.UserID != 0 {
+			t.Fatalf("stored ballot = %+v", b)
+		}
+	}
+
+	h.mustOK(http.MethodPost, base+"/close", alice, nil, nil)
+	w, _ := h.do(http.MethodGet, base+"/audit", "", nil)
+	if body := w.Body.String(); strings.Contains(body, bobID) || strings.Contains(body, carolID) || strings.Contains(body, "user_id") {
+		t.Fatalf("audit exposes voters: %s", body)
+	}
+	var audit models.ApiDecisionAudit
+	h.mustOK(http.MethodGet, base+"/audit", "", nil, &audit)
+	if !audit.Secret || len(audit.Ballots) != 2 {
+		t.Fatalf("audit = %+v", audit)
+	}
+}
@@ -0,0 +1,105 @@
+package e2e
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// apiEvent 实时事件的json结构
+type apiEvent struct {
+	Type        string   `json:"type"`
+	PostID      string   `json:"post_id"`
+	CommunityID int64    `json:"community_id"`
+	VoteNum     int64    `json:"vote_num"`
+	Score       float64  `json:"score"`
+	Post        *apiPost `json:"post"`
+}
+
+func TestStreamEvents(t *testing.T) {
+	h := newHarness(t)
+	srv := httptest.NewServer(h.handler)
+	defer srv.Close()
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "hello", "content": "hello content", "community_id": 1,
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?order=time", "", nil, &posts)
+	pid := posts[0].ID
+
+	// WebSocket订阅帖子, SSE订阅社区
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/v1/stream/ws?topic=post:"+pid, "", srv.URL)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	defer ws.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/stream/sse?topic=community:1")
+	if err != nil {
+		t.Fatalf("sse request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("sse content type = %q", ct)
+	}
+	sse := make(chan apiEvent, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+				var e apiEvent
+				if json.Unmarshal([]byte(data), &e) == nil {
+					sse <- e
+				}
+			}
+		}
+	}()
+
+	// 投票事件同时推送给帖子和社区的订阅
+	h.mustOK(http.MethodPost, "/api/v1/vote", bob, map[string]interface{}{"post_id": pid, "direction": 1}, nil)
+
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var e apiEvent
+	if err := websocket.JSON.Receive(ws, &e); err != nil {
+		t.Fatalf("websocket receive failed: %v", err)
+	}
+	if e.Type != "vote" || e.PostID != pid || e.VoteNum != 1 || e.Score == 0 {
+		t.Fatalf("websocket event = %+v", e)
+	}
+	if e := receive(t, sse); e.Type != "vote" || e.PostID != pid || e.CommunityID != 1 {
+		t.Fatalf("sse vote event = %+v", e)
+	}
+
+	// 新帖子只推送给社区和首页的订阅
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "second", "content": "second content", "community_id": 1,
+	}, nil)
+	if e := receive(t, sse); e.Type != "new_post" || e.Post == nil || e.Post.Title != "second" {
+		t.Fatalf("sse new post event = %+v", e)
+	}
+
+	// 不合法的频道
+	if _, r := h.do(http.MethodGet, "/api/v1/stream/sse?topic=post:abc", "", nil); r.Code != 1001 {
+		t.Fatalf("invalid topic code = %d, want 1001", r.Code)
+	}
+}
+
+func receive(t *testing.T, ch <-chan apiEvent) apiEvent {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+		return apiEvent{}
+	}
+}
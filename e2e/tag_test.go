@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"bluebell/models"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestPostTags(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	_, bob := h.signUpAndLogin("bob")
+
+	for _, p := range []struct {
+		title       string
+		communityID int64
+		tags        []string
+	}{
+		{"generics", 1, []string{"Go", "lang"}},
+		{"channels", 1, []string{"go"}},
+		{"dp", 2, []string{"go", "algo"}},
+		{"misc", 1, nil},
+	} {
+		h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+			"title": p.title, "content": p.title, "community_id": p.communityID, "tags": p.tags,
+		}, nil)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "bad", "content": "bad", "community_id": 1, "tags": []string{"a b"},
+	}); resp.Code != 1001 {
+		t.Fatalf("create post with invalid tag code = %d, want 1001", resp.Code)
+	}
+
+	// 标签不区分大小写, 可以和社区一起筛选
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?tag=GO", "", nil, &posts)
+	if len(posts) != 3 {
+		t.Fatalf("posts tagged go = %+v", posts)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/posts2?tag=go&community_id=1&order=time", "", nil, &posts)
+	if len(posts) != 2 || posts[0].Title != "channels" || posts[1].Title != "generics" {
+		t.Fatalf("community 1 posts tagged go = %+v", posts)
+	}
+	h.mustOK(http.MethodGet, "/api/v1/posts2?tag=nothing", "", nil, &posts)
+	if len(posts) != 0 {
+		t.Fatalf("posts tagged nothing = %+v", posts)
+	}
+
+	var counts []models.ApiTagCount
+	h.mustOK(http.MethodGet, "/api/v1/community/1/tags", "", nil, &counts)
+	if len(counts) != 2 || counts[0].Name != "go" || counts[0].PostNum != 2 || counts[1].Name != "lang" {
+		t.Fatalf("community 1 tag counts = %+v", counts)
+	}
+
+	// 只有作者可以修改标签
+	h.mustOK(http.MethodGet, "/api/v1/posts2?tag=lang", "", nil, &posts)
+	path := "/api/v1/post/" + posts[0].ID + "/tags"
+	if _, resp := h.do(http.MethodPut, path, bob, map[string]interface{}{"tags": []string{"spam"}}); resp.Code != 1012 {
+		t.Fatalf("update other's tags code = %d, want 1012", resp.Code)
+	}
+	h.mustOK(http.MethodPut, path, alice, map[string]interface{}{"tags": []string{"lang", "generics"}}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/community/1/tags", "", nil, &counts)
+	if len(counts) != 3 || counts[0].Name != "generics" || counts[0].PostNum != 1 || counts[1].Name != "go" || counts[1].PostNum != 1 {
+		t.Fatalf("community 1 tag counts after update = %+v", counts)
+	}
+
+	// 社区定义了标签之后只能使用定义的标签
+	adminID, admin := h.signUpAndLogin("admin")
+	id, _ := strconv.ParseInt(adminID, 10, 64)
+	if err := h.users.SetUserRole(id, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/admin/community/2/tags", admin, map[string]string{"name": "Solved", "color": "#00ff00"}, nil)
+	if _, resp := h.do(http.MethodPost, "/api/v1/admin/community/2/tags", admin, map[string]string{"name": "solved"}); resp.Code != 1032 {
+		t.Fatalf("create duplicate tag code = %d, want 1032", resp.Code)
+	}
+	if _, resp := h.do(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "free", "content": "free", "community_id": 2, "tags": []string{"free"},
+	}); resp.Code != 1031 {
+		t.Fatalf("create post with undefined tag code = %d, want 1031", resp.Code)
+	}
+	h.mustOK(http.MethodPost, "/api/v1/post", bob, map[string]interface{}{
+		"title": "two sum", "content": "two sum", "community_id": 2, "tags": []string{"solved"},
+	}, nil)
+	h.mustOK(http.MethodGet, "/api/v1/community/2/tags", "", nil, &counts)
+	if counts[0].Name != "solved" || !counts[0].Defined || counts[0].Color != "#00ff00" || counts[0].PostNum != 1 {
+		t.Fatalf("community 2 tag counts = %+v", counts)
+	}
+	h.mustOK(http.MethodDelete, "/api/v1/admin/community/2/tags/solved", admin, nil, nil)
+	if _, resp := h.do(http.MethodDelete, "/api/v1/admin/community/2/tags/solved", admin, nil); resp.Code != 1033 {
+		t.Fatalf("delete missing tag code = %d, want 1033", resp.Code)
+	}
+}
+
+func TestUpdatePostTagsRedisFailure(t *testing.T) {
+	h := newHarness(t)
+	_, alice := h.signUpAndLogin("alice")
+	h.mustOK(http.MethodPost, "/api/v1/post", alice, map[string]interface{}{
+		"title": "generics", "content": "generics", "community_id": 1, "tags": []string{"go"},
+	}, nil)
+	var posts []apiPost
+	h.mustOK(http.MethodGet, "/api/v1/posts2?tag=go", "", nil, &posts)
+	postID, _ := strconv.ParseInt(posts[0].ID, 10, 64)
+
+	// 标签集合类型不对时写入redis失败, mysql中恢复成原来的标签
+	if err := h.redis.Set("govote:tag:broken", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/post/" + posts[0].ID + "/tags"
+	if _, resp := h.do(http.MethodPut, path, alice, map[string]interface{}{"tags": []string{"broken"}}); resp.Code != 1006 {
+		t.Fatalf("update tags with redis failure code = %d, want 1006", resp.Code)
+	}
+	post, err := h.posts.GetPostByID(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(post.Tags) != 1 || post.Tags[0] != "go" {
+		t.Fatalf("tags after failed update = %v, want [go]", post.Tags)
+	}
+}
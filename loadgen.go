@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bluebell/loadgen"
+	"bluebell/seeder"
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runLoadGen 对运行中的服务回放读和投票的混合流量, 使用govote seed生成的账号投票
+// 压测前建议使用test环境关闭限流
+func runLoadGen(args []string) error {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	var o loadgen.Options
+	fs.StringVar(&o.Target, "target", "http://127.0.0.1:8080", "服务地址")
+	prefix := fs.String("user-prefix", "", "govote seed输出的用户名前缀, 例如 seed_123456_")
+	users := fs.Int("users", 10, "使用的账号数量, 账号为 <user-prefix>0 到 <user-prefix>N-1")
+	fs.StringVar(&o.Password, "password", seeder.Password, "账号的密码")
+	fs.IntVar(&o.Concurrency, "concurrency", 8, "并发数")
+	fs.DurationVar(&o.Duration, "duration", 30*time.Second, "压测时长")
+	fs.IntVar(&o.Requests, "requests", 0, "请求总数, 大于0时忽略-duration")
+	fs.Float64Var(&o.VoteRatio, "vote-ratio", 0.2, "投票请求的比例, 其余为读请求")
+	fs.Int64Var(&o.Seed, "seed", time.Now().UnixNano(), "随机数种子")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *prefix == "" {
+		return errors.New("-user-prefix is required")
+	}
+	for i := 0; i < *users; i++ {
+		o.Usernames = append(o.Usernames, *prefix+strconv.Itoa(i))
+	}
+
+	report, err := loadgen.Run(o)
+	if err != nil {
+		return err
+	}
+	report.Print(os.Stdout)
+	return nil
+}
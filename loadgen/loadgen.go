@@ -0,0 +1,290 @@
+// Package loadgen 对运行中的服务回放读和投票的混合流量, 统计各类请求的延迟分位数
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求类型
+const (
+	OpList   = "list"   // 帖子列表
+	OpDetail = "detail" // 帖子详情
+	OpVote   = "vote"   // 投票
+)
+
+// 接口返回的业务状态码, 与controller中的定义一致
+const (
+	codeSuccess    = 1000
+	codeServerBusy = 1006
+)
+
+// listPages 读请求随机访问的帖子列表页数
+const listPages = 5
+
+// Options 压测参数
+type Options struct {
+	Target      string   // 服务地址, 例如 http://127.0.0.1:8080
+	Usernames   []string // 投票使用的账号
+	Password    string
+	Concurrency int
+	Duration    time.Duration // 压测时长, Requests大于0时以请求数为准
+	Requests    int
+	VoteRatio   float64 // 投票请求的比例, 其余为读请求
+	Seed        int64
+	Client      *http.Client
+}
+
+// OpStats 一类请求的统计
+type OpStats struct {
+	Count    int
+	Errors   int // 网络错误、非200响应或服务器繁忙
+	Rejected int // 业务上拒绝的请求, 例如重复投票
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+
+	latencies []time.Duration
+}
+
+// Report 压测结果
+type Report struct {
+	Elapsed time.Duration
+	Ops     map[string]*OpStats
+}
+
+// response 与controller.ResponseData对应
+type response struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+}
+
+type runner struct {
+	o       Options
+	tokens  []string
+	postIDs []string
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// Run 登录账号并获取帖子之后开始压测
+func Run(o Options) (*Report, error) {
+	if len(o.Usernames) == 0 {
+		return nil, errors.New("no user to vote with")
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	o.Target = strings.TrimRight(o.Target, "/")
+	r := &runner{o: o, report: &Report{Ops: make(map[string]*OpStats)}}
+
+	if err := r.login(); err != nil {
+		return nil, err
+	}
+	if err := r.loadPosts(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	deadline := start.Add(o.Duration)
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for range jobs {
+				r.step(rnd)
+			}
+		}(o.Seed + int64(i))
+	}
+	for n := 0; ; n++ {
+		if o.Requests > 0 && n >= o.Requests || o.Requests <= 0 && time.Now().After(deadline) {
+			break
+		}
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+
+	r.report.Elapsed = time.Since(start)
+	for _, s := range r.report.Ops {
+		s.summarize()
+	}
+	return r.report, nil
+}
+
+// login 登录全部账号
+func (r *runner) login() error {
+	for _, name := range r.o.Usernames {
+		var data struct {
+			Token string `json:"token"`
+		}
+		body := map[string]string{"username": name, "password": r.o.Password}
+		resp, _, err := r.do(http.MethodPost, "/api/v1/login", "", body)
+		if err != nil {
+			return fmt.Errorf("login %s: %w", name, err)
+		}
+		if resp.Code != codeSuccess || json.Unmarshal(resp.Data, &data) != nil || data.Token == "" {
+			return fmt.Errorf("login %s: code %d", name, resp.Code)
+		}
+		r.tokens = append(r.tokens, data.Token)
+	}
+	return nil
+}
+
+// loadPosts 获取投票和查看详情使用的帖子id
+func (r *runner) loadPosts() error {
+	for page := 1; page <= listPages; page++ {
+		var posts []struct {
+			ID string `json:"id"`
+		}
+		resp, _, err := r.do(http.MethodGet, fmt.Sprintf("/api/v1/posts2?order=time&page=%d&size=100", page), "", nil)
+		if err != nil {
+			return err
+		}
+		if resp.Code != codeSuccess || json.Unmarshal(resp.Data, &posts) != nil {
+			return fmt.Errorf("list posts: code %d", resp.Code)
+		}
+		for _, p := range posts {
+			r.postIDs = append(r.postIDs, p.ID)
+		}
+		if len(posts) < 100 {
+			break
+		}
+	}
+	if len(r.postIDs) == 0 {
+		return errors.New("no post found, run govote seed first")
+	}
+	return nil
+}
+
+// step 随机发送一个请求并记录延迟
+func (r *runner) step(rnd *rand.Rand) {
+	postID := r.postIDs[rnd.Intn(len(r.postIDs))]
+	var (
+		op     string
+		resp   *response
+		status int
+		cost   time.Duration
+		err    error
+	)
+	start := time.Now()
+	switch {
+	case rnd.Float64() < r.o.VoteRatio:
+		op = OpVote
+		body := map[string]interface{}{"post_id": postID, "direction": rnd.Intn(3) - 1}
+		resp, status, err = r.do(http.MethodPost, "/api/v1/vote", r.tokens[rnd.Intn(len(r.tokens))], body)
+	case rnd.Intn(2) == 0:
+		op = OpList
+		order := []string{"time", "score"}[rnd.Intn(2)]
+		resp, status, err = r.do(http.MethodGet, fmt.Sprintf("/api/v1/posts2?order=%s&page=%d", order, rnd.Intn(listPages)+1), "", nil)
+	default:
+		op = OpDetail
+		resp, status, err = r.do(http.MethodGet, "/api/v1/post/"+postID, "", nil)
+	}
+	cost = time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.report.Ops[op]
+	if s == nil {
+		s = new(OpStats)
+		r.report.Ops[op] = s
+	}
+	s.Count++
+	s.latencies = append(s.latencies, cost)
+	switch {
+	case err != nil || status != http.StatusOK || resp.Code == codeServerBusy:
+		s.Errors++
+	case resp.Code != codeSuccess:
+		s.Rejected++
+	}
+}
+
+// do 发送请求并解析响应
+func (r *runner) do(method, path, token string, body interface{}) (*response, int, error) {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, r.o.Target+path, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := r.o.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	resp := new(response)
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return nil, res.StatusCode, err
+	}
+	return resp, res.StatusCode, nil
+}
+
+// summarize 计算延迟分位数
+func (s *OpStats) summarize() {
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	s.P50 = percentile(s.latencies, 50)
+	s.P90 = percentile(s.latencies, 90)
+	s.P99 = percentile(s.latencies, 99)
+	if n := len(s.latencies); n > 0 {
+		s.Max = s.latencies[n-1]
+	}
+}
+
+// percentile 最近秩法计算分位数, sorted需要升序
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Print 以表格的形式输出压测结果
+func (r *Report) Print(w io.Writer) {
+	total := 0
+	fmt.Fprintf(w, "%-8s %8s %7s %9s %10s %10s %10s %10s\n", "OP", "COUNT", "ERRORS", "REJECTED", "P50", "P90", "P99", "MAX")
+	for _, op := range []string{OpList, OpDetail, OpVote} {
+		s, ok := r.Ops[op]
+		if !ok {
+			continue
+		}
+		total += s.Count
+		fmt.Fprintf(w, "%-8s %8d %7d %9d %10s %10s %10s %10s\n", op, s.Count, s.Errors, s.Rejected,
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	if secs := r.Elapsed.Seconds(); secs > 0 {
+		fmt.Fprintf(w, "total %d requests in %s, %.1f req/s\n", total, r.Elapsed.Round(time.Millisecond), float64(total)/secs)
+	}
+}
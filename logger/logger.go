@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bluebell/setting"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,13 +13,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
-
 // 自定义日志写入器
 type dynamicLogWriter struct {
 	mu         sync.Mutex
@@ -57,23 +55,22 @@ func (w *dynamicLogWriter) Write(p []byte) (n int, err error) {
 	return w.file.Write(p)
 }
 
-func Init(mode string) {
+// New 根据配置创建logger, level可以在运行时修改
+func New(cfg setting.LogConfig, mode string, level zap.AtomicLevel) *zap.Logger {
 	// 发布模式的话不需要把日志文件写到终端上面
 	var core zapcore.Core
 	jsonEncoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
 
 	if mode == "release" {
-		core = zapcore.NewCore(jsonEncoder, getLogWriter(), zapcore.DebugLevel) //文件
+		core = zapcore.NewCore(jsonEncoder, getLogWriter(cfg.LogDir), level) //文件
 	} else {
 		core = zapcore.NewTee(
-			zapcore.NewCore(getEncoder(), zapcore.AddSync(os.Stdout), zapcore.DebugLevel), //终端
-			zapcore.NewCore(jsonEncoder, getLogWriter(), zapcore.DebugLevel),              // 文件
+			zapcore.NewCore(getEncoder(), zapcore.AddSync(os.Stdout), level), //终端
+			zapcore.NewCore(jsonEncoder, getLogWriter(cfg.LogDir), level),    // 文件
 		)
 	}
 	// 创建logger
-	logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	// 替代全局变量
-	zap.ReplaceGlobals(logger)
+	return zap.New(core, zap.AddCaller())
 }
 
 // 获取Encoder
@@ -88,16 +85,16 @@ func getEncoder() zapcore.Encoder {
 }
 
 // 日志同步器 --> 写到文件和终端上
-func getLogWriter() zapcore.WriteSyncer {
+func getLogWriter(logDir string) zapcore.WriteSyncer {
 	writer := &dynamicLogWriter{
-		logDir: viper.GetString("log.logDir"),
+		logDir: logDir,
 	}
 	// 将日志文件写到文件当中去
 	return zapcore.AddSync(writer)
 }
 
 // GinLogger 接收gin框架默认的日志
-func GinLogger() gin.HandlerFunc {
+func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -119,7 +116,7 @@ func GinLogger() gin.HandlerFunc {
 }
 
 // GinRecovery recover掉项目可能出现的panic，并使用zap记录相关日志
-func GinRecovery(stack bool) gin.HandlerFunc {
+func GinRecovery(logger *zap.Logger, stack bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
@@ -0,0 +1,260 @@
+package logic
+
+import (
+	"bluebell/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrVoteFlagReviewed 可疑投票已经审核过
+var ErrVoteFlagReviewed = errors.New("该投票已经审核过")
+
+// recordVote 写入投票记录, 写入失败不影响投票
+func (s *Service) recordVote(post *models.Post, userID int64, direction int8, ip string) {
+	record := &models.VoteRecord{
+		PostID:     post.ID,
+		AuthorID:   post.AuthorID,
+		UserID:     userID,
+		Direction:  direction,
+		IP:         ip,
+		CreateTime: s.now(),
+	}
+	if err := s.Audit.InsertVoteRecord(record); err != nil {
+		s.log.Error("mysql.InsertVoteRecord failed", zap.Error(err))
+	}
+}
+
+// RunVoteAnalyzer 定期分析投票记录, 开关和间隔每次从最新的配置读取, ctx取消后退出
+func (s *Service) RunVoteAnalyzer(ctx context.Context) {
+	for {
+		interval := time.Duration(s.cfg.Get().VoteAudit.Interval) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if !s.cfg.Get().VoteAudit.Enable {
+			continue
+		}
+		if _, err := s.AnalyzeVotes(); err != nil {
+			s.log.Error("logic.AnalyzeVotes failed", zap.Error(err))
+		}
+	}
+}
+
+// AnalyzeVotes 分析最近的投票记录, 把可疑的投票加入审核队列, 返回新加入的数量
+// 同一用户对同一帖子只看最后一次投票, 已经取消的投票和给自己的投票不分析
+func (s *Service) AnalyzeVotes() (int64, error) {
+	cfg := s.cfg.Get().VoteAudit
+	since := s.now().Add(-time.Duration(cfg.Lookback) * time.Second)
+	records, err := s.Audit.GetVoteRecordsSince(since)
+	if err != nil {
+		return 0, err
+	}
+
+	type voteKey struct{ postID, userID int64 }
+	latest := make(map[voteKey]*models.VoteRecord, len(records))
+	for _, r := range records {
+		latest[voteKey{r.PostID, r.UserID}] = r
+	}
+	votes := make([]*models.VoteRecord, 0, len(latest))
+	for _, r := range records {
+		if latest[voteKey{r.PostID, r.UserID}] == r && r.Direction != 0 && r.UserID != r.AuthorID {
+			votes = append(votes, r)
+		}
+	}
+
+	flags := append(s.flagNewAccounts(votes, cfg.NewAccountAge, cfg.NewAccountVoters), flagBurst(votes, cfg.BurstWindow, cfg.BurstVoters)...)
+	flags = append(flags, flagSharedIP(votes, cfg.SharedIPVoters)...)
+	if len(flags) == 0 {
+		return 0, nil
+	}
+
+	n, err := s.Audit.InsertVoteFlags(flags)
+	if n > 0 {
+		s.log.Warn("suspicious votes flagged", zap.Int64("count", n))
+	}
+	return n, err
+}
+
+// flagNewAccounts 同一作者收到min个以上注册不到age秒的账号投票
+func (s *Service) flagNewAccounts(votes []*models.VoteRecord, age, min int64) []*models.VoteFlag {
+	if min <= 0 {
+		return nil
+	}
+	created := make(map[int64]time.Time)
+	byAuthor := make(map[int64][]*models.VoteRecord)
+	for _, v := range votes {
+		t, ok := created[v.UserID]
+		if !ok {
+			profile, err := s.Users.GetUserProfile(v.UserID)
+			if err != nil {
+				// 查不到的用户跳过, 不影响其他投票的分析
+				s.log.Error("mysql.GetUserProfile failed", zap.Int64("user_id", v.UserID), zap.Error(err))
+				created[v.UserID] = time.Time{}
+				continue
+			}
+			t = profile.CreateTime
+			created[v.UserID] = t
+		}
+		if t.IsZero() {
+			continue
+		}
+		if v.CreateTime.Sub(t) < time.Duration(age)*time.Second {
+			byAuthor[v.AuthorID] = append(byAuthor[v.AuthorID], v)
+		}
+	}
+
+	var flags []*models.VoteFlag
+	for _, group := range byAuthor {
+		n := countVoters(group)
+		if n < min {
+			continue
+		}
+		detail := fmt.Sprintf("作者收到%d个新账号的投票", n)
+		flags = append(flags, newVoteFlags(group, models.VoteFlagNewAccounts, detail)...)
+	}
+	return flags
+}
+
+// flagBurst 同一帖子在window秒内收到min票以上
+func flagBurst(votes []*models.VoteRecord, window, min int64) []*models.VoteFlag {
+	if min <= 0 {
+		return nil
+	}
+	byPost := make(map[int64][]*models.VoteRecord)
+	for _, v := range votes {
+		byPost[v.PostID] = append(byPost[v.PostID], v)
+	}
+
+	var flags []*models.VoteFlag
+	for _, group := range byPost {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].CreateTime.Before(group[j].CreateTime) })
+		// 滑动窗口, 窗口内的票数达到门槛时标记窗口内的全部投票
+		flagged := make([]bool, len(group))
+		for i, j := 0, 0; j < len(group); j++ {
+			for group[j].CreateTime.Sub(group[i].CreateTime) > time.Duration(window)*time.Second {
+				i++
+			}
+			if int64(j-i+1) >= min {
+				for k := i; k <= j; k++ {
+					flagged[k] = true
+				}
+			}
+		}
+		var burst []*models.VoteRecord
+		for i, v := range group {
+			if flagged[i] {
+				burst = append(burst, v)
+			}
+		}
+		if len(burst) > 0 {
+			detail := fmt.Sprintf("帖子%d秒内收到%d票以上", window, min)
+			flags = append(flags, newVoteFlags(burst, models.VoteFlagBurst, detail)...)
+		}
+	}
+	return flags
+}
+
+// flagSharedIP 同一ip有min个以上账号给同一作者投票
+func flagSharedIP(votes []*models.VoteRecord, min int64) []*models.VoteFlag {
+	if min <= 0 {
+		return nil
+	}
+	type ipKey struct {
+		authorID int64
+		ip       string
+	}
+	byIP := make(map[ipKey][]*models.VoteRecord)
+	for _, v := range votes {
+		if v.IP == "" {
+			continue
+		}
+		key := ipKey{v.AuthorID, v.IP}
+		byIP[key] = append(byIP[key], v)
+	}
+
+	var flags []*models.VoteFlag
+	for key, group := range byIP {
+		n := countVoters(group)
+		if n < min {
+			continue
+		}
+		detail := fmt.Sprintf("ip %s 有%d个账号给该作者投票", key.ip, n)
+		flags = append(flags, newVoteFlags(group, models.VoteFlagSharedIP, detail)...)
+	}
+	return flags
+}
+
+// countVoters 统计不同的投票用户数量
+func countVoters(votes []*models.VoteRecord) int64 {
+	users := make(map[int64]struct{})
+	for _, v := range votes {
+		users[v.UserID] = struct{}{}
+	}
+	return int64(len(users))
+}
+
+func newVoteFlags(votes []*models.VoteRecord, reason, detail string) []*models.VoteFlag {
+	flags := make([]*models.VoteFlag, 0, len(votes))
+	for _, v := range votes {
+		flags = append(flags, &models.VoteFlag{
+			PostID:   v.PostID,
+			AuthorID: v.AuthorID,
+			UserID:   v.UserID,
+			Reason:   reason,
+			Detail:   detail,
+		})
+	}
+	return flags
+}
+
+// GetVoteFlags 分页查询审核队列
+func (s *Service) GetVoteFlags(status int8, page, size int64) ([]*models.VoteFlag, error) {
+	return s.Audit.GetVoteFlags(status, page, size)
+}
+
+// ReviewVoteFlag 审核可疑投票, nullify为true时作废这次投票并重新计算帖子分数, 否则忽略
+// 同一投票因为其他原因被标记的记录一起审核
+func (s *Service) ReviewVoteFlag(id, reviewerID int64, nullify bool) error {
+	flag, err := s.Audit.GetVoteFlagByID(id)
+	if err != nil {
+		return err
+	}
+	if flag.Status != models.VoteFlagPending {
+		return ErrVoteFlagReviewed
+	}
+
+	status := models.VoteFlagDismissed
+	if nullify {
+		post, err := s.Posts.GetPostByID(flag.PostID)
+		if err != nil {
+			return err
+		}
+		rule, err := s.CommunityVoteRule(post.CommunityID)
+		if err != nil {
+			return err
+		}
+		err = s.Votes.NullifyVote(strconv.FormatInt(flag.UserID, 10), strconv.FormatInt(flag.PostID, 10),
+			strconv.FormatInt(post.AuthorID, 10), rule.ScorePerVote)
+		if err != nil {
+			return err
+		}
+		s.log.Info("vote nullified", zap.Int64("post_id", flag.PostID), zap.Int64("user_id", flag.UserID),
+			zap.Int64("reviewer_id", reviewerID))
+		s.notifyModeratorAction(flag.UserID, reviewerID, post,
+			fmt.Sprintf("你对帖子《%s》的投票被管理员作废", shorten(post.Title, 50)))
+		status = models.VoteFlagNullified
+	}
+	return s.Audit.ReviewVoteFlags(flag.PostID, flag.UserID, status, reviewerID)
+}
@@ -0,0 +1,116 @@
+package logic_test
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/setting"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeVotes(t *testing.T) {
+	env := newTestEnv(t)
+	env.cfg.VoteAudit = setting.VoteAuditConfig{
+		Lookback:         3600,
+		NewAccountAge:    3600,
+		NewAccountVoters: 3,
+		BurstWindow:      60,
+		BurstVoters:      3,
+		SharedIPVoters:   3,
+	}
+	alice := env.signUp(t, "alice")
+	pid := formatID(env.createPost(t, alice, 1, "hello"))
+	base := env.votes.GetPostScore(pid)
+
+	// 三个新账号从同一个ip集中投票, 三种规则都会命中
+	var voters []int64
+	for _, name := range []string{"bob", "carol", "dave"} {
+		uid := env.signUp(t, name)
+		voters = append(voters, uid)
+		if err := env.svc.VoteForPost(uid, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "10.0.0.1"); err != nil {
+			t.Fatalf("VoteForPost failed: %v", err)
+		}
+	}
+	// 给自己投票不分析
+	if err := env.svc.VoteForPost(alice, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "10.0.0.1"); err != nil {
+		t.Fatalf("VoteForPost failed: %v", err)
+	}
+
+	// 查不到注册时间的用户跳过, 不影响其他投票的分析
+	other := env.createPost(t, alice, 1, "other")
+	if err := env.audit.InsertVoteRecord(&models.VoteRecord{
+		PostID: other, AuthorID: alice, UserID: 404, Direction: 1, CreateTime: time.Now(),
+	}); err != nil {
+		t.Fatalf("InsertVoteRecord failed: %v", err)
+	}
+
+	n, err := env.svc.AnalyzeVotes()
+	if err != nil {
+		t.Fatalf("AnalyzeVotes failed: %v", err)
+	}
+	if n != 9 {
+		t.Fatalf("flagged = %d, want 9", n)
+	}
+	// 重复分析不会重复加入队列
+	if n, _ := env.svc.AnalyzeVotes(); n != 0 {
+		t.Fatalf("flagged again = %d, want 0", n)
+	}
+
+	flags, err := env.svc.GetVoteFlags(models.VoteFlagPending, 1, 20)
+	if err != nil {
+		t.Fatalf("GetVoteFlags failed: %v", err)
+	}
+	reasons := make(map[string]int)
+	var bobFlag *models.VoteFlag
+	for _, f := range flags {
+		reasons[f.Reason]++
+		if f.UserID == alice {
+			t.Fatalf("self vote flagged: %+v", f)
+		}
+		if f.UserID == voters[0] {
+			bobFlag = f
+		}
+	}
+	for _, reason := range []string{models.VoteFlagNewAccounts, models.VoteFlagBurst, models.VoteFlagSharedIP} {
+		if reasons[reason] != 3 {
+			t.Fatalf("flags by reason = %v", reasons)
+		}
+	}
+
+	// 作废bob的投票, 重新计算分数和karma, bob的其他标记一起审核
+	if err := env.svc.ReviewVoteFlag(bobFlag.ID, alice, true); err != nil {
+		t.Fatalf("ReviewVoteFlag failed: %v", err)
+	}
+	if got, want := env.votes.GetPostScore(pid), base+3*432; got != want {
+		t.Fatalf("score after nullify = %v, want %v", got, want)
+	}
+	if got, _ := env.votes.GetUserKarma(formatID(alice)); got != 2 {
+		t.Fatalf("karma after nullify = %d, want 2", got)
+	}
+	if status, _ := env.votes.GetPostVoteForUser(formatID(voters[0]), pid); status != 0 {
+		t.Fatalf("nullified vote status = %v, want 0", status)
+	}
+	// 被作废的账号不能再次给该帖子投票
+	if err := env.svc.VoteForPost(voters[0], &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "10.0.0.1"); !errors.Is(err, logic.ErrVoteNullified) {
+		t.Fatalf("vote after nullify: got %v, want %v", err, logic.ErrVoteNullified)
+	}
+	if flags, _ := env.svc.GetVoteFlags(models.VoteFlagPending, 1, 20); len(flags) != 6 {
+		t.Fatalf("pending flags = %d, want 6", len(flags))
+	}
+	if err := env.svc.ReviewVoteFlag(bobFlag.ID, alice, false); !errors.Is(err, logic.ErrVoteFlagReviewed) {
+		t.Fatalf("review twice: got %v, want %v", err, logic.ErrVoteFlagReviewed)
+	}
+
+	// 忽略不改变分数
+	flags, _ = env.svc.GetVoteFlags(models.VoteFlagPending, 1, 20)
+	if err := env.svc.ReviewVoteFlag(flags[0].ID, alice, false); err != nil {
+		t.Fatalf("dismiss failed: %v", err)
+	}
+	if got, want := env.votes.GetPostScore(pid), base+3*432; got != want {
+		t.Fatalf("score after dismiss = %v, want %v", got, want)
+	}
+	if flags, _ := env.svc.GetVoteFlags(models.VoteFlagDismissed, 1, 20); len(flags) != 3 {
+		t.Fatalf("dismissed flags = %d, want 3", len(flags))
+	}
+}
@@ -0,0 +1,32 @@
+package logic
+
+import (
+	"bluebell/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// ballotPseudonymPrefix 化名的前缀, 与数字形式的用户id区分
+const ballotPseudonymPrefix = "s:"
+
+// VoterID 用户在帖子投票记录中的标识
+// 秘密投票的帖子使用HMAC生成的化名, 同一用户在同一帖子中的化名固定, 可以识别重复投票,
+// 不同帖子中的化名不同, 没有密钥无法关联到用户
+func (s *Service) VoterID(post *models.Post, userID int64) string {
+	if !post.SecretBallot {
+		return strconv.FormatInt(userID, 10)
+	}
+	mac := hmac.New(sha256.New, []byte(s.cfg.Get().Auth.BallotSecret))
+	mac.Write([]byte(strconv.FormatInt(post.ID, 10) + ":" + strconv.FormatInt(userID, 10)))
+	return ballotPseudonymPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// voter 用户给帖子投票时的身份
+func (s *Service) voter(post *models.Post, userID int64) models.Voter {
+	return models.Voter{
+		ID:   s.VoterID(post, userID),
+		Self: post.AuthorID == userID,
+	}
+}
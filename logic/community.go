@@ -1,16 +1,40 @@
 package logic
 
 import (
-	"bluebell/dao/mysql"
 	"bluebell/models"
-
-	"github.com/gin-gonic/gin"
+	"strconv"
 )
 
-func GetCommunityList(c *gin.Context) ([]*models.Community, error) {
-	return mysql.GetCommunityList()
+func (s *Service) GetCommunityList() ([]*models.Community, error) {
+	return s.Communities.GetCommunityList()
+}
+
+func (s *Service) GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
+	return s.Communities.GetCommunityDetail(id)
+}
+
+// CreateCommunity 创建社区, 需要达到一定的karma
+func (s *Service) CreateCommunity(userID int64, p *models.ParamCreateCommunity) (*models.CommunityDetail, error) {
+	if err := s.checkKarma(strconv.FormatInt(userID, 10), s.cfg.Get().Karma.MinToCreateCommunity); err != nil {
+		return nil, err
+	}
+	return s.Communities.CreateCommunity(p.Name, p.Introduction)
+}
+
+// UpdateCommunityVoteRule 修改社区的投票规则
+func (s *Service) UpdateCommunityVoteRule(id int64, rule *models.CommunityVoteRule) error {
+	return s.Communities.UpdateCommunityVoteRule(id, rule)
+}
+
+// FollowCommunity 关注社区, 社区有新帖子时收到通知
+func (s *Service) FollowCommunity(userID, communityID int64) error {
+	if _, err := s.Communities.GetCommunityDetail(communityID); err != nil {
+		return err
+	}
+	return s.Communities.FollowCommunity(userID, communityID)
 }
 
-func GetCommunityDetail(c *gin.Context, id int64) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityDetail(id)
+// UnfollowCommunity 取消关注社区
+func (s *Service) UnfollowCommunity(userID, communityID int64) error {
+	return s.Communities.UnfollowCommunity(userID, communityID)
 }
@@ -0,0 +1,283 @@
+package logic
+
+import (
+	"bluebell/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotDecision  = errors.New("该帖子不是决策帖")
+	ErrDecisionOpen = errors.New("投票尚未截止")
+)
+
+// checkDecision 整理决策帖的选项, 截止时间必须在将来
+func (s *Service) checkDecision(d *models.Decision) (err error) {
+	if d.Method != models.DecisionApproval && d.Method != models.DecisionIRV {
+		return ErrInvalidPoll
+	}
+	if d.Options, err = checkOptions(d.Options); err != nil {
+		return err
+	}
+	d.CloseTime, err = s.checkCloseTime(d.CloseTime)
+	return err
+}
+
+// getDecisionPost 查询决策帖, 不是决策帖时返回ErrNotDecision
+func (s *Service) getDecisionPost(postID int64) (*models.Post, error) {
+	post, err := s.getPublishedPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Decision == nil {
+		return nil, ErrNotDecision
+	}
+	return post, nil
+}
+
+// CastBallot 给决策帖投票, 每个用户只能投一次
+// 认可投票的choices是赞成的选项, 排序复选制按偏好从高到低排列, 可以只排前几个
+func (s *Service) CastBallot(postID, userID int64, choices []int) error {
+	post, err := s.getDecisionPost(postID)
+	if err != nil {
+		return err
+	}
+	if post.Decision.Closed(s.now()) {
+		return ErrPollClosed
+	}
+	if err := checkChoices(len(post.Decision.Options), true, choices); err != nil {
+		return err
+	}
+	// 认可投票与顺序无关
+	if post.Decision.Method == models.DecisionApproval {
+		choices = slices.Clone(choices)
+		slices.Sort(choices)
+	}
+
+	community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkVoter(userID, 1, &community.CommunityVoteRule); err != nil {
+		return err
+	}
+
+	// 秘密投票的帖子只保存投票人的化名
+	err = s.Posts.InsertBallot(&models.Ballot{
+		PostID:     postID,
+		Voter:      s.VoterID(post, userID),
+		Choices:    choices,
+		CreateTime: s.now(),
+	})
+	if err != nil {
+		s.log.Error("mysql.InsertBallot failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// CloseDecision 提前截止决策帖的投票, 只有作者、版主和管理员可以操作
+func (s *Service) CloseDecision(postID, userID int64) error {
+	post, err := s.getDecisionPost(postID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPostEditor(post, userID); err != nil {
+		return err
+	}
+	if post.Decision.Closed(s.now()) {
+		return ErrPollClosed
+	}
+	return s.Posts.CloseDecision(postID, s.now().Truncate(time.Second))
+}
+
+// GetDecisionTally 查询决策帖的计票结果, userID大于0时返回当前用户的选票
+func (s *Service) GetDecisionTally(postID, userID int64) (*models.ApiDecisionTally, error) {
+	post, err := s.getDecisionPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decisionTally(post, userID)
+}
+
+func (s *Service) decisionTally(post *models.Post, userID int64) (*models.ApiDecisionTally, error) {
+	ballots, err := s.Posts.GetBallots(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	tally := tallyBallots(post.Decision, ballots)
+	tally.Closed = post.Decision.Closed(s.now())
+	if userID > 0 {
+		voter := s.VoterID(post, userID)
+		for _, b := range ballots {
+			if b.Voter == voter {
+				tally.MyBallot = b.Choices
+			}
+		}
+	}
+	return tally, nil
+}
+
+// ExportDecisionAudit 导出截止之后的选票和计票过程, 秘密投票时不包含投票人
+func (s *Service) ExportDecisionAudit(postID int64) (*models.ApiDecisionAudit, error) {
+	post, err := s.getDecisionPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.Decision.Closed(s.now()) {
+		return nil, ErrDecisionOpen
+	}
+	ballots, err := s.Posts.GetBallots(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 帖子开启秘密投票时决策帖同样是秘密投票
+	secret := post.SecretBallot || post.Decision.Secret
+	if secret {
+		// 去掉投票人和投票时间, 按选票内容排序, 避免通过顺序推断投票人
+		for i, b := range ballots {
+			ballots[i] = &models.Ballot{Choices: b.Choices}
+		}
+		slices.SortFunc(ballots, func(a, b *models.Ballot) int { return slices.Compare(a.Choices, b.Choices) })
+	} else {
+		// 公开投票的投票人就是用户id
+		for _, b := range ballots {
+			b.UserID, _ = strconv.ParseInt(b.Voter, 10, 64)
+		}
+	}
+
+	return &models.ApiDecisionAudit{
+		PostID:      post.ID,
+		Title:       post.Title,
+		Method:      post.Decision.Method,
+		Options:     post.Decision.Options,
+		Secret:      secret,
+		CloseTime:   *post.Decision.CloseTime,
+		GeneratedAt: s.now(),
+		Ballots:     ballots,
+		Tally:       tallyBallots(post.Decision, ballots),
+		Digest:      ballotDigest(ballots),
+	}, nil
+}
+
+// ballotDigest 选票的摘要, 每张选票一行, 格式为 投票人:选项, 秘密投票时投票人为空
+func ballotDigest(ballots []*models.Ballot) string {
+	h := sha256.New()
+	for _, b := range ballots {
+		var line strings.Builder
+		if b.UserID > 0 {
+			line.WriteString(strconv.FormatInt(b.UserID, 10))
+		}
+		line.WriteByte(':')
+		for i, c := range b.Choices {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(strconv.Itoa(c))
+		}
+		line.WriteByte('\n')
+		h.Write([]byte(line.String()))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// tallyBallots 按决策帖的计票方式计票
+func tallyBallots(d *models.Decision, ballots []*models.Ballot) *models.ApiDecisionTally {
+	tally := &models.ApiDecisionTally{
+		Method:  d.Method,
+		Ballots: int64(len(ballots)),
+	}
+	if d.Method == models.DecisionApproval {
+		tally.Rounds, tally.Winners = tallyApproval(len(d.Options), ballots)
+	} else {
+		tally.Rounds, tally.Winners = tallyIRV(len(d.Options), ballots)
+	}
+	return tally
+}
+
+// tallyApproval 认可投票只有一轮, 得票最多的选项胜出, 并列时都算胜出
+func tallyApproval(options int, ballots []*models.Ballot) ([]*models.TallyRound, []int) {
+	counts := make([]int64, options)
+	for _, b := range ballots {
+		for _, c := range b.Choices {
+			counts[c]++
+		}
+	}
+	round := &models.TallyRound{Round: 1, Counts: counts, Eliminated: []int{}}
+	max := slices.Max(counts)
+	winners := []int{}
+	if max > 0 {
+		for i, n := range counts {
+			if n == max {
+				winners = append(winners, i)
+			}
+		}
+	}
+	return []*models.TallyRound{round}, winners
+}
+
+// tallyIRV 排序复选制, 每张选票计入排名最高的未淘汰选项
+// 有选项得票过半时胜出, 否则淘汰得票最少的选项(并列时一起淘汰)进入下一轮
+// 剩下的选项票数全部相同时并列胜出
+func tallyIRV(options int, ballots []*models.Ballot) ([]*models.TallyRound, []int) {
+	active := make([]bool, options)
+	for i := range active {
+		active[i] = true
+	}
+	var rounds []*models.TallyRound
+	for n := 1; ; n++ {
+		round := &models.TallyRound{Round: n, Counts: make([]int64, options), Eliminated: []int{}}
+		rounds = append(rounds, round)
+		for _, b := range ballots {
+			i := slices.IndexFunc(b.Choices, func(c int) bool { return active[c] })
+			if i < 0 {
+				round.Exhausted++
+				continue
+			}
+			round.Counts[b.Choices[i]]++
+		}
+		continuing := int64(len(ballots)) - round.Exhausted
+		if continuing == 0 {
+			return rounds, []int{}
+		}
+
+		var remaining []int
+		for i, ok := range active {
+			if ok {
+				remaining = append(remaining, i)
+			}
+		}
+		for _, c := range remaining {
+			if round.Counts[c]*2 > continuing {
+				return rounds, []int{c}
+			}
+		}
+
+		min := round.Counts[remaining[0]]
+		for _, c := range remaining {
+			if round.Counts[c] < min {
+				min = round.Counts[c]
+			}
+		}
+		for _, c := range remaining {
+			if round.Counts[c] == min {
+				round.Eliminated = append(round.Eliminated, c)
+			}
+		}
+		if len(round.Eliminated) == len(remaining) {
+			round.Eliminated = []int{}
+			return rounds, remaining
+		}
+		for _, c := range round.Eliminated {
+			active[c] = false
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package logic_test
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// createDecision 发一个决策帖, 返回帖子id
+func (env *testEnv) createDecision(t *testing.T, authorID int64, d *models.Decision) int64 {
+	t.Helper()
+	p := &models.Post{
+		AuthorID:    authorID,
+		CommunityID: 1,
+		Title:       "decision",
+		Content:     "decision content",
+		Decision:    d,
+	}
+	if err := env.svc.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost(decision) failed: %v", err)
+	}
+	return p.ID
+}
+
+// castBallots 每张选票由一个新注册的用户投出, 每个测试只能调用一次
+func (env *testEnv) castBallots(t *testing.T, postID int64, ballots ...[]int) {
+	t.Helper()
+	for i, choices := range ballots {
+		voter := env.signUp(t, "voter"+strconv.Itoa(i))
+		if err := env.svc.CastBallot(postID, voter, choices); err != nil {
+			t.Fatalf("CastBallot(%v) failed: %v", choices, err)
+		}
+	}
+}
+
+func TestIRVTally(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	pid := env.createDecision(t, alice, &models.Decision{Method: models.DecisionIRV, Options: []string{"a", "b", "c"}})
+
+	env.castBallots(t, pid,
+		[]int{0, 1, 2}, []int{0, 1, 2}, []int{0, 1, 2}, []int{0, 1, 2},
+		[]int{1, 2}, []int{1, 2}, []int{1, 2},
+		[]int{2, 1}, []int{2, 1},
+	)
+
+	tally, err := env.svc.GetDecisionTally(pid, 0)
+	if err != nil {
+		t.Fatalf("GetDecisionTally failed: %v", err)
+	}
+	if tally.Ballots != 9 || len(tally.Rounds) != 2 || !slices.Equal(tally.Winners, []int{1}) {
+		t.Fatalf("tally = %+v", tally)
+	}
+	first, second := tally.Rounds[0], tally.Rounds[1]
+	if !slices.Equal(first.Counts, []int64{4, 3, 2}) || !slices.Equal(first.Eliminated, []int{2}) {
+		t.Fatalf("round 1 = %+v", first)
+	}
+	if !slices.Equal(second.Counts, []int64{4, 5, 0}) || len(second.Eliminated) != 0 {
+		t.Fatalf("round 2 = %+v", second)
+	}
+}
+
+func TestApprovalTally(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	pid := env.createDecision(t, alice, &models.Decision{Method: models.DecisionApproval, Options: []string{"a", "b", "c"}})
+
+	if err := env.svc.CastBallot(pid, alice, []int{2, 2}); !errors.Is(err, logic.ErrInvalidChoice) {
+		t.Fatalf("repeated choice error = %v, want ErrInvalidChoice", err)
+	}
+	if err := env.svc.CastBallot(pid, alice, []int{2, 0}); err != nil {
+		t.Fatalf("CastBallot failed: %v", err)
+	}
+	if err := env.svc.CastBallot(pid, alice, []int{1}); !errors.Is(err, mysql.ErrorBallotExist) {
+		t.Fatalf("second ballot error = %v, want ErrorBallotExist", err)
+	}
+	env.castBallots(t, pid, []int{0, 1}, []int{2})
+
+	tally, err := env.svc.GetDecisionTally(pid, alice)
+	if err != nil {
+		t.Fatalf("GetDecisionTally failed: %v", err)
+	}
+	if len(tally.Rounds) != 1 || !slices.Equal(tally.Rounds[0].Counts, []int64{2, 1, 2}) ||
+		!slices.Equal(tally.Winners, []int{0, 2}) || !slices.Equal(tally.MyBallot, []int{0, 2}) {
+		t.Fatalf("tally = %+v", tally)
+	}
+}
+
+func TestDecisionAudit(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	pid := env.createDecision(t, alice, &models.Decision{Method: models.DecisionIRV, Options: []string{"a", "b"}, Secret: true})
+	env.castBallots(t, pid, []int{1, 0}, []int{0})
+
+	if _, err := env.svc.ExportDecisionAudit(pid); !errors.Is(err, logic.ErrDecisionOpen) {
+		t.Fatalf("export open decision error = %v, want ErrDecisionOpen", err)
+	}
+	if err := env.svc.CloseDecision(pid, bob); !errors.Is(err, logic.ErrNoPermission) {
+		t.Fatalf("close by others error = %v, want ErrNoPermission", err)
+	}
+	if err := env.svc.CloseDecision(pid, alice); err != nil {
+		t.Fatalf("CloseDecision failed: %v", err)
+	}
+	if err := env.svc.CastBallot(pid, bob, []int{0}); !errors.Is(err, logic.ErrPollClosed) {
+		t.Fatalf("ballot after close error = %v, want ErrPollClosed", err)
+	}
+
+	audit, err := env.svc.ExportDecisionAudit(pid)
+	if err != nil {
+		t.Fatalf("ExportDecisionAudit failed: %v", err)
+	}
+	// 秘密投票不包含投票人, 选票按内容排序
+	if len(audit.Ballots) != 2 || audit.Ballots[0].UserID != 0 || !slices.Equal(audit.Ballots[0].Choices, []int{0}) ||
+		!slices.Equal(audit.Ballots[1].Choices, []int{1, 0}) || audit.Digest == "" {
+		t.Fatalf("audit ballots = %+v", audit.Ballots)
+	}
+	if len(audit.Tally.Rounds) != 1 || !slices.Equal(audit.Tally.Winners, []int{0, 1}) {
+		t.Fatalf("audit tally = %+v", audit.Tally)
+	}
+}
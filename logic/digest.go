@@ -0,0 +1,128 @@
+package logic
+
+import (
+	"bluebell/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// digestPeriod 每周摘要包含的帖子时间范围
+const digestPeriod = 7 * 24 * time.Hour
+
+// digestPost 摘要邮件中的一个帖子
+type digestPost struct {
+	Title     string
+	Community string
+	VoteNum   int64
+	Link      string
+}
+
+// SendDigest 给开启了每周摘要并验证了邮箱的用户发送关注社区最近一周的热门帖子, 返回发送的邮件数
+// 单个用户发送失败时记录日志并继续
+func (s *Service) SendDigest() (int64, error) {
+	userIDs, err := s.Notifications.GetNotificationEnabledUsers(models.NotifyEmailDigest)
+	if err != nil {
+		return 0, err
+	}
+
+	since := s.now().Add(-digestPeriod)
+	var sent int64
+	for _, userID := range userIDs {
+		email, err := s.Users.GetUserEmail(userID)
+		if err != nil {
+			s.log.Error("mysql.GetUserEmail failed", zap.Int64("user_id", userID), zap.Error(err))
+			continue
+		}
+		if email.Email == "" || !email.Verified {
+			continue
+		}
+		posts, err := s.digestPosts(userID, since)
+		if err != nil {
+			s.log.Error("collect digest posts failed", zap.Int64("user_id", userID), zap.Error(err))
+			continue
+		}
+		if len(posts) == 0 {
+			continue
+		}
+		user, err := s.Users.GetUserByID(userID)
+		if err != nil {
+			s.log.Error("mysql.GetUserByID failed", zap.Int64("user_id", userID), zap.Error(err))
+			continue
+		}
+		err = s.sendMail(email.Email, "你关注的社区本周热门", "digest", map[string]interface{}{
+			"Username": user.Username,
+			"Posts":    posts,
+		})
+		if err != nil {
+			s.log.Error("send digest failed", zap.Int64("user_id", userID), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// digestPosts 从用户关注的每个社区取分数最高的帖子, 只保留since之后发布的, 按票数排序
+func (s *Service) digestPosts(userID int64, since time.Time) ([]*digestPost, error) {
+	communityIDs, err := s.Communities.GetFollowedCommunities(userID)
+	if err != nil {
+		return nil, err
+	}
+	top := s.cfg.Get().Mail.DigestTop
+
+	var ids []string
+	for _, communityID := range communityIDs {
+		list, err := s.Votes.GetCommunityPostIDsInOrder(&models.ParamPostList{
+			CommunityID: communityID,
+			Page:        1,
+			Size:        top,
+			Order:       models.OrderScore,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, list...)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	posts, err := s.Posts.GetPostListsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	voteData, err := s.Votes.GetPostVoteList(ids)
+	if err != nil {
+		return nil, err
+	}
+	votes := make(map[string]int64, len(ids))
+	for i, id := range ids {
+		votes[id] = voteData[i]
+	}
+
+	var list []*digestPost
+	for _, post := range posts {
+		if post.CreateTime.Before(since) {
+			continue
+		}
+		community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+		if err != nil {
+			return nil, err
+		}
+		id := strconv.FormatInt(post.ID, 10)
+		list = append(list, &digestPost{
+			Title:     post.Title,
+			Community: community.Name,
+			VoteNum:   votes[id],
+			Link:      s.siteURL("/post/" + id),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].VoteNum > list[j].VoteNum })
+	if int64(len(list)) > top {
+		list = list[:top]
+	}
+	return list, nil
+}
@@ -0,0 +1,181 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/markdown"
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// postSchedulerInterval 定时发布的检查间隔
+const postSchedulerInterval = 10 * time.Second
+
+// ErrInvalidPublishTime 定时发布的时间必须在将来, 草稿不能同时设置发布时间
+var ErrInvalidPublishTime = errors.New("发布时间必须晚于当前时间")
+
+// checkSchedule 根据草稿标记和发布时间设置帖子状态
+// 定时发布时投票帖和决策帖的截止时间必须晚于发布时间
+func (s *Service) checkSchedule(p *models.Post, draft bool, publishTime *time.Time) error {
+	p.PublishTime = nil
+	switch {
+	case draft && publishTime != nil:
+		return ErrInvalidPublishTime
+	case draft:
+		p.Status = models.PostStatusDraft
+	case publishTime != nil:
+		// 数据库只保存到秒
+		t := publishTime.Truncate(time.Second)
+		if !t.After(s.now()) {
+			return ErrInvalidPublishTime
+		}
+		if c := postCloseTime(p); c != nil && !c.After(t) {
+			return ErrInvalidCloseTime
+		}
+		p.Status = models.PostStatusScheduled
+		p.PublishTime = &t
+	default:
+		p.Status = models.PostStatusPublished
+	}
+	return nil
+}
+
+// postCloseTime 投票帖和决策帖的截止时间, 没有截止时间时返回nil
+func postCloseTime(p *models.Post) *time.Time {
+	switch {
+	case p.Poll != nil:
+		return p.Poll.CloseTime
+	case p.Decision != nil:
+		return p.Decision.CloseTime
+	}
+	return nil
+}
+
+// getDraft 查询作者自己未发布的帖子, 其他用户看不到草稿, 当作不存在
+func (s *Service) getDraft(postID, userID int64) (*models.Post, error) {
+	post, err := s.Posts.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != userID {
+		if !post.Published() {
+			return nil, mysql.ErrorPostNotExist
+		}
+		return nil, ErrNoPermission
+	}
+	if post.Published() {
+		return nil, mysql.ErrorPostPublished
+	}
+	return post, nil
+}
+
+// GetDrafts 按保存时间倒序查询用户的草稿和定时发布的帖子
+func (s *Service) GetDrafts(userID, page, size int64) ([]*models.Post, error) {
+	posts, err := s.Posts.GetDraftsByAuthor(userID, page, size)
+	if err != nil {
+		s.log.Error("mysql.GetDraftsByAuthor failed", zap.Error(err))
+		return nil, err
+	}
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	return posts, nil
+}
+
+// UpdateDraft 修改草稿, 只有作者可以修改, 设置了发布时间时改为定时发布, 否则改回草稿
+func (s *Service) UpdateDraft(postID, userID int64, p *models.ParamDraft) (*models.Post, error) {
+	post, err := s.getDraft(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.checkPostTags(post.CommunityID, p.Tags)
+	if err != nil {
+		return nil, err
+	}
+	post.Title = p.Title
+	post.Content = p.Content
+	post.ContentHTML = markdown.Render(p.Content)
+	post.Tags = tags
+	if err := s.checkSchedule(post, p.PublishTime == nil, p.PublishTime); err != nil {
+		return nil, err
+	}
+	post.CreateTime = s.now()
+
+	if err := s.Posts.UpdateDraft(post); err != nil {
+		s.log.Error("mysql.UpdateDraft failed", zap.Error(err))
+		return nil, err
+	}
+	return post, nil
+}
+
+// PublishPost 立即发布草稿或定时发布的帖子, 只有作者可以发布
+func (s *Service) PublishPost(postID, userID int64) error {
+	post, err := s.getDraft(postID, userID)
+	if err != nil {
+		return err
+	}
+	// 草稿保存之后投票可能已经截止
+	if c := postCloseTime(post); c != nil && !c.After(s.now()) {
+		return ErrInvalidCloseTime
+	}
+	return s.publishDraft(post)
+}
+
+// publishDraft 把草稿改为已发布, 发帖时间和投票窗口期从现在开始
+// 多个实例同时发布时只有一个成功, 其他的返回ErrorPostPublished
+// 写入redis失败时恢复原来的状态, 定时发布的帖子在下一次检查时重试
+func (s *Service) publishDraft(p *models.Post) error {
+	now := s.now()
+	if err := s.Posts.PublishPost(p.ID, now); err != nil {
+		return err
+	}
+	draft := *p
+	p.Status = models.PostStatusPublished
+	p.CreateTime = now
+	if err := s.publishPost(p); err != nil {
+		if rerr := s.Posts.UnpublishPost(&draft); rerr != nil {
+			s.log.Error("mysql.UnpublishPost failed", zap.Int64("post_id", p.ID), zap.Error(rerr))
+		}
+		return err
+	}
+	return nil
+}
+
+// RunPostScheduler 定期发布到期的定时帖子, ctx取消后退出
+func (s *Service) RunPostScheduler(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(postSchedulerInterval):
+		}
+		if _, err := s.PublishDuePosts(); err != nil {
+			s.log.Error("logic.PublishDuePosts failed", zap.Error(err))
+		}
+	}
+}
+
+// PublishDuePosts 发布所有发布时间已到的定时帖子, 返回本次发布的数量
+// 单个帖子发布失败时记录日志并继续, 已经被其他实例发布的帖子直接跳过
+func (s *Service) PublishDuePosts() (int64, error) {
+	posts, err := s.Posts.GetDuePosts(s.now())
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, p := range posts {
+		err := s.publishDraft(p)
+		if errors.Is(err, mysql.ErrorPostPublished) {
+			continue
+		}
+		if err != nil {
+			s.log.Error("publish scheduled post failed", zap.Int64("post_id", p.ID), zap.Error(err))
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
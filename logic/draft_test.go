@@ -0,0 +1,141 @@
+package logic_test
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listedIDs 首页按时间排序的帖子id
+func (env *testEnv) listedIDs(t *testing.T) []int64 {
+	t.Helper()
+	data, err := env.svc.GetPostListNew(&models.ParamPostList{Page: 1, Size: 10, Order: models.OrderTime}, 0)
+	if err != nil {
+		t.Fatalf("GetPostListNew failed: %v", err)
+	}
+	return postIDs(data)
+}
+
+func TestDraftPost(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+
+	draft := &models.Post{AuthorID: alice, CommunityID: 1, Title: "draft", Content: "draft content", Draft: true}
+	if err := env.svc.CreatePost(draft); err != nil {
+		t.Fatalf("CreatePost(draft) failed: %v", err)
+	}
+	if got := env.listedIDs(t); len(got) != 0 {
+		t.Fatalf("listed posts = %v, want none before publish", got)
+	}
+
+	// 草稿只有作者可见, 不能投票
+	if _, err := env.svc.GetPostByID(draft.ID, bob); !errors.Is(err, mysql.ErrorPostNotExist) {
+		t.Fatalf("other user get draft error = %v, want ErrorPostNotExist", err)
+	}
+	if _, err := env.svc.GetPostByID(draft.ID, alice); err != nil {
+		t.Fatalf("author get draft failed: %v", err)
+	}
+	vote := &models.ParamVoteData{PostID: formatID(draft.ID), Direction: direction(1)}
+	if err := env.svc.VoteForPost(bob, vote, "127.0.0.1"); !errors.Is(err, mysql.ErrorPostNotExist) {
+		t.Fatalf("vote on draft error = %v, want ErrorPostNotExist", err)
+	}
+
+	edit := &models.ParamDraft{Title: "edited", Content: "edited content", Tags: []string{"news"}}
+	if _, err := env.svc.UpdateDraft(draft.ID, bob, edit); !errors.Is(err, mysql.ErrorPostNotExist) {
+		t.Fatalf("other user edit draft error = %v, want ErrorPostNotExist", err)
+	}
+	if _, err := env.svc.UpdateDraft(draft.ID, alice, edit); err != nil {
+		t.Fatalf("UpdateDraft failed: %v", err)
+	}
+	drafts, err := env.svc.GetDrafts(alice, 1, 10)
+	if err != nil {
+		t.Fatalf("GetDrafts failed: %v", err)
+	}
+	if len(drafts) != 1 || drafts[0].Title != "edited" || !slices.Equal(drafts[0].Tags, []string{"news"}) {
+		t.Fatalf("drafts = %+v", drafts)
+	}
+
+	if err := env.svc.PublishPost(draft.ID, alice); err != nil {
+		t.Fatalf("PublishPost failed: %v", err)
+	}
+	if got := env.listedIDs(t); !equalIDs(got, []int64{draft.ID}) {
+		t.Fatalf("listed posts = %v, want published draft", got)
+	}
+	if err := env.svc.VoteForPost(bob, vote, "127.0.0.1"); err != nil {
+		t.Fatalf("vote after publish failed: %v", err)
+	}
+	if err := env.svc.PublishPost(draft.ID, alice); !errors.Is(err, mysql.ErrorPostPublished) {
+		t.Fatalf("publish twice error = %v, want ErrorPostPublished", err)
+	}
+	if _, err := env.svc.UpdateDraft(draft.ID, alice, edit); !errors.Is(err, mysql.ErrorPostPublished) {
+		t.Fatalf("edit published post error = %v, want ErrorPostPublished", err)
+	}
+	if drafts, _ := env.svc.GetDrafts(alice, 1, 10); len(drafts) != 0 {
+		t.Fatalf("drafts after publish = %+v", drafts)
+	}
+}
+
+func TestScheduledPost(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	past := time.Now().Add(-time.Minute)
+	publishTime := time.Now().Add(time.Hour)
+	closeTime := time.Now().Add(30 * time.Minute)
+
+	for _, tt := range []struct {
+		name string
+		p    *models.Post
+		want error
+	}{
+		{"publish time in the past", &models.Post{PublishTime: &past}, logic.ErrInvalidPublishTime},
+		{"draft with publish time", &models.Post{Draft: true, PublishTime: &publishTime}, logic.ErrInvalidPublishTime},
+		{"poll closes before publish", &models.Post{
+			PublishTime: &publishTime,
+			Poll:        &models.Poll{Options: []string{"a", "b"}, CloseTime: &closeTime},
+		}, logic.ErrInvalidCloseTime},
+	} {
+		tt.p.AuthorID, tt.p.CommunityID, tt.p.Title, tt.p.Content = alice, 1, "scheduled", "scheduled"
+		if err := env.svc.CreatePost(tt.p); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: CreatePost error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	p := &models.Post{AuthorID: alice, CommunityID: 1, Title: "scheduled", Content: "scheduled", PublishTime: &publishTime}
+	if err := env.svc.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost(scheduled) failed: %v", err)
+	}
+	plain := env.createPost(t, alice, 1, "plain")
+	if n, err := env.svc.PublishDuePosts(); err != nil || n != 0 {
+		t.Fatalf("PublishDuePosts before due = %d, %v", n, err)
+	}
+	if got := env.listedIDs(t); !equalIDs(got, []int64{plain}) {
+		t.Fatalf("listed posts = %v, want only plain post", got)
+	}
+
+	// 到时间之后发布, 投票窗口期从发布时开始
+	env.offset = 2 * time.Hour
+	if n, err := env.svc.PublishDuePosts(); err != nil || n != 1 {
+		t.Fatalf("PublishDuePosts after due = %d, %v", n, err)
+	}
+	if n, err := env.svc.PublishDuePosts(); err != nil || n != 0 {
+		t.Fatalf("PublishDuePosts again = %d, %v", n, err)
+	}
+	if got := env.listedIDs(t); !equalIDs(got, []int64{p.ID, plain}) {
+		t.Fatalf("listed posts = %v, want scheduled post first", got)
+	}
+	detail, err := env.svc.GetPostByID(p.ID, 0)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	if !detail.Published() || detail.Post.CreateTime.Before(publishTime) {
+		t.Fatalf("published post = %+v", detail.Post)
+	}
+	if score := env.votes.GetPostScore(formatID(p.ID)); score != float64(detail.Post.CreateTime.Unix()) {
+		t.Fatalf("post score = %v, want publish time %d", score, detail.Post.CreateTime.Unix())
+	}
+}
@@ -0,0 +1,146 @@
+package logic_test
+
+import (
+	"bluebell/dao/memory"
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/mailer"
+	"bluebell/pkg/snowflake"
+	"bluebell/setting"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testConfig 测试使用的配置, 不需要登录延迟
+func testConfig() *setting.Config {
+	return &setting.Config{
+		App: setting.AppConfig{Name: "govote"},
+		Auth: setting.AuthConfig{
+			BallotSecret: "govote-test-ballot-secret",
+			LoginGuard: setting.LoginGuardConfig{
+				Window:               900,
+				DelayAfter:           0,
+				AccountLockThreshold: 3,
+				IPLockThreshold:      10,
+				LockDuration:         900,
+			},
+		},
+		Vote: setting.VoteConfig{Window: 7 * 24 * 3600, ScorePerVote: 432},
+		Mail: setting.MailConfig{BaseURL: "http://govote.test", VerifyExpire: 3600, ResetExpire: 1800, DigestTop: 10},
+	}
+}
+
+// testEnv 基于内存存储的业务逻辑
+type testEnv struct {
+	svc           *logic.Service
+	cfg           *setting.Config
+	tokens        *jwt.Manager
+	users         *memory.UserStore
+	posts         *memory.PostStore
+	communities   *memory.CommunityStore
+	votes         *memory.VoteStore
+	auth          *memory.AuthStore
+	audit         *memory.AuditStore
+	notifications *memory.NotificationStore
+	mails         *mailBox
+	offset        time.Duration // 加到当前时间上, 模拟时间流逝
+}
+
+// mailBox 记录发送的邮件
+type mailBox struct {
+	mu   sync.Mutex
+	sent []*mailer.Message
+}
+
+func (b *mailBox) Send(msg *mailer.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, msg)
+	return nil
+}
+
+// last 最后一封发给to的邮件, 没有时返回nil
+func (b *mailBox) last(to string) *mailer.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.sent) - 1; i >= 0; i-- {
+		if b.sent[i].To == to {
+			return b.sent[i]
+		}
+	}
+	return nil
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		users: memory.NewUserStore(),
+		posts: memory.NewPostStore(),
+		communities: memory.NewCommunityStore(
+			&models.CommunityDetail{ID: 1, Name: "Go", Introduction: "Golang"},
+			&models.CommunityDetail{ID: 2, Name: "leetcode", Introduction: "刷题刷题刷题"},
+		),
+		votes:         memory.NewVoteStore(),
+		auth:          memory.NewAuthStore(),
+		audit:         memory.NewAuditStore(),
+		notifications: memory.NewNotificationStore(),
+		mails:         new(mailBox),
+	}
+	ids, err := snowflake.New("2025-09-30", 1)
+	if err != nil {
+		t.Fatalf("snowflake.New failed: %v", err)
+	}
+	stores := logic.Stores{
+		Users:         env.users,
+		Posts:         env.posts,
+		Communities:   env.communities,
+		Votes:         env.votes,
+		Auth:          env.auth,
+		Audit:         env.audit,
+		Events:        memory.NewEventStore(),
+		Notifications: env.notifications,
+	}
+	env.cfg = testConfig()
+	env.tokens = jwt.New("test", time.Hour)
+	env.svc = logic.NewService(setting.NewHolder(env.cfg), zap.NewNop(), stores, ids, env.tokens, env.mails, func() time.Time {
+		return time.Now().Add(env.offset)
+	})
+	return env
+}
+
+// signUp 注册并登录一个用户, 返回用户id
+func (env *testEnv) signUp(t *testing.T, username string) int64 {
+	t.Helper()
+	err := env.svc.SignUp(&models.ParamSignUp{Username: username, Password: "123456", RePassword: "123456"})
+	if err != nil {
+		t.Fatalf("SignUp(%s) failed: %v", username, err)
+	}
+	res, err := env.svc.Login(&models.ParamLogin{Username: username, Password: "123456"}, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login(%s) failed: %v", username, err)
+	}
+	return res.UserID
+}
+
+// createPost 发一个帖子, 返回帖子id
+func (env *testEnv) createPost(t *testing.T, authorID, communityID int64, title string) int64 {
+	t.Helper()
+	p := &models.Post{
+		AuthorID:    authorID,
+		CommunityID: communityID,
+		Title:       title,
+		Content:     title + " content",
+	}
+	if err := env.svc.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost(%s) failed: %v", title, err)
+	}
+	return p.ID
+}
+
+func direction(d int8) *int8 {
+	return &d
+}
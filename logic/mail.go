@@ -0,0 +1,242 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/mailer"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	htmltemplate "html/template"
+	"net/url"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidMailToken     = errors.New("链接无效或已过期")
+	ErrEmailNotSet          = errors.New("还没有设置邮箱")
+	ErrEmailAlreadyVerified = errors.New("邮箱已经验证过")
+)
+
+// 邮件链接中token的用途
+const (
+	mailTokenVerify = "verify"
+	mailTokenReset  = "reset"
+)
+
+//go:embed templates
+var mailTemplateFS embed.FS
+
+// 邮件模板, 每封邮件都有同名的纯文本和HTML两个模板
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(mailTemplateFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(mailTemplateFS, "templates/*.html"))
+)
+
+// sendMail 使用模板渲染邮件并发送, data中自动加上AppName
+func (s *Service) sendMail(to, subject, name string, data map[string]interface{}) error {
+	data["AppName"] = s.cfg.Get().App.Name
+	var text, html bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return err
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return err
+	}
+	return s.mailer.Send(&mailer.Message{
+		To:      to,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
+
+// newMailToken 生成邮件链接中的一次性token, redis中只保存token的哈希
+func (s *Service) newMailToken(purpose, value string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	if err := s.Auth.SaveMailToken(purpose, hashMailToken(token), value, ttl); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeMailToken 使用token, 每个token只能使用一次
+func (s *Service) consumeMailToken(purpose, token string) (string, error) {
+	value, err := s.Auth.ConsumeMailToken(purpose, hashMailToken(token))
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", ErrInvalidMailToken
+	}
+	return value, nil
+}
+
+func hashMailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// siteURL 生成邮件中指向前端页面的链接
+func (s *Service) siteURL(path string) string {
+	return strings.TrimSuffix(s.cfg.Get().Mail.BaseURL, "/") + path
+}
+
+// formatExpire 把有效期格式化成邮件中展示的文字
+func formatExpire(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "小时"
+	case d >= time.Minute:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "分钟"
+	default:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "秒"
+	}
+}
+
+// sendVerifyEmail 发送邮箱验证邮件, token绑定用户和邮箱, 修改邮箱之后旧的链接失效
+func (s *Service) sendVerifyEmail(userID int64, username, email string) error {
+	ttl := time.Duration(s.cfg.Get().Mail.VerifyExpire) * time.Second
+	token, err := s.newMailToken(mailTokenVerify, strconv.FormatInt(userID, 10)+":"+email, ttl)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(email, "验证你的邮箱", "verify_email", map[string]interface{}{
+		"Username": username,
+		"Link":     s.siteURL("/verify-email?token=" + url.QueryEscape(token)),
+		"Expire":   formatExpire(ttl),
+	})
+}
+
+// VerifyEmail 通过邮件中的链接验证邮箱
+func (s *Service) VerifyEmail(token string) error {
+	value, err := s.consumeMailToken(mailTokenVerify, token)
+	if err != nil {
+		return err
+	}
+	id, email, _ := strings.Cut(value, ":")
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return ErrInvalidMailToken
+	}
+	if err := s.Users.SetEmailVerified(userID, email); err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			// 验证邮件发出之后用户又修改了邮箱
+			return ErrInvalidMailToken
+		}
+		return err
+	}
+	return nil
+}
+
+// ResendVerifyEmail 重新发送邮箱验证邮件
+func (s *Service) ResendVerifyEmail(userID int64) error {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	email, err := s.Users.GetUserEmail(userID)
+	if err != nil {
+		return err
+	}
+	if email.Email == "" {
+		return ErrEmailNotSet
+	}
+	if email.Verified {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerifyEmail(userID, user.Username, email.Email)
+}
+
+// UpdateEmail 修改邮箱, 新邮箱需要重新验证
+func (s *Service) UpdateEmail(userID int64, email string) error {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.SetUserEmail(userID, email); err != nil {
+		return err
+	}
+	if err := s.sendVerifyEmail(userID, user.Username, email); err != nil {
+		s.log.Error("send verify email failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
+
+// ForgotPassword 在后台给已验证的邮箱发送重置密码邮件
+// 不管邮箱是否存在都立即返回, 避免通过返回结果或响应时间探测邮箱是否注册
+func (s *Service) ForgotPassword(email string) {
+	s.mails.Add(1)
+	go func() {
+		defer s.mails.Done()
+		if err := s.sendResetPassword(email); err != nil {
+			s.log.Error("send reset password mail failed", zap.Error(err))
+		}
+	}()
+}
+
+// sendResetPassword 发送重置密码邮件, 邮箱不存在或未验证时不发送
+func (s *Service) sendResetPassword(email string) error {
+	user, err := s.Users.GetUserByEmail(email)
+	if errors.Is(err, mysql.ErrorUserNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ue, err := s.Users.GetUserEmail(user.UserID)
+	if err != nil {
+		return err
+	}
+	if !ue.Verified {
+		s.log.Info("password reset requested for unverified email", zap.Int64("user_id", user.UserID))
+		return nil
+	}
+
+	ttl := time.Duration(s.cfg.Get().Mail.ResetExpire) * time.Second
+	token, err := s.newMailToken(mailTokenReset, strconv.FormatInt(user.UserID, 10), ttl)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(email, "重置密码", "reset_password", map[string]interface{}{
+		"Username": user.Username,
+		"Link":     s.siteURL("/reset-password?token=" + url.QueryEscape(token)),
+		"Expire":   formatExpire(ttl),
+	})
+}
+
+// ResetPassword 通过邮件中的链接重置密码, 同时解除账号的登录锁定
+func (s *Service) ResetPassword(p *models.ParamResetPassword) error {
+	value, err := s.consumeMailToken(mailTokenReset, p.Token)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return ErrInvalidMailToken
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.UpdatePassword(userID, p.Password); err != nil {
+		return err
+	}
+	if err := s.Auth.UnlockAccount(user.Username); err != nil {
+		s.log.Error("redis.UnlockAccount failed", zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package logic_test
+
+import (
+	"bluebell/logic"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+var tokenRe = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func TestVerifyEmailAfterChange(t *testing.T) {
+	env := newTestEnv(t)
+	userID := env.signUp(t, "alice")
+
+	if err := env.svc.ResendVerifyEmail(userID); !errors.Is(err, logic.ErrEmailNotSet) {
+		t.Fatalf("ResendVerifyEmail without email: err = %v", err)
+	}
+	if err := env.svc.UpdateEmail(userID, "old@example.com"); err != nil {
+		t.Fatalf("UpdateEmail failed: %v", err)
+	}
+	old := tokenRe.FindStringSubmatch(env.mails.last("old@example.com").Text)
+	if err := env.svc.UpdateEmail(userID, "new@example.com"); err != nil {
+		t.Fatalf("UpdateEmail failed: %v", err)
+	}
+
+	// 旧邮箱的验证链接不能验证新邮箱
+	if err := env.svc.VerifyEmail(old[1]); !errors.Is(err, logic.ErrInvalidMailToken) {
+		t.Fatalf("VerifyEmail with old token: err = %v", err)
+	}
+	cur := tokenRe.FindStringSubmatch(env.mails.last("new@example.com").Text)
+	if err := env.svc.VerifyEmail(cur[1]); err != nil {
+		t.Fatalf("VerifyEmail failed: %v", err)
+	}
+	profile, err := env.svc.GetMyProfile(userID)
+	if err != nil {
+		t.Fatalf("GetMyProfile failed: %v", err)
+	}
+	if profile.Email != "new@example.com" || !profile.EmailVerified {
+		t.Fatalf("profile = %+v", profile)
+	}
+}
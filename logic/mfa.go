@@ -0,0 +1,266 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/totp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	mfaTokenExpire       = 5 * time.Minute  // 登录第二步的有效期
+	mfaEnrollTokenExpire = 15 * time.Minute // 强制绑定两步验证的有效期
+	recoveryCodeCount    = 10
+)
+
+var (
+	ErrInvalidMFACode    = errors.New("验证码错误")
+	ErrMFANotEnrolled    = errors.New("未开启两步验证")
+	ErrMFAAlreadyEnabled = errors.New("已经开启两步验证")
+	ErrMFARequired       = errors.New("当前角色必须开启两步验证")
+)
+
+// mfaRequiredForRole 判断该角色是否被要求必须开启两步验证
+func (s *Service) mfaRequiredForRole(role int8) bool {
+	if role < models.RoleModerator {
+		return false
+	}
+	require, err := s.Auth.GetRequireMFAForModerators()
+	if err != nil {
+		s.log.Error("redis.GetRequireMFAForModerators failed", zap.Error(err))
+		return false
+	}
+	return require
+}
+
+// LoginMFA 登录第二步, 校验mfa_token和验证码(或恢复码)后签发正式token
+func (s *Service) LoginMFA(p *models.ParamLoginMFA, ip string) (*models.ApiLoginResult, error) {
+	mc, err := s.tokens.ParsePurposeToken(p.MFAToken, jwt.PurposeMFA)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证码同样计入登录失败次数, 防止暴力猜测6位数字
+	ttl, err := s.Auth.GetLoginLockTTL(mc.Username, ip)
+	if err != nil {
+		s.log.Error("redis.GetLoginLockTTL failed", zap.Error(err))
+	} else if ttl > 0 {
+		s.recordLoginAttempt(mc.UserID, mc.Username, ip, models.LoginReasonLocked)
+		return nil, &ErrLoginLocked{RetryAfter: ttl}
+	}
+
+	user, err := s.Users.GetUserByID(mc.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyMFACode(user, p.Code, true); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordLoginAttempt(user.UserID, user.Username, ip, models.LoginReasonWrongMFACode)
+			s.onLoginFailure(user.Username, ip)
+		}
+		return nil, err
+	}
+
+	if err := s.Auth.ClearLoginFailures(user.Username); err != nil {
+		s.log.Error("redis.ClearLoginFailures failed", zap.Error(err))
+	}
+	s.recordLoginAttempt(user.UserID, user.Username, ip, models.LoginReasonSuccess)
+
+	token, err := s.tokens.GenToken(user.UserID, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiLoginResult{
+		UserID:   user.UserID,
+		Username: user.Username,
+		Token:    token,
+	}, nil
+}
+
+// EnrollMFA 生成新的两步验证密钥, 需要调用ActivateMFA校验之后才生效
+func (s *Service) EnrollMFA(userID int64) (*models.ApiMFAEnroll, error) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Users.SetTOTPSecret(userID, secret); err != nil {
+		return nil, err
+	}
+	return &models.ApiMFAEnroll{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.cfg.Get().App.Name, user.Username, secret),
+	}, nil
+}
+
+// ActivateMFA 校验验证器App生成的验证码, 开启两步验证并返回恢复码
+func (s *Service) ActivateMFA(userID int64, code string) (*models.ApiMFAActivate, error) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.verifyMFACode(user, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Users.EnableTOTP(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	// 被强制绑定的用户此时拿到的还是mfa_enroll token, 这里直接签发正式token
+	token, err := s.tokens.GenToken(user.UserID, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiMFAActivate{
+		RecoveryCodes: codes,
+		Token:         token,
+	}, nil
+}
+
+// DisableMFA 关闭两步验证
+func (s *Service) DisableMFA(userID int64, code string) error {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+	if s.mfaRequiredForRole(user.Role) {
+		return ErrMFARequired
+	}
+	if err := s.verifyMFACode(user, code, true); err != nil {
+		return err
+	}
+	return s.Users.DisableTOTP(userID)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码, 旧的恢复码全部作废
+func (s *Service) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.verifyMFACode(user, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Users.EnableTOTP(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CheckMFAEnrolled 开启了强制两步验证时, 版主及以上角色还没有绑定的返回ErrMFARequired
+// 开启要求之前签发的token同样受限, 只能访问绑定两步验证的接口
+func (s *Service) CheckMFAEnrolled(userID int64) error {
+	require, err := s.Auth.GetRequireMFAForModerators()
+	if err != nil {
+		s.log.Error("redis.GetRequireMFAForModerators failed", zap.Error(err))
+		return nil
+	}
+	if !require {
+		return nil
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Role >= models.RoleModerator && !user.TOTPEnabled {
+		return ErrMFARequired
+	}
+	return nil
+}
+
+// SetRequireMFAForModerators 管理员设置版主及以上角色是否必须开启两步验证
+func (s *Service) SetRequireMFAForModerators(require bool) error {
+	return s.Auth.SetRequireMFAForModerators(require)
+}
+
+// verifyMFACode 校验验证码, allowRecovery为true时也接受恢复码
+func (s *Service) verifyMFACode(user *models.User, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		if !allowRecovery {
+			return ErrInvalidMFACode
+		}
+		err := s.Users.UseRecoveryCode(user.UserID, hashRecoveryCode(code))
+		if errors.Is(err, mysql.ErrorRecoveryCodeInvalid) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, s.now(), 1)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// 同一个验证码只能使用一次
+	fresh, err := s.Auth.MarkTOTPStepUsed(user.UserID, step, 3*totp.Period*time.Second)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// generateRecoveryCodes 生成一组恢复码, 数据库中只保存哈希
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes = make([]string, 0, recoveryCodeCount)
+	hashes = make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(enc.EncodeToString(buf)) // 8个字符
+		code := s[:4] + "-" + s[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return
+}
+
+// hashRecoveryCode 计算恢复码的哈希, 忽略大小写和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
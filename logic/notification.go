@@ -0,0 +1,233 @@
+package logic
+
+import (
+	"bluebell/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// ErrUnknownNotificationType 通知设置中有不存在的通知类型
+var ErrUnknownNotificationType = errors.New("未知的通知类型")
+
+// voteMilestones 帖子票数达到这些值时通知作者, 每个值只通知一次
+var voteMilestones = []int64{10, 50, 100, 500, 1000}
+
+// maxMentions 一个帖子最多通知的被@用户数
+const maxMentions = 10
+
+// maxMentionNames 一个帖子最多查询的被@用户名数, 不存在的用户名也计算在内
+const maxMentionNames = 2 * maxMentions
+
+var mentionRe = regexp.MustCompile(`@([\p{L}\p{N}_-]+)`)
+
+// notify 给用户发送通知, 关闭了该类通知的用户不会收到, 发送失败不影响主流程
+func (s *Service) notify(typ string, ns []*models.Notification) {
+	if len(ns) == 0 {
+		return
+	}
+	userIDs := make([]int64, len(ns))
+	for i, n := range ns {
+		userIDs[i] = n.UserID
+	}
+	disabled, err := s.Notifications.GetNotificationDisabledUsers(typ, userIDs)
+	if err != nil {
+		s.log.Error("mysql.GetNotificationDisabledUsers failed", zap.Error(err))
+		return
+	}
+
+	send := make([]*models.Notification, 0, len(ns))
+	for _, n := range ns {
+		if disabled[n.UserID] {
+			continue
+		}
+		n.Type = typ
+		send = append(send, n)
+	}
+	if err := s.Notifications.InsertNotifications(send); err != nil {
+		s.log.Error("mysql.InsertNotifications failed", zap.String("type", typ), zap.Error(err))
+	}
+}
+
+// notifyNewPost 通知帖子中被@的用户和关注了社区的用户
+func (s *Service) notifyNewPost(p *models.Post) {
+	author, err := s.Users.GetUserByID(p.AuthorID)
+	if err != nil {
+		s.log.Error("mysql.GetUserByID failed", zap.Error(err))
+		return
+	}
+	title := shorten(p.Title, 50)
+
+	// 被@的用户, 不包括作者自己
+	var mentions []*models.Notification
+	mentioned := make(map[int64]bool)
+	for _, name := range mentionNames(p.Title + " " + p.Content) {
+		if len(mentioned) >= maxMentions {
+			break
+		}
+		if name == author.Username {
+			continue
+		}
+		user, err := s.Users.GetUserByUsername(name)
+		if err != nil || user.UserID == p.AuthorID || mentioned[user.UserID] {
+			continue
+		}
+		mentioned[user.UserID] = true
+		mentions = append(mentions, &models.Notification{
+			UserID:      user.UserID,
+			ActorID:     p.AuthorID,
+			PostID:      p.ID,
+			CommunityID: p.CommunityID,
+			Content:     fmt.Sprintf("%s 在帖子《%s》中提到了你", author.Username, title),
+			DedupeKey:   dedupeKey(models.NotifyMention, p.ID),
+		})
+	}
+	s.notify(models.NotifyMention, mentions)
+
+	// 关注了社区的用户, 已经收到@通知的不再重复通知
+	followers, err := s.Communities.GetCommunityFollowers(p.CommunityID)
+	if err != nil {
+		s.log.Error("mysql.GetCommunityFollowers failed", zap.Error(err))
+		return
+	}
+	community, err := s.Communities.GetCommunityDetail(p.CommunityID)
+	if err != nil {
+		s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
+		return
+	}
+	var posts []*models.Notification
+	for _, uid := range followers {
+		if uid == p.AuthorID || mentioned[uid] {
+			continue
+		}
+		posts = append(posts, &models.Notification{
+			UserID:      uid,
+			ActorID:     p.AuthorID,
+			PostID:      p.ID,
+			CommunityID: p.CommunityID,
+			Content:     fmt.Sprintf("你关注的社区 %s 有新帖子《%s》", community.Name, title),
+		})
+	}
+	s.notify(models.NotifyCommunityPost, posts)
+}
+
+// mentionNames 文本中被@的用户名, 去掉重复之后最多返回maxMentionNames个
+func mentionNames(text string) []string {
+	var names []string
+	for _, m := range mentionRe.FindAllStringSubmatch(text, -1) {
+		if len(names) >= maxMentionNames {
+			break
+		}
+		if !slices.Contains(names, m[1]) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// notifyVoteMilestone 帖子的票数刚好达到里程碑时通知作者
+func (s *Service) notifyVoteMilestone(post *models.Post) {
+	voteData, err := s.Votes.GetPostVoteList([]string{strconv.FormatInt(post.ID, 10)})
+	if err != nil {
+		s.log.Error("redis.GetPostVoteList failed", zap.Error(err))
+		return
+	}
+	if !slices.Contains(voteMilestones, voteData[0]) {
+		return
+	}
+	s.notify(models.NotifyVoteMilestone, []*models.Notification{{
+		UserID:      post.AuthorID,
+		PostID:      post.ID,
+		CommunityID: post.CommunityID,
+		Content:     fmt.Sprintf("你的帖子《%s》获得了%d票", shorten(post.Title, 50), voteData[0]),
+		DedupeKey:   dedupeKey(models.NotifyVoteMilestone, post.ID, voteData[0]),
+	}})
+}
+
+// notifyModeratorAction 通知用户管理员对其账号或投票进行了操作, actorID为0表示系统操作
+func (s *Service) notifyModeratorAction(userID, actorID int64, post *models.Post, content string) {
+	n := &models.Notification{UserID: userID, ActorID: actorID, Content: content}
+	if post != nil {
+		n.PostID = post.ID
+		n.CommunityID = post.CommunityID
+	}
+	s.notify(models.NotifyModeratorAction, []*models.Notification{n})
+}
+
+// dedupeKey 生成通知的去重标识
+func dedupeKey(typ string, ids ...int64) *string {
+	key := typ
+	for _, id := range ids {
+		key += ":" + strconv.FormatInt(id, 10)
+	}
+	return &key
+}
+
+// shorten 截断过长的标题
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// GetNotifications 分页查询用户的通知和未读数量
+func (s *Service) GetNotifications(userID int64, p *models.ParamNotifications) (*models.ApiNotifications, error) {
+	list, err := s.Notifications.GetNotifications(userID, p.Unread, p.Page, p.Size)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.Notifications.CountUnreadNotifications(userID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []*models.Notification{}
+	}
+	return &models.ApiNotifications{Unread: unread, List: list}, nil
+}
+
+// MarkNotificationRead 把一条通知标记为已读
+func (s *Service) MarkNotificationRead(userID, id int64) error {
+	return s.Notifications.MarkNotificationRead(userID, id)
+}
+
+// MarkAllNotificationsRead 把全部通知标记为已读
+func (s *Service) MarkAllNotificationsRead(userID int64) error {
+	return s.Notifications.MarkAllNotificationsRead(userID)
+}
+
+// GetNotificationPrefs 查询用户的通知设置, 返回全部通知类型
+func (s *Service) GetNotificationPrefs(userID int64) (map[string]bool, error) {
+	saved, err := s.Notifications.GetNotificationPrefs(userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]bool, len(models.NotificationTypes))
+	for _, typ := range models.NotificationTypes {
+		enabled, ok := saved[typ]
+		if !ok {
+			enabled = !slices.Contains(models.OptInNotificationTypes, typ)
+		}
+		prefs[typ] = enabled
+	}
+	return prefs, nil
+}
+
+// UpdateNotificationPrefs 修改用户的通知设置, 没有传的类型不修改
+func (s *Service) UpdateNotificationPrefs(userID int64, prefs map[string]bool) (map[string]bool, error) {
+	for typ := range prefs {
+		if !slices.Contains(models.NotificationTypes, typ) {
+			return nil, ErrUnknownNotificationType
+		}
+	}
+	if err := s.Notifications.SetNotificationPrefs(userID, prefs); err != nil {
+		return nil, err
+	}
+	return s.GetNotificationPrefs(userID)
+}
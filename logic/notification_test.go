@@ -0,0 +1,108 @@
+package logic_test
+
+import (
+	"bluebell/models"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNotifyVoteMilestone(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	pid := formatID(env.createPost(t, alice, 1, "hello"))
+
+	milestones := func() int {
+		data, err := env.svc.GetNotifications(alice, &models.ParamNotifications{Page: 1, Size: 20})
+		if err != nil {
+			t.Fatalf("GetNotifications failed: %v", err)
+		}
+		n := 0
+		for _, item := range data.List {
+			if item.Type == models.NotifyVoteMilestone {
+				n++
+			}
+		}
+		return n
+	}
+
+	var voters []int64
+	for i := 0; i < 10; i++ {
+		uid := env.signUp(t, fmt.Sprintf("voter%d", i))
+		voters = append(voters, uid)
+		if err := env.svc.VoteForPost(uid, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1"); err != nil {
+			t.Fatalf("VoteForPost failed: %v", err)
+		}
+	}
+	if got := milestones(); got != 1 {
+		t.Fatalf("milestone notifications = %d, want 1", got)
+	}
+
+	// 票数回落之后再次达到里程碑不重复通知
+	last := voters[len(voters)-1]
+	for _, dir := range []int8{0, 1} {
+		if err := env.svc.VoteForPost(last, &models.ParamVoteData{PostID: pid, Direction: direction(dir)}, "127.0.0.1"); err != nil {
+			t.Fatalf("VoteForPost failed: %v", err)
+		}
+	}
+	if got := milestones(); got != 1 {
+		t.Fatalf("milestone notifications after revote = %d, want 1", got)
+	}
+
+	// 关闭之后不再收到
+	if _, err := env.svc.UpdateNotificationPrefs(alice, map[string]bool{models.NotifyVoteMilestone: false}); err != nil {
+		t.Fatalf("UpdateNotificationPrefs failed: %v", err)
+	}
+	pid2 := formatID(env.createPost(t, alice, 1, "second"))
+	for _, uid := range voters {
+		if err := env.svc.VoteForPost(uid, &models.ParamVoteData{PostID: pid2, Direction: direction(1)}, "127.0.0.1"); err != nil {
+			t.Fatalf("VoteForPost failed: %v", err)
+		}
+	}
+	if got := milestones(); got != 1 {
+		t.Fatalf("milestone notifications when disabled = %d, want 1", got)
+	}
+}
+
+func TestNotifyMention(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+
+	mentions := func() int {
+		data, err := env.svc.GetNotifications(bob, &models.ParamNotifications{Page: 1, Size: 20})
+		if err != nil {
+			t.Fatalf("GetNotifications failed: %v", err)
+		}
+		n := 0
+		for _, item := range data.List {
+			if item.Type == models.NotifyMention {
+				n++
+			}
+		}
+		return n
+	}
+	post := func(content string) {
+		t.Helper()
+		p := &models.Post{AuthorID: alice, CommunityID: 1, Title: "mention", Content: content}
+		if err := env.svc.CreatePost(p); err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+	}
+
+	// 重复的用户名只算一次
+	post(strings.Repeat("@ghost ", 50) + "@bob")
+	if got := mentions(); got != 1 {
+		t.Fatalf("mentions after repeated names = %d, want 1", got)
+	}
+
+	// 超过上限的用户名不再查询
+	var names []string
+	for i := 0; i < 50; i++ {
+		names = append(names, fmt.Sprintf("@ghost%d", i))
+	}
+	post(strings.Join(names, " ") + " @bob")
+	if got := mentions(); got != 1 {
+		t.Fatalf("mentions after too many names = %d, want 1", got)
+	}
+}
@@ -0,0 +1,157 @@
+package logic
+
+import (
+	"bluebell/models"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidPoll      = errors.New("投票选项有误")
+	ErrInvalidChoice    = errors.New("选择的选项不存在")
+	ErrNotPoll          = errors.New("该帖子不是投票帖")
+	ErrPollClosed       = errors.New("投票已截止")
+	ErrInvalidCloseTime = errors.New("截止时间必须晚于当前时间")
+)
+
+// checkPoll 整理投票帖的选项, 截止时间必须在将来
+func (s *Service) checkPoll(poll *models.Poll) (err error) {
+	if poll.Options, err = checkOptions(poll.Options); err != nil {
+		return err
+	}
+	poll.CloseTime, err = s.checkCloseTime(poll.CloseTime)
+	return err
+}
+
+// checkOptions 整理投票帖和决策帖的选项, 选项不能为空或重复
+func checkOptions(list []string) ([]string, error) {
+	if len(list) < models.PollMinOptions || len(list) > models.PollMaxOptions {
+		return nil, ErrInvalidPoll
+	}
+	options := make([]string, len(list))
+	for i, o := range list {
+		o = strings.TrimSpace(o)
+		if o == "" || slices.Contains(options[:i], o) {
+			return nil, ErrInvalidPoll
+		}
+		options[i] = o
+	}
+	return options, nil
+}
+
+// checkCloseTime 截止时间必须在将来, 为空表示不截止
+func (s *Service) checkCloseTime(t *time.Time) (*time.Time, error) {
+	if t == nil {
+		return nil, nil
+	}
+	// 数据库只保存到秒
+	closeTime := t.Truncate(time.Second)
+	if !closeTime.After(s.now()) {
+		return nil, ErrInvalidCloseTime
+	}
+	return &closeTime, nil
+}
+
+// checkChoices 判断选择的选项是否合法, 选项不能重复, 单选时只能选一个
+func checkChoices(options int, multiple bool, choices []int) error {
+	if len(choices) == 0 || (!multiple && len(choices) > 1) {
+		return ErrInvalidChoice
+	}
+	for i, c := range choices {
+		if c < 0 || c >= options || slices.Contains(choices[:i], c) {
+			return ErrInvalidChoice
+		}
+	}
+	return nil
+}
+
+// VotePoll 给投票帖投票, 每个用户只能投一次, 返回投票之后的结果
+func (s *Service) VotePoll(postID, userID int64, choices []int) (*models.ApiPollResult, error) {
+	post, err := s.getPublishedPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Poll == nil {
+		return nil, ErrNotPoll
+	}
+	if post.Poll.Closed(s.now()) {
+		return nil, ErrPollClosed
+	}
+	if err := checkChoices(len(post.Poll.Options), post.Poll.Multiple, choices); err != nil {
+		return nil, err
+	}
+	choices = slices.Clone(choices)
+	slices.Sort(choices)
+
+	// 投票帖同样需要满足社区的投票条件
+	community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVoter(userID, 1, &community.CommunityVoteRule); err != nil {
+		return nil, err
+	}
+
+	if err := s.Votes.CastPollBallot(postID, s.VoterID(post, userID), choices); err != nil {
+		return nil, err
+	}
+
+	// 推送最新结果, 隐藏结果时只推送投票人数
+	result, err := s.pollResult(post, 0)
+	if err != nil {
+		s.log.Error("get poll result failed", zap.Error(err))
+		return nil, err
+	}
+	err = s.Events.PublishEvent(&models.Event{
+		Type:        models.EventPoll,
+		PostID:      post.ID,
+		CommunityID: post.CommunityID,
+		Poll:        result,
+	})
+	if err != nil {
+		s.log.Error("redis.PublishEvent failed", zap.Error(err))
+	}
+
+	mine := *result
+	mine.MyChoices = choices
+	return &mine, nil
+}
+
+// GetPollResult 查询投票帖的结果, userID大于0时返回当前用户的选择
+func (s *Service) GetPollResult(postID, userID int64) (*models.ApiPollResult, error) {
+	post, err := s.getPublishedPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Poll == nil {
+		return nil, ErrNotPoll
+	}
+	return s.pollResult(post, userID)
+}
+
+// pollResult 统计投票帖的结果, 作者选择隐藏结果时截止之前只返回投票人数
+func (s *Service) pollResult(post *models.Post, userID int64) (*models.ApiPollResult, error) {
+	counts, voters, err := s.Votes.GetPollTally(post.ID, len(post.Poll.Options))
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ApiPollResult{
+		Closed: post.Poll.Closed(s.now()),
+		Voters: voters,
+		Counts: counts,
+	}
+	if post.Poll.HideResults && !result.Closed {
+		result.Hidden = true
+		result.Counts = nil
+	}
+	if userID > 0 {
+		if result.MyChoices, err = s.Votes.GetPollBallot(post.ID, s.VoterID(post, userID)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
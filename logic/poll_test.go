@@ -0,0 +1,138 @@
+package logic_test
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// createPoll 发一个投票帖, 返回帖子id
+func (env *testEnv) createPoll(t *testing.T, authorID int64, poll *models.Poll) int64 {
+	t.Helper()
+	p := &models.Post{
+		AuthorID:    authorID,
+		CommunityID: 1,
+		Title:       "poll",
+		Content:     "poll content",
+		Poll:        poll,
+	}
+	if err := env.svc.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost(poll) failed: %v", err)
+	}
+	return p.ID
+}
+
+func TestCreatePollValidation(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		poll *models.Poll
+		want error
+	}{
+		{"too few options", &models.Poll{Options: []string{"a"}}, logic.ErrInvalidPoll},
+		{"duplicate options", &models.Poll{Options: []string{"a", " a "}}, logic.ErrInvalidPoll},
+		{"blank option", &models.Poll{Options: []string{"a", "  "}}, logic.ErrInvalidPoll},
+		{"closed already", &models.Poll{Options: []string{"a", "b"}, CloseTime: &past}, logic.ErrInvalidCloseTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.svc.CreatePost(&models.Post{AuthorID: alice, CommunityID: 1, Title: "poll", Content: "poll", Poll: tt.poll})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CreatePost error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVotePoll(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	single := env.createPoll(t, alice, &models.Poll{Options: []string{"go", "rust", "zig"}})
+	multi := env.createPoll(t, alice, &models.Poll{Options: []string{"go", "rust", "zig"}, Multiple: true})
+
+	for _, tt := range []struct {
+		name    string
+		postID  int64
+		choices []int
+	}{
+		{"two choices in single poll", single, []int{0, 1}},
+		{"out of range", single, []int{3}},
+		{"negative", multi, []int{-1}},
+		{"repeated", multi, []int{1, 1}},
+	} {
+		if _, err := env.svc.VotePoll(tt.postID, bob, tt.choices); !errors.Is(err, logic.ErrInvalidChoice) {
+			t.Fatalf("%s: VotePoll error = %v, want ErrInvalidChoice", tt.name, err)
+		}
+	}
+
+	res, err := env.svc.VotePoll(multi, bob, []int{2, 0})
+	if err != nil {
+		t.Fatalf("VotePoll failed: %v", err)
+	}
+	if res.Voters != 1 || !slices.Equal(res.Counts, []int64{1, 0, 1}) || !slices.Equal(res.MyChoices, []int{0, 2}) {
+		t.Fatalf("poll result = %+v", res)
+	}
+	if _, err := env.svc.VotePoll(multi, bob, []int{1}); !errors.Is(err, redis.ErrPollVoted) {
+		t.Fatalf("second ballot error = %v, want ErrPollVoted", err)
+	}
+	if _, err := env.svc.VotePoll(multi, alice, []int{1}); err != nil {
+		t.Fatalf("VotePoll(alice) failed: %v", err)
+	}
+
+	res, err = env.svc.GetPollResult(multi, 0)
+	if err != nil {
+		t.Fatalf("GetPollResult failed: %v", err)
+	}
+	if res.Voters != 2 || !slices.Equal(res.Counts, []int64{1, 1, 1}) || res.MyChoices != nil {
+		t.Fatalf("anonymous poll result = %+v", res)
+	}
+
+	plain := env.createPost(t, alice, 1, "plain")
+	if _, err := env.svc.VotePoll(plain, bob, []int{0}); !errors.Is(err, logic.ErrNotPoll) {
+		t.Fatalf("vote on plain post error = %v, want ErrNotPoll", err)
+	}
+}
+
+func TestPollHiddenUntilClose(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	closeTime := time.Now().Add(time.Hour)
+	pid := env.createPoll(t, alice, &models.Poll{Options: []string{"yes", "no"}, HideResults: true, CloseTime: &closeTime})
+
+	res, err := env.svc.VotePoll(pid, bob, []int{1})
+	if err != nil {
+		t.Fatalf("VotePoll failed: %v", err)
+	}
+	if !res.Hidden || res.Counts != nil || res.Voters != 1 || !slices.Equal(res.MyChoices, []int{1}) {
+		t.Fatalf("hidden poll result = %+v", res)
+	}
+	detail, err := env.svc.GetPostByID(pid, alice)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	if detail.PollResult == nil || !detail.PollResult.Hidden || detail.PollResult.Counts != nil {
+		t.Fatalf("post detail poll result = %+v", detail.PollResult)
+	}
+
+	// 截止之后公开结果, 不能再投票
+	env.offset = 2 * time.Hour
+	if _, err := env.svc.VotePoll(pid, alice, []int{0}); !errors.Is(err, logic.ErrPollClosed) {
+		t.Fatalf("vote after close error = %v, want ErrPollClosed", err)
+	}
+	res, err = env.svc.GetPollResult(pid, 0)
+	if err != nil {
+		t.Fatalf("GetPollResult failed: %v", err)
+	}
+	if !res.Closed || res.Hidden || !slices.Equal(res.Counts, []int64{0, 1}) {
+		t.Fatalf("closed poll result = %+v", res)
+	}
+}
@@ -2,62 +2,155 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
-	"bluebell/dao/redis"
 	"bluebell/models"
-	"bluebell/pkg/snowflake"
+	"bluebell/pkg/markdown"
+	"errors"
 	"strconv"
-	"time"
 
 	"go.uber.org/zap"
 )
 
-// CreatePost创建帖子logic
-func CreatePost(p *models.Post) error {
-	// 1生成postID
-	p.ID = snowflake.GenID()
-	p.CreateTime = time.Now()
+// ErrNoPermission 没有修改该资源的权限
+var ErrNoPermission = errors.New("没有权限")
+
+// CreatePost创建帖子logic, 草稿和定时发布的帖子只保存到数据库
+func (s *Service) CreatePost(p *models.Post) error {
+	// 1 整理标签、投票方式、选项和发布计划, 生成postID
+	tags, err := s.checkPostTags(p.CommunityID, p.Tags)
+	if err != nil {
+		return err
+	}
+	p.Tags = tags
+	p.ContentHTML = markdown.Render(p.Content)
+	// 社区开启秘密投票时新帖子都是秘密投票, 发帖之后不能再修改
+	community, err := s.Communities.GetCommunityDetail(p.CommunityID)
+	if err != nil {
+		return err
+	}
+	p.SecretBallot = p.SecretBallot || community.SecretBallot
+	p.Type = models.PostTypeText
+	switch {
+	case p.Poll != nil && p.Decision != nil:
+		return ErrInvalidPoll
+	case p.Poll != nil:
+		if err := s.checkPoll(p.Poll); err != nil {
+			return err
+		}
+		p.Type = models.PostTypePoll
+	case p.Decision != nil:
+		if err := s.checkDecision(p.Decision); err != nil {
+			return err
+		}
+		p.Decision.Secret = p.Decision.Secret || p.SecretBallot
+		p.Type = models.PostTypeDecision
+	}
+	if err := s.checkSchedule(p, p.Draft, p.PublishTime); err != nil {
+		return err
+	}
+	p.ID = s.ids.NextID()
+	p.CreateTime = s.now()
 
 	// 2 保存到数据库
-	if err := mysql.CreatePost(p); err != nil {
-		zap.L().Error("mysql.CreatePost failed", zap.Error(err))
+	if err := s.Posts.CreatePost(p); err != nil {
+		s.log.Error("mysql.CreatePost failed", zap.Error(err))
 		return err
 	}
+	if !p.Published() {
+		return nil
+	}
+	return s.publishPost(p)
+}
 
+// publishPost 把刚发布的帖子保存到redis, 投票窗口期从这时开始, 然后推送新帖事件和站内通知
+func (s *Service) publishPost(p *models.Post) error {
 	// 3 保存到redis
-	if err := redis.CreatePost(p); err != nil {
-		zap.L().Error("redis.CreatePost failed", zap.Error(err))
+	if err := s.Votes.CreatePost(p); err != nil {
+		s.log.Error("redis.CreatePost failed", zap.Error(err))
+		return err
+	}
+
+	// 4 通知订阅了首页和社区的客户端, 发布失败不影响发帖
+	err := s.Events.PublishEvent(&models.Event{
+		Type:        models.EventNewPost,
+		PostID:      p.ID,
+		CommunityID: p.CommunityID,
+		Post:        p,
+	})
+	if err != nil {
+		s.log.Error("redis.PublishEvent failed", zap.Error(err))
+	}
+
+	// 5 站内通知
+	s.notifyNewPost(p)
+	return nil
+}
+
+// checkPostEditor 只有作者、版主和管理员可以修改帖子
+func (s *Service) checkPostEditor(post *models.Post, userID int64) error {
+	if post.AuthorID == userID {
+		return nil
+	}
+	role, err := s.GetUserRole(userID)
+	if err != nil {
 		return err
 	}
+	if role < models.RoleModerator {
+		return ErrNoPermission
+	}
 	return nil
 }
 
-// GetPostByID 根据帖子的id来查询帖子的详细数据
-func GetPostByID(id, userID int64) (data *models.ApiPostDetail, err error) {
+// PreviewContent 预览帖子内容的渲染结果, 和发帖时保存的HTML相同
+func (s *Service) PreviewContent(content string) string {
+	return markdown.Render(content)
+}
+
+// getPublishedPost 查询已发布的帖子, 草稿和定时发布的帖子不能投票, 当作不存在
+func (s *Service) getPublishedPost(postID int64) (*models.Post, error) {
+	post, err := s.Posts.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.Published() {
+		return nil, mysql.ErrorPostNotExist
+	}
+	return post, nil
+}
+
+// GetPostByID 根据帖子的id来查询帖子的详细数据, 未发布的帖子只有作者可以查看
+func (s *Service) GetPostByID(id, userID int64) (data *models.ApiPostDetail, err error) {
 	//查询帖子的基本信息
-	post, err := mysql.GetPostByID(id)
+	post, err := s.Posts.GetPostByID(id)
 	if err != nil {
-		zap.L().Error("mysql.GetPostById failed", zap.Error(err))
+		s.log.Error("mysql.GetPostById failed", zap.Error(err))
 		return nil, err
 	}
+	if !post.Published() && post.AuthorID != userID {
+		return nil, mysql.ErrorPostNotExist
+	}
+	// 增加HTML之前发的帖子读取时渲染
+	if post.ContentHTML == "" {
+		post.ContentHTML = markdown.Render(post.Content)
+	}
 
 	// 根据帖子的作者id查询作者的姓名
-	user, err := mysql.GetUserByID(post.AuthorID)
+	user, err := s.Users.GetUserByID(post.AuthorID)
 	if err != nil {
-		zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+		s.log.Error("mysql.GetUserByID failed", zap.Error(err))
 		return
 	}
 
 	// 根据社区id查询社区的详细信息
-	communityDetail, err := mysql.GetCommunityDetail(post.CommunityID)
+	communityDetail, err := s.Communities.GetCommunityDetail(post.CommunityID)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityDetail failed", zap.Error(err))
+		s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
 		return
 	}
 
 	// 从redis获取投票数
-	voteData, err := redis.GetPostVoteList([]string{strconv.FormatInt(post.ID, 10)})
+	voteData, err := s.Votes.GetPostVoteList([]string{strconv.FormatInt(post.ID, 10)})
 	if err != nil {
-		zap.L().Error("redis.GetPostVoteList failed", zap.Error(err))
+		s.log.Error("redis.GetPostVoteList failed", zap.Error(err))
 		// 不影响主流程，默认为0
 		voteData = []int64{0}
 	}
@@ -65,167 +158,290 @@ func GetPostByID(id, userID int64) (data *models.ApiPostDetail, err error) {
 	// 获取当前用户的投票状态
 	var voteStatus int32
 	if userID > 0 {
-		status, err := redis.GetPostVoteForUser(strconv.FormatInt(userID, 10), strconv.FormatInt(post.ID, 10))
-		if err != nil && err != redis.Nil {
-			zap.L().Error("redis.GetPostVoteForUser failed", zap.Error(err))
+		status, err := s.Votes.GetPostVoteForUser(s.VoterID(post, userID), strconv.FormatInt(post.ID, 10))
+		if err != nil {
+			s.log.Error("redis.GetPostVoteForUser failed", zap.Error(err))
 		} else {
 			voteStatus = int32(status)
 		}
 	}
 
+	// 投票帖的结果, 出错时不影响帖子详情
+	var pollResult *models.ApiPollResult
+	if post.Poll != nil {
+		if pollResult, err = s.pollResult(post, userID); err != nil {
+			s.log.Error("get poll result failed", zap.Error(err))
+			err = nil
+		}
+	}
+
+	// 决策帖的计票结果
+	var tally *models.ApiDecisionTally
+	if post.Decision != nil {
+		if tally, err = s.decisionTally(post, userID); err != nil {
+			s.log.Error("get decision tally failed", zap.Error(err))
+			err = nil
+		}
+	}
+
 	// 将数据组合到模型中
 	data = &models.ApiPostDetail{
 		AuthorName:      user.Username,
+		AuthorKarma:     s.getAuthorKarma([]*models.Post{post})[0],
 		VoteNum:         voteData[0],
 		VoteStatus:      voteStatus,
+		PollResult:      pollResult,
+		DecisionTally:   tally,
 		Post:            post,
 		CommunityDetail: communityDetail,
 	}
 	return
 }
 
-// GetPostList 获取所有帖子的列表logic
-func GetPostList(page int64, size int64) (data []*models.ApiPostDetail, err error) {
+// GetPostList 获取所有帖子的列表logic, userID大于0时返回当前用户的投票状态
+func (s *Service) GetPostList(page int64, size int64, userID int64) (data []*models.ApiPostDetail, err error) {
 	var user *models.User
 	var community *models.CommunityDetail
 	var posts []*models.Post
 
-	posts, err = mysql.GetPostList(page, size)
+	posts, err = s.Posts.GetPostList(page, size)
 	if err != nil {
-		zap.L().Error("mysql.GetPostList failed", zap.Error(err))
+		s.log.Error("mysql.GetPostList failed", zap.Error(err))
 		return
 	}
 
+	karma := s.getAuthorKarma(posts)
 	data = make([]*models.ApiPostDetail, len(posts))
 	for idx, post := range posts {
 
 		// 根据作者id查找作者信息
-		user, err = mysql.GetUserByID(post.AuthorID)
+		user, err = s.Users.GetUserByID(post.AuthorID)
 		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+			s.log.Error("mysql.GetUserByID failed", zap.Error(err))
 			return
 		}
 		// 根据社区id查找社区信息
-		community, err = mysql.GetCommunityDetail(post.CommunityID)
+		community, err = s.Communities.GetCommunityDetail(post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetail failed", zap.Error(err))
+			s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
 			return
 		}
 		postDetail := &models.ApiPostDetail{
 			AuthorName:      user.Username,
+			AuthorKarma:     karma[idx],
 			Post:            post,
 			CommunityDetail: community,
 		}
 		data[idx] = postDetail
 	}
+	s.setVoteStatus(userID, data)
 	return
 }
 
 // GetPostList根据指定顺序获取帖子列表logic
-func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+func (s *Service) GetPostList2(p *models.ParamPostList, userID int64) (data []*models.ApiPostDetail, err error) {
 
 	// 去redis查询ids
-	ids, err := redis.GetPostIDsInOrder(p)
+	ids, err := s.Votes.GetPostIDsInOrder(p)
 	if err != nil {
 		return
 	}
 
 	// 根据ids去MYSQL中查询帖子的详细信息
-	posts, err := mysql.GetPostListsByIDs(ids)
+	posts, err := s.Posts.GetPostListsByIDs(ids)
 	if err != nil {
 		return
 	}
 
 	// 根据ids查找每个帖子有多少赞成票
-	voteData, err := redis.GetPostVoteList(ids)
+	voteData, err := s.Votes.GetPostVoteList(ids)
 	if err != nil {
 		return
 	}
 
+	karma := s.getAuthorKarma(posts)
 	data = make([]*models.ApiPostDetail, len(posts))
 	for idx, post := range posts {
 		// 根据作者id查找作者信息
-		user, err := mysql.GetUserByID(post.AuthorID)
+		user, err := s.Users.GetUserByID(post.AuthorID)
 		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+			s.log.Error("mysql.GetUserByID failed", zap.Error(err))
 			return nil, err
 		}
 
 		// 根据社区id查找社区信息
-		community, err := mysql.GetCommunityDetail(post.CommunityID)
+		community, err := s.Communities.GetCommunityDetail(post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetail failed", zap.Error(err))
+			s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
 			return nil, err
 		}
 
 		postDetail := &models.ApiPostDetail{
 			AuthorName:      user.Username,
+			AuthorKarma:     karma[idx],
 			VoteNum:         voteData[idx],
 			Post:            post,
 			CommunityDetail: community,
 		}
 		data[idx] = postDetail
 	}
+	s.setVoteStatus(userID, data)
 	return
 }
 
 // GetCommunityList 按社区获取帖子的详情
-func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+func (s *Service) GetCommunityPostList(p *models.ParamPostList, userID int64) (data []*models.ApiPostDetail, err error) {
 	// 去redis查询ids
-	ids, err := redis.GetCommunityPostIDsInOrder(p)
+	ids, err := s.Votes.GetCommunityPostIDsInOrder(p)
 	if err != nil {
 		return
 	}
 
 	// 根据ids去MYSQL中查询帖子的详细信息
-	posts, err := mysql.GetPostListsByIDs(ids)
+	posts, err := s.Posts.GetPostListsByIDs(ids)
 	if err != nil {
 		return
 	}
 
 	// 根据ids查找每个帖子有多少赞成票
-	voteData, err := redis.GetPostVoteList(ids)
+	voteData, err := s.Votes.GetPostVoteList(ids)
 	if err != nil {
 		return
 	}
 
+	karma := s.getAuthorKarma(posts)
 	data = make([]*models.ApiPostDetail, len(posts))
 	for idx, post := range posts {
 		// 根据作者id查找作者信息
-		user, err := mysql.GetUserByID(post.AuthorID)
+		user, err := s.Users.GetUserByID(post.AuthorID)
 		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed", zap.Error(err))
+			s.log.Error("mysql.GetUserByID failed", zap.Error(err))
 			return nil, err
 		}
 
 		// 根据社区id查找社区信息
-		community, err := mysql.GetCommunityDetail(post.CommunityID)
+		community, err := s.Communities.GetCommunityDetail(post.CommunityID)
 		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetail failed", zap.Error(err))
+			s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
 			return nil, err
 		}
 
 		postDetail := &models.ApiPostDetail{
 			AuthorName:      user.Username,
+			AuthorKarma:     karma[idx],
 			VoteNum:         voteData[idx],
 			Post:            post,
 			CommunityDetail: community,
 		}
 		data[idx] = postDetail
 	}
+	s.setVoteStatus(userID, data)
 	return
 }
 
-// GetPostListNew 按社区按顺序查询所有帖子的详情
-func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+// GetPostListNew 按社区按顺序查询所有帖子的详情, 可以按标签筛选
+func (s *Service) GetPostListNew(p *models.ParamPostList, userID int64) (data []*models.ApiPostDetail, err error) {
+	if p.Tag != "" {
+		if p.Tag = normalizeTag(p.Tag); p.Tag == "" {
+			return nil, ErrInvalidTag
+		}
+	}
 	// 未按社区查询
 	if p.CommunityID == 0 {
-		data, err = GetPostList2(p)
+		data, err = s.GetPostList2(p, userID)
 	} else {
-		data, err = GetCommunityPostList(p)
+		data, err = s.GetCommunityPostList(p, userID)
 	}
 	if err != nil {
 		return nil, err
 	}
 	return data, err
 }
+
+// getAuthorKarma 批量获取帖子作者的karma, 出错时不影响主流程, 默认为0
+func (s *Service) getAuthorKarma(posts []*models.Post) []int64 {
+	ids := make([]string, len(posts))
+	for idx, post := range posts {
+		ids[idx] = strconv.FormatInt(post.AuthorID, 10)
+	}
+	karma, err := s.Votes.GetUserKarmaList(ids)
+	if err != nil {
+		s.log.Error("redis.GetUserKarmaList failed", zap.Error(err))
+		return make([]int64, len(posts))
+	}
+	return karma
+}
+
+// GetUserVotedPosts 按投票时间从新到旧获取用户投过票的帖子, vote_status为投票方向
+func (s *Service) GetUserVotedPosts(userID, page, size int64) (data []*models.ApiPostDetail, err error) {
+	ids, err := s.Votes.GetUserVotedPostIDs(strconv.FormatInt(userID, 10), page, size)
+	if err != nil {
+		s.log.Error("redis.GetUserVotedPostIDs failed", zap.Error(err))
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*models.ApiPostDetail{}, nil
+	}
+
+	posts, err := s.Posts.GetPostListsByIDs(ids)
+	if err != nil {
+		s.log.Error("mysql.GetPostListsByIDs failed", zap.Error(err))
+		return nil, err
+	}
+
+	// 帖子可能已经不存在, 使用查询到的帖子重新获取投票数
+	ids = make([]string, len(posts))
+	for idx, post := range posts {
+		ids[idx] = strconv.FormatInt(post.ID, 10)
+	}
+	voteData, err := s.Votes.GetPostVoteList(ids)
+	if err != nil {
+		s.log.Error("redis.GetPostVoteList failed", zap.Error(err))
+		return nil, err
+	}
+
+	karma := s.getAuthorKarma(posts)
+	data = make([]*models.ApiPostDetail, len(posts))
+	for idx, post := range posts {
+		user, err := s.Users.GetUserByID(post.AuthorID)
+		if err != nil {
+			s.log.Error("mysql.GetUserByID failed", zap.Error(err))
+			return nil, err
+		}
+		community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+		if err != nil {
+			s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
+			return nil, err
+		}
+		data[idx] = &models.ApiPostDetail{
+			AuthorName:      user.Username,
+			AuthorKarma:     karma[idx],
+			VoteNum:         voteData[idx],
+			Post:            post,
+			CommunityDetail: community,
+		}
+	}
+	s.setVoteStatus(userID, data)
+	return data, nil
+}
+
+// setVoteStatus 批量填充当前用户的投票状态, 未登录时不处理, 出错时不影响主流程
+func (s *Service) setVoteStatus(userID int64, data []*models.ApiPostDetail) {
+	if userID <= 0 || len(data) == 0 {
+		return
+	}
+	ids := make([]string, len(data))
+	voters := make([]string, len(data))
+	for idx, d := range data {
+		ids[idx] = strconv.FormatInt(d.Post.ID, 10)
+		voters[idx] = s.VoterID(d.Post, userID)
+	}
+	status, err := s.Votes.GetPostVotesForUser(voters, ids)
+	if err != nil {
+		s.log.Error("redis.GetPostVotesForUser failed", zap.Error(err))
+		return
+	}
+	for idx, d := range data {
+		d.VoteStatus = int32(status[idx])
+	}
+}
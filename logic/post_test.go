@@ -0,0 +1,124 @@
+package logic_test
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCreatePostAndList(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+
+	first := env.createPost(t, alice, 1, "first")
+	second := env.createPost(t, alice, 2, "second")
+	third := env.createPost(t, alice, 1, "third")
+
+	tests := []struct {
+		name string
+		p    *models.ParamPostList
+		want []int64
+	}{
+		{"all by time", &models.ParamPostList{Page: 1, Size: 10, Order: models.OrderTime}, []int64{third, second, first}},
+		{"paged", &models.ParamPostList{Page: 2, Size: 2, Order: models.OrderTime}, []int64{first}},
+		{"community", &models.ParamPostList{CommunityID: 1, Page: 1, Size: 10, Order: models.OrderTime}, []int64{third, first}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := env.svc.GetPostListNew(tt.p, 0)
+			if err != nil {
+				t.Fatalf("GetPostListNew failed: %v", err)
+			}
+			if got := postIDs(data); !equalIDs(got, tt.want) {
+				t.Fatalf("post ids = %v, want %v", got, tt.want)
+			}
+			for _, d := range data {
+				if d.AuthorName != "alice" || d.CommunityDetail == nil || d.CommunityDetail.ID != d.Post.CommunityID {
+					t.Fatalf("post %d detail = %+v", d.Post.ID, d)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPostByID(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	pid := env.createPost(t, alice, 1, "hello")
+
+	if err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: formatID(pid), Direction: direction(1)}, "127.0.0.1"); err != nil {
+		t.Fatalf("VoteForPost failed: %v", err)
+	}
+
+	data, err := env.svc.GetPostByID(pid, bob)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	if data.VoteNum != 1 || data.VoteStatus != 1 || data.AuthorKarma != 1 {
+		t.Fatalf("post detail = %+v, want one upvote from current user", data)
+	}
+
+	if _, err := env.svc.GetPostByID(pid+1, 0); !errors.Is(err, mysql.ErrorPostNotExist) {
+		t.Fatalf("GetPostByID missing post: got %v, want %v", err, mysql.ErrorPostNotExist)
+	}
+}
+
+func postIDs(data []*models.ApiPostDetail) []int64 {
+	ids := make([]int64, len(data))
+	for i, d := range data {
+		ids[i] = d.Post.ID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPostMarkdown(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+
+	p := &models.Post{
+		AuthorID:    alice,
+		CommunityID: 1,
+		Title:       "markdown",
+		Content:     "**bold** [bad](javascript:alert(1)) <script>alert(1)</script>\n\n```go\nfmt.Println(\"<b>\")\n```",
+	}
+	if err := env.svc.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	data, err := env.svc.GetPostByID(p.ID, 0)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	html := data.Post.ContentHTML
+	for _, want := range []string{"<strong>bold</strong>", `<code class="language-go">`, "&lt;b&gt;"} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("html = %q, want %q", html, want)
+		}
+	}
+	for _, bad := range []string{"<script", "javascript:"} {
+		if strings.Contains(html, bad) {
+			t.Fatalf("html = %q, contains %q", html, bad)
+		}
+	}
+	// 原文保持不变
+	if data.Post.Content != p.Content {
+		t.Fatalf("content = %q, want markdown source", data.Post.Content)
+	}
+	if got := env.svc.PreviewContent(p.Content); got != html {
+		t.Fatalf("preview = %q, want stored html %q", got, html)
+	}
+}
@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"bluebell/models"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidAvatar 头像不是http或https链接
+var ErrInvalidAvatar = errors.New("头像必须是http或https链接")
+
+// GetUserDetail 查询用户主页: 公开资料, 票数和分页的帖子列表
+func (s *Service) GetUserDetail(userID, page, size int64) (data *models.ApiUserDetail, err error) {
+	profile, err := s.Users.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	karma, err := s.Votes.GetUserKarma(strconv.FormatInt(userID, 10))
+	if err != nil {
+		s.log.Error("redis.GetUserKarma failed", zap.Error(err))
+		return nil, err
+	}
+
+	posts, err := s.Posts.GetPostListByAuthor(userID, page, size)
+	if err != nil {
+		s.log.Error("mysql.GetPostListByAuthor failed", zap.Error(err))
+		return nil, err
+	}
+
+	ids := make([]string, len(posts))
+	for idx, post := range posts {
+		ids[idx] = strconv.FormatInt(post.ID, 10)
+	}
+	voteData := make([]int64, len(posts))
+	if len(ids) > 0 {
+		if voteData, err = s.Votes.GetPostVoteList(ids); err != nil {
+			s.log.Error("redis.GetPostVoteList failed", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	list := make([]*models.ApiPostDetail, len(posts))
+	for idx, post := range posts {
+		community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+		if err != nil {
+			s.log.Error("mysql.GetCommunityDetail failed", zap.Error(err))
+			return nil, err
+		}
+		list[idx] = &models.ApiPostDetail{
+			AuthorName:      profile.Username,
+			AuthorKarma:     karma,
+			VoteNum:         voteData[idx],
+			Post:            post,
+			CommunityDetail: community,
+		}
+	}
+
+	return &models.ApiUserDetail{
+		UserProfile: profile,
+		Karma:       karma,
+		Posts:       list,
+	}, nil
+}
+
+// GetMyProfile 查询当前用户自己的资料, 包括邮箱和验证状态
+func (s *Service) GetMyProfile(userID int64) (*models.ApiMyProfile, error) {
+	profile, err := s.Users.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	email, err := s.Users.GetUserEmail(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApiMyProfile{
+		UserProfile:   profile,
+		Email:         email.Email,
+		EmailVerified: email.Verified,
+	}, nil
+}
+
+// UpdateProfile 修改当前用户的资料, 返回修改之后的资料
+func (s *Service) UpdateProfile(userID int64, p *models.ParamUpdateProfile) (*models.UserProfile, error) {
+	if p.Avatar != nil && !validAvatar(*p.Avatar) {
+		return nil, ErrInvalidAvatar
+	}
+	if err := s.Users.UpdateUserProfile(userID, p); err != nil {
+		return nil, err
+	}
+	return s.Users.GetUserProfile(userID)
+}
+
+// validAvatar 头像为空表示清除, 否则必须是http或https的绝对地址, 不能是javascript:或data:链接
+func validAvatar(avatar string) bool {
+	if avatar == "" {
+		return true
+	}
+	u, err := url.Parse(avatar)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
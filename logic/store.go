@@ -0,0 +1,186 @@
+package logic
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/mailer"
+	"bluebell/setting"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// UserStore 用户数据的存储, 由dao/mysql实现
+type UserStore interface {
+	CheckUserExist(username string) error
+	InsertUser(user *models.User) error
+	Login(user *models.User) error
+	GetUserByID(id int64) (*models.User, error)
+	GetUserByUsername(username string) (*models.User, error)
+	GetUserProfile(id int64) (*models.UserProfile, error)
+	UpdateUserProfile(id int64, p *models.ParamUpdateProfile) error
+	SetUserRole(id int64, role int8) error
+	GetUserEmail(id int64) (*models.UserEmail, error)
+	GetUserByEmail(email string) (*models.User, error)
+	SetUserEmail(id int64, email string) error
+	SetEmailVerified(id int64, email string) error
+	UpdatePassword(id int64, password string) error
+
+	InsertLoginAttempt(a *models.LoginAttempt) error
+	GetLoginAttempts(username string, page, size int64) ([]*models.LoginAttempt, error)
+
+	SetTOTPSecret(userID int64, secret string) error
+	EnableTOTP(userID int64, codeHashes []string) error
+	DisableTOTP(userID int64) error
+	UseRecoveryCode(userID int64, codeHash string) error
+}
+
+// PostStore 帖子数据的存储, 由dao/mysql实现
+type PostStore interface {
+	CreatePost(p *models.Post) error
+	GetPostByID(id int64) (*models.Post, error)
+	GetPostList(page, size int64) ([]*models.Post, error)
+	GetPostListsByIDs(ids []string) ([]*models.Post, error)
+	GetPostListByAuthor(authorID, page, size int64) ([]*models.Post, error)
+	GetDraftsByAuthor(authorID, page, size int64) ([]*models.Post, error)
+	GetDuePosts(now time.Time) ([]*models.Post, error)
+	UpdateDraft(p *models.Post) error
+	PublishPost(postID int64, publishTime time.Time) error
+	UnpublishPost(p *models.Post) error
+	SetPostTags(postID int64, tags []string) error
+	CloseDecision(postID int64, closeTime time.Time) error
+	InsertBallot(b *models.Ballot) error
+	GetBallots(postID int64) ([]*models.Ballot, error)
+	GetBallot(postID int64, voter string) (*models.Ballot, error)
+}
+
+// CommunityStore 社区数据的存储, 由dao/mysql实现
+type CommunityStore interface {
+	GetCommunityList() ([]*models.Community, error)
+	GetCommunityDetail(id int64) (*models.CommunityDetail, error)
+	CreateCommunity(name, introduction string) (*models.CommunityDetail, error)
+	UpdateCommunityVoteRule(id int64, rule *models.CommunityVoteRule) error
+	FollowCommunity(userID, communityID int64) error
+	UnfollowCommunity(userID, communityID int64) error
+	GetCommunityFollowers(communityID int64) ([]int64, error)
+	GetFollowedCommunities(userID int64) ([]int64, error)
+	GetCommunityTags(communityID int64) ([]*models.CommunityTag, error)
+	CreateCommunityTag(tag *models.CommunityTag) error
+	DeleteCommunityTag(communityID int64, name string) error
+}
+
+// VoteStore 帖子排序、投票以及karma的存储, 由dao/redis实现
+type VoteStore interface {
+	CreatePost(p *models.Post) error
+	GetPostIDsInOrder(p *models.ParamPostList) ([]string, error)
+	GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error)
+	UpdatePostTags(p *models.Post, oldTags []string) error
+	GetCommunityTagCounts(communityID int64) (map[string]int64, error)
+	GetPostVoteList(ids []string) ([]int64, error)
+	VoteForPost(voter models.Voter, post *models.Post, dir float64, rule models.VoteRule) error
+	GetPostVoteForUser(userID, postID string) (float64, error)
+	GetPostVotesForUser(userIDs, postIDs []string) ([]float64, error)
+	GetUserVotedPostIDs(userID string, page, size int64) ([]string, error)
+	GetUserKarma(userID string) (int64, error)
+	GetUserKarmaList(userIDs []string) ([]int64, error)
+	NullifyVote(userID, postID, authorID string, scorePerVote float64) error
+	CastPollBallot(postID int64, voter string, choices []int) error
+	GetPollTally(postID int64, options int) ([]int64, int64, error)
+	GetPollBallot(postID int64, voter string) ([]int, error)
+}
+
+// AuditStore 投票记录和可疑投票的审核队列, 由dao/mysql实现
+type AuditStore interface {
+	InsertVoteRecord(r *models.VoteRecord) error
+	GetVoteRecordsSince(since time.Time) ([]*models.VoteRecord, error)
+	InsertVoteFlags(flags []*models.VoteFlag) (int64, error)
+	GetVoteFlags(status int8, page, size int64) ([]*models.VoteFlag, error)
+	GetVoteFlagByID(id int64) (*models.VoteFlag, error)
+	ReviewVoteFlags(postID, userID int64, status int8, reviewerID int64) error
+	IsVoteNullified(postID, userID int64) (bool, error)
+}
+
+// AuthStore 登录保护、两步验证和邮件链接的临时状态, 由dao/redis实现
+type AuthStore interface {
+	GetLoginLockTTL(username, ip string) (time.Duration, error)
+	GetLoginFailures(username, ip string) (accountFails, ipFails int64, err error)
+	IncrLoginFailures(username, ip string, window time.Duration) (accountFails, ipFails int64, err error)
+	LockAccount(username string, d time.Duration) error
+	LockIP(ip string, d time.Duration) error
+	ClearLoginFailures(username string) error
+	UnlockAccount(username string) error
+	UnlockIP(ip string) error
+
+	MarkTOTPStepUsed(userID, step int64, ttl time.Duration) (bool, error)
+	GetRequireMFAForModerators() (bool, error)
+	SetRequireMFAForModerators(require bool) error
+
+	SaveMailToken(purpose, tokenHash, value string, ttl time.Duration) error
+	ConsumeMailToken(purpose, tokenHash string) (string, error)
+}
+
+// NotificationStore 站内通知和通知设置, 由dao/mysql实现
+type NotificationStore interface {
+	InsertNotifications(ns []*models.Notification) error
+	GetNotifications(userID int64, unreadOnly bool, page, size int64) ([]*models.Notification, error)
+	CountUnreadNotifications(userID int64) (int64, error)
+	MarkNotificationRead(userID, id int64) error
+	MarkAllNotificationsRead(userID int64) error
+	GetNotificationPrefs(userID int64) (map[string]bool, error)
+	SetNotificationPrefs(userID int64, prefs map[string]bool) error
+	GetNotificationDisabledUsers(typ string, userIDs []int64) (map[int64]bool, error)
+	GetNotificationEnabledUsers(typ string) ([]int64, error)
+}
+
+// EventPublisher 实时事件的发布, 由dao/redis实现, 通过pub/sub发送给所有实例
+type EventPublisher interface {
+	PublishEvent(e *models.Event) error
+}
+
+// IDGenerator 分布式id生成器, 由pkg/snowflake实现
+type IDGenerator interface {
+	NextID() int64
+}
+
+// Stores 业务逻辑依赖的全部存储
+type Stores struct {
+	Users         UserStore
+	Posts         PostStore
+	Communities   CommunityStore
+	Votes         VoteStore
+	Auth          AuthStore
+	Audit         AuditStore
+	Events        EventPublisher
+	Notifications NotificationStore
+}
+
+// Service 业务逻辑, 通过接口访问存储, 方便替换成内存实现做单元测试
+type Service struct {
+	Stores
+	cfg    *setting.Holder
+	log    *zap.Logger
+	ids    IDGenerator
+	tokens *jwt.Manager
+	mailer mailer.Mailer
+	now    func() time.Time
+	mails  sync.WaitGroup // 后台发送中的邮件
+}
+
+// NewService 创建业务逻辑对象, 所有依赖都由调用方传入
+func NewService(cfg *setting.Holder, log *zap.Logger, stores Stores, ids IDGenerator, tokens *jwt.Manager, m mailer.Mailer, now func() time.Time) *Service {
+	return &Service{
+		Stores: stores,
+		cfg:    cfg,
+		log:    log,
+		ids:    ids,
+		tokens: tokens,
+		mailer: m,
+		now:    now,
+	}
+}
+
+// WaitMails 等待后台发送的邮件全部完成, 关闭存储之前调用
+func (s *Service) WaitMails() {
+	s.mails.Wait()
+}
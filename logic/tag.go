@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"bluebell/models"
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidTag    = errors.New("标签格式不正确")
+	ErrTagNotAllowed = errors.New("该社区只能使用社区定义的标签")
+)
+
+// maxPostTags 一个帖子最多的标签数
+const maxPostTags = 5
+
+// tagRe 标签只能包含文字、数字、下划线和中划线
+var tagRe = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+// normalizeTag 去掉首尾空格并转成小写, 格式不正确时返回空字符串
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagRe.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
+
+// checkPostTags 整理帖子的标签并去重, 社区定义了标签时只能使用定义的标签, 否则可以使用任意标签
+func (s *Service) checkPostTags(communityID int64, tags []string) ([]string, error) {
+	list := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		t := normalizeTag(tag)
+		if t == "" {
+			return nil, ErrInvalidTag
+		}
+		if !slices.Contains(list, t) {
+			list = append(list, t)
+		}
+	}
+	if len(list) > maxPostTags {
+		return nil, ErrInvalidTag
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	defined, err := s.Communities.GetCommunityTags(communityID)
+	if err != nil {
+		return nil, err
+	}
+	if len(defined) == 0 {
+		return list, nil
+	}
+	for _, t := range list {
+		if !slices.ContainsFunc(defined, func(d *models.CommunityTag) bool { return d.Name == t }) {
+			return nil, ErrTagNotAllowed
+		}
+	}
+	return list, nil
+}
+
+// UpdatePostTags 修改已发布帖子的标签, 只有作者、版主和管理员可以修改, 返回修改后的标签
+// 草稿的标签通过UpdateDraft修改
+func (s *Service) UpdatePostTags(postID, userID int64, tags []string) ([]string, error) {
+	post, err := s.getPublishedPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPostEditor(post, userID); err != nil {
+		return nil, err
+	}
+
+	if tags, err = s.checkPostTags(post.CommunityID, tags); err != nil {
+		return nil, err
+	}
+	if err := s.Posts.SetPostTags(postID, tags); err != nil {
+		s.log.Error("mysql.SetPostTags failed", zap.Error(err))
+		return nil, err
+	}
+	oldTags := post.Tags
+	post.Tags = tags
+	if err := s.Votes.UpdatePostTags(post, oldTags); err != nil {
+		s.log.Error("redis.UpdatePostTags failed", zap.Error(err))
+		// redis中还是旧的标签, 恢复mysql中的标签保持两边一致
+		if rerr := s.Posts.SetPostTags(postID, oldTags); rerr != nil {
+			s.log.Error("mysql.SetPostTags rollback failed", zap.Int64("post_id", postID), zap.Error(rerr))
+		}
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetCommunityTags 查询社区的标签及每个标签的帖子数, 包括社区定义的标签和帖子中使用的自由标签
+// 按帖子数从多到少排序
+func (s *Service) GetCommunityTags(communityID int64) ([]*models.ApiTagCount, error) {
+	if _, err := s.Communities.GetCommunityDetail(communityID); err != nil {
+		return nil, err
+	}
+	defined, err := s.Communities.GetCommunityTags(communityID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.Votes.GetCommunityTagCounts(communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*models.ApiTagCount, 0, len(defined)+len(counts))
+	for _, d := range defined {
+		list = append(list, &models.ApiTagCount{
+			Name:        d.Name,
+			Color:       d.Color,
+			Description: d.Description,
+			Defined:     true,
+			PostNum:     counts[d.Name],
+		})
+		delete(counts, d.Name)
+	}
+	for name, n := range counts {
+		list = append(list, &models.ApiTagCount{Name: name, PostNum: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].PostNum != list[j].PostNum {
+			return list[i].PostNum > list[j].PostNum
+		}
+		// 帖子数相同时社区定义的标签排在前面
+		if list[i].Defined != list[j].Defined {
+			return list[i].Defined
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// CreateCommunityTag 给社区定义一个标签, 定义之后社区只能使用定义的标签
+func (s *Service) CreateCommunityTag(communityID int64, p *models.ParamCommunityTag) (*models.CommunityTag, error) {
+	if _, err := s.Communities.GetCommunityDetail(communityID); err != nil {
+		return nil, err
+	}
+	name := normalizeTag(p.Name)
+	if name == "" {
+		return nil, ErrInvalidTag
+	}
+	tag := &models.CommunityTag{
+		CommunityID: communityID,
+		Name:        name,
+		Color:       p.Color,
+		Description: p.Description,
+	}
+	if err := s.Communities.CreateCommunityTag(tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteCommunityTag 删除社区定义的标签, 已经使用该标签的帖子不受影响
+func (s *Service) DeleteCommunityTag(communityID int64, name string) error {
+	return s.Communities.DeleteCommunityTag(communityID, normalizeTag(name))
+}
@@ -4,18 +4,49 @@ import (
 	"bluebell/dao/mysql"
 	"bluebell/models"
 	"bluebell/pkg/jwt"
-	"bluebell/pkg/snowflake"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-// SignUp 用户注册信息的logic
-func SignUp(p *models.ParamSignUp) error {
-	// 1 判断用户存在不存在
-	if err := mysql.CheckUserExist(p.Username); err != nil {
+// ErrLoginLocked 登录失败次数过多, 账号或ip被临时锁定
+type ErrLoginLocked struct {
+	RetryAfter time.Duration // 距离解锁还有多久
+}
+
+func (e *ErrLoginLocked) Error() string {
+	return "登录失败次数过多,请稍后再试"
+}
+
+// SignUp 用户注册信息的logic, 填写了邮箱时发送验证邮件, 发送失败不影响注册
+func (s *Service) SignUp(p *models.ParamSignUp) error {
+	userID, err := s.CreateUser(p, models.RoleUser)
+	if err != nil || p.Email == "" {
 		return err
 	}
+	if err := s.sendVerifyEmail(userID, p.Username, p.Email); err != nil {
+		s.log.Error("send verify email failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
+
+// CreateUser 创建指定角色的用户, 返回用户id, 命令行创建管理员时使用
+func (s *Service) CreateUser(p *models.ParamSignUp, role int8) (int64, error) {
+	// 1 判断用户存在不存在
+	if err := s.Users.CheckUserExist(p.Username); err != nil {
+		return 0, err
+	}
+	if p.Email != "" {
+		if _, err := s.Users.GetUserByEmail(p.Email); err == nil {
+			return 0, mysql.ErrorEmailExist
+		} else if !errors.Is(err, mysql.ErrorUserNotExist) {
+			return 0, err
+		}
+	}
 
 	// 2生成UID
-	userID := snowflake.GenID()
+	userID := s.ids.NextID()
 
 	// 构造一个user实例
 	user := &models.User{
@@ -25,23 +56,177 @@ func SignUp(p *models.ParamSignUp) error {
 	}
 
 	// 3 将user保存在数据库当中去
-	return mysql.InsertUser(user)
+	if err := s.Users.InsertUser(user); err != nil {
+		return 0, err
+	}
+	if p.Email != "" {
+		if err := s.Users.SetUserEmail(userID, p.Email); err != nil {
+			return 0, err
+		}
+	}
+	if role != models.RoleUser {
+		if err := s.Users.SetUserRole(userID, role); err != nil {
+			return 0, err
+		}
+	}
+	return userID, nil
 }
 
-// Login 用户登录的logic
-func Login(p *models.ParamLogin) (string, int64, string, error) {
+// Login 用户登录的logic, ip用于登录失败的计数和审计
+// 开启了两步验证的用户只会拿到一个短期的mfa_token
+func (s *Service) Login(p *models.ParamLogin, ip string) (*models.ApiLoginResult, error) {
+	// 1 账号或ip处于锁定期, 直接拒绝
+	ttl, err := s.Auth.GetLoginLockTTL(p.Username, ip)
+	if err != nil {
+		// redis不可用时不影响登录
+		s.log.Error("redis.GetLoginLockTTL failed", zap.Error(err))
+	} else if ttl > 0 {
+		s.recordLoginAttempt(0, p.Username, ip, models.LoginReasonLocked)
+		return nil, &ErrLoginLocked{RetryAfter: ttl}
+	}
+
+	// 2 账号或ip连续失败多次之后逐步增加响应延迟, 拖慢暴力破解, 按两者中较多的失败次数计算
+	if accountFails, ipFails, err := s.Auth.GetLoginFailures(p.Username, ip); err == nil {
+		time.Sleep(s.loginDelay(max(accountFails, ipFails)))
+	}
+
 	user := &models.User{
 		Username: p.Username,
 		Password: p.Password,
 	}
 
-	// 进行数据库层面的处理
-	if err := mysql.Login(user); err != nil {
-		return "", 0, "", err
+	// 3 进行数据库层面的处理
+	if err := s.Users.Login(user); err != nil {
+		var reason string
+		switch {
+		case errors.Is(err, mysql.ErrorUserNotExist):
+			reason = models.LoginReasonUserNotExist
+		case errors.Is(err, mysql.ErrorInvalidPassword):
+			reason = models.LoginReasonWrongPassword
+		default:
+			return nil, err
+		}
+		s.recordLoginAttempt(user.UserID, p.Username, ip, reason)
+		s.onLoginFailure(p.Username, ip)
+		return nil, err
+	}
+
+	result := &models.ApiLoginResult{
+		UserID:   user.UserID,
+		Username: user.Username,
+	}
+
+	// 4 开启了两步验证, 等待第二步
+	if user.TOTPEnabled {
+		s.recordLoginAttempt(user.UserID, user.Username, ip, models.LoginReasonMFAPending)
+		result.MFARequired = true
+		result.MFAToken, err = s.tokens.GenPurposeToken(user.UserID, user.Username, jwt.PurposeMFA, mfaTokenExpire)
+		return result, err
 	}
-	token, err := jwt.GenToken(user.UserID, user.Username)
+
+	if err := s.Auth.ClearLoginFailures(p.Username); err != nil {
+		s.log.Error("redis.ClearLoginFailures failed", zap.Error(err))
+	}
+	s.recordLoginAttempt(user.UserID, p.Username, ip, models.LoginReasonSuccess)
+
+	// 5 被要求开启两步验证但还没有绑定, 只能访问绑定接口
+	if s.mfaRequiredForRole(user.Role) {
+		result.MFAEnrollRequired = true
+		result.MFAToken, err = s.tokens.GenPurposeToken(user.UserID, user.Username, jwt.PurposeMFAEnroll, mfaEnrollTokenExpire)
+		return result, err
+	}
+
+	result.Token, err = s.tokens.GenToken(user.UserID, user.Username)
 	if err != nil {
-		return "", 0, "", err
+		return nil, err
 	}
-	return token, user.UserID, user.Username, nil
+	return result, nil
+}
+
+// loginDelay 根据失败次数计算登录的延迟时间, 超过阈值后每失败一次翻倍
+func (s *Service) loginDelay(fails int64) time.Duration {
+	after := s.cfg.Get().Auth.LoginGuard.DelayAfter
+	if after <= 0 || fails < after {
+		return 0
+	}
+	delay := time.Duration(s.cfg.Get().Auth.LoginGuard.DelayBaseMs) * time.Millisecond
+	max := time.Duration(s.cfg.Get().Auth.LoginGuard.DelayMaxMs) * time.Millisecond
+	for i := after; i < fails && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// onLoginFailure 记录登录失败, 达到阈值时锁定账号或ip
+func (s *Service) onLoginFailure(username, ip string) {
+	window := time.Duration(s.cfg.Get().Auth.LoginGuard.Window) * time.Second
+	lockDuration := time.Duration(s.cfg.Get().Auth.LoginGuard.LockDuration) * time.Second
+
+	accountFails, ipFails, err := s.Auth.IncrLoginFailures(username, ip, window)
+	if err != nil {
+		s.log.Error("redis.IncrLoginFailures failed", zap.Error(err))
+		return
+	}
+
+	if threshold := s.cfg.Get().Auth.LoginGuard.AccountLockThreshold; threshold > 0 && accountFails >= threshold {
+		s.log.Warn("account locked", zap.String("username", username), zap.Int64("fails", accountFails))
+		if err := s.Auth.LockAccount(username, lockDuration); err != nil {
+			s.log.Error("redis.LockAccount failed", zap.Error(err))
+		}
+	}
+	if threshold := s.cfg.Get().Auth.LoginGuard.IPLockThreshold; threshold > 0 && ipFails >= threshold {
+		s.log.Warn("ip locked", zap.String("ip", ip), zap.Int64("fails", ipFails))
+		if err := s.Auth.LockIP(ip, lockDuration); err != nil {
+			s.log.Error("redis.LockIP failed", zap.Error(err))
+		}
+	}
+}
+
+// recordLoginAttempt 写入登录审计记录, 写入失败不影响登录流程
+func (s *Service) recordLoginAttempt(userID int64, username, ip, reason string) {
+	attempt := &models.LoginAttempt{
+		UserID:   userID,
+		Username: username,
+		IP:       ip,
+		Success:  reason == models.LoginReasonSuccess,
+		Reason:   reason,
+	}
+	if err := s.Users.InsertLoginAttempt(attempt); err != nil {
+		s.log.Error("mysql.InsertLoginAttempt failed", zap.Error(err))
+	}
+}
+
+// GetUserRole 获取用户的角色
+func (s *Service) GetUserRole(userID int64) (int8, error) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.Role, nil
+}
+
+// UnlockUser 管理员解除账号的登录锁定, ip不为空时同时解除该ip的锁定
+func (s *Service) UnlockUser(userID int64, ip string) error {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.Auth.UnlockAccount(user.Username); err != nil {
+		return err
+	}
+	if ip != "" {
+		if err := s.Auth.UnlockIP(ip); err != nil {
+			return err
+		}
+	}
+	s.notifyModeratorAction(userID, 0, nil, "你的账号已被管理员解除登录锁定")
+	return nil
+}
+
+// GetLoginAttempts 查询用户名的登录审计记录
+func (s *Service) GetLoginAttempts(username string, page, size int64) ([]*models.LoginAttempt, error) {
+	return s.Users.GetLoginAttempts(username, page, size)
 }
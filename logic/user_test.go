@@ -0,0 +1,180 @@
+package logic_test
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/totp"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignUp(t *testing.T) {
+	env := newTestEnv(t)
+	p := &models.ParamSignUp{Username: "alice", Password: "123456", RePassword: "123456"}
+
+	if err := env.svc.SignUp(p); err != nil {
+		t.Fatalf("SignUp failed: %v", err)
+	}
+	if err := env.svc.SignUp(p); !errors.Is(err, mysql.ErrorUserExist) {
+		t.Fatalf("SignUp duplicate user: got %v, want %v", err, mysql.ErrorUserExist)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	env := newTestEnv(t)
+	userID := env.signUp(t, "alice")
+
+	res, err := env.svc.Login(&models.ParamLogin{Username: "alice", Password: "123456"}, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if res.UserID != userID || res.Username != "alice" {
+		t.Fatalf("Login result = %+v, want user %d alice", res, userID)
+	}
+	mc, err := env.tokens.ParseToken(res.Token)
+	if err != nil {
+		t.Fatalf("ParseToken failed: %v", err)
+	}
+	if mc.UserID != userID || mc.Username != "alice" {
+		t.Fatalf("claims = %+v, want user %d alice", mc, userID)
+	}
+
+	tests := []struct {
+		name string
+		p    *models.ParamLogin
+		want error
+	}{
+		{"wrong password", &models.ParamLogin{Username: "alice", Password: "654321"}, mysql.ErrorInvalidPassword},
+		{"unknown user", &models.ParamLogin{Username: "bob", Password: "123456"}, mysql.ErrorUserNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := env.svc.Login(tt.p, "127.0.0.1"); !errors.Is(err, tt.want) {
+				t.Fatalf("Login: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	attempts, err := env.svc.GetLoginAttempts("alice", 1, 10)
+	if err != nil {
+		t.Fatalf("GetLoginAttempts failed: %v", err)
+	}
+	if len(attempts) != 3 || attempts[0].Reason != models.LoginReasonWrongPassword {
+		t.Fatalf("login attempts = %d, latest reason %q", len(attempts), attempts[0].Reason)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	env := newTestEnv(t)
+	userID := env.signUp(t, "alice")
+
+	wrong := &models.ParamLogin{Username: "alice", Password: "wrong"}
+	for i := 0; i < 3; i++ {
+		if _, err := env.svc.Login(wrong, "10.0.0.1"); !errors.Is(err, mysql.ErrorInvalidPassword) {
+			t.Fatalf("attempt %d: got %v, want %v", i, err, mysql.ErrorInvalidPassword)
+		}
+	}
+
+	// 锁定之后即使密码正确也无法登录
+	right := &models.ParamLogin{Username: "alice", Password: "123456"}
+	_, err := env.svc.Login(right, "10.0.0.2")
+	var locked *logic.ErrLoginLocked
+	if !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("Login after lockout: got %v, want ErrLoginLocked", err)
+	}
+
+	// 锁定到期后可以登录
+	env.auth.Now = func() time.Time { return time.Now().Add(901 * time.Second) }
+	if _, err := env.svc.Login(right, "10.0.0.2"); err != nil {
+		t.Fatalf("Login after lock expired failed: %v", err)
+	}
+
+	// 管理员解锁
+	env.auth.Now = time.Now
+	for i := 0; i < 3; i++ {
+		_, _ = env.svc.Login(wrong, "10.0.0.1")
+	}
+	if err := env.svc.UnlockUser(userID, ""); err != nil {
+		t.Fatalf("UnlockUser failed: %v", err)
+	}
+	if _, err := env.svc.Login(right, "10.0.0.2"); err != nil {
+		t.Fatalf("Login after unlock failed: %v", err)
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	env := newTestEnv(t)
+	userID := env.signUp(t, "alice")
+
+	enroll, err := env.svc.EnrollMFA(userID)
+	if err != nil {
+		t.Fatalf("EnrollMFA failed: %v", err)
+	}
+	code, err := totp.Code(enroll.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("totp.Code failed: %v", err)
+	}
+	activate, err := env.svc.ActivateMFA(userID, code)
+	if err != nil {
+		t.Fatalf("ActivateMFA failed: %v", err)
+	}
+	if len(activate.RecoveryCodes) == 0 {
+		t.Fatal("ActivateMFA returned no recovery codes")
+	}
+
+	res, err := env.svc.Login(&models.ParamLogin{Username: "alice", Password: "123456"}, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if !res.MFARequired || res.Token != "" || res.MFAToken == "" {
+		t.Fatalf("Login with MFA = %+v, want only mfa_token", res)
+	}
+
+	// 已经用过的验证码不能再次使用
+	_, err = env.svc.LoginMFA(&models.ParamLoginMFA{MFAToken: res.MFAToken, Code: code}, "127.0.0.1")
+	if !errors.Is(err, logic.ErrInvalidMFACode) {
+		t.Fatalf("LoginMFA with replayed code: got %v, want %v", err, logic.ErrInvalidMFACode)
+	}
+
+	recovery := activate.RecoveryCodes[0]
+	full, err := env.svc.LoginMFA(&models.ParamLoginMFA{MFAToken: res.MFAToken, Code: recovery}, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("LoginMFA with recovery code failed: %v", err)
+	}
+	if _, err := env.tokens.ParseToken(full.Token); err != nil {
+		t.Fatalf("ParseToken failed: %v", err)
+	}
+	_, err = env.svc.LoginMFA(&models.ParamLoginMFA{MFAToken: res.MFAToken, Code: recovery}, "127.0.0.1")
+	if !errors.Is(err, logic.ErrInvalidMFACode) {
+		t.Fatalf("LoginMFA with used recovery code: got %v, want %v", err, logic.ErrInvalidMFACode)
+	}
+}
+
+func TestUpdateProfileAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+
+	for _, tt := range []struct {
+		avatar string
+		want   error
+	}{
+		{"https://example.com/a.png", nil},
+		{"HTTP://example.com/a.png", nil},
+		{"", nil},
+		{"javascript:alert(1)", logic.ErrInvalidAvatar},
+		{"data:image/svg+xml;base64,PHN2Zz4=", logic.ErrInvalidAvatar},
+		{"//example.com/a.png", logic.ErrInvalidAvatar},
+		{"/static/a.png", logic.ErrInvalidAvatar},
+	} {
+		avatar := tt.avatar
+		profile, err := env.svc.UpdateProfile(alice, &models.ParamUpdateProfile{Avatar: &avatar})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("UpdateProfile(%q): got %v, want %v", tt.avatar, err, tt.want)
+		}
+		if err == nil && profile.Avatar != tt.avatar {
+			t.Fatalf("UpdateProfile(%q): avatar = %q", tt.avatar, profile.Avatar)
+		}
+	}
+}
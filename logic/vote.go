@@ -1,12 +1,134 @@
 package logic
 
 import (
-	"bluebell/dao/redis"
+	"bluebell/dao/mysql"
 	"bluebell/models"
+	"errors"
 	"strconv"
+	"time"
 )
 
-// VoteForPost 为帖子投票logic
-func VoteForPost(userID int64, p *models.ParamVoteData) error {
-	return redis.VoteForPost(strconv.Itoa(int(userID)), p.PostID, float64(*p.Direction))
+var (
+	// ErrKarmaTooLow karma不足, 无法进行该操作
+	ErrKarmaTooLow = errors.New("karma不足")
+	// ErrDownvoteDisabled 社区禁止反对票
+	ErrDownvoteDisabled = errors.New("该社区不允许投反对票")
+	// ErrAccountTooNew 注册时间太短, 不能在该社区投票
+	ErrAccountTooNew = errors.New("注册时间太短")
+	// ErrVoteNullified 投票被管理员作废过, 不能再给该帖子投票
+	ErrVoteNullified = errors.New("投票已被作废")
+)
+
+// VoteForPost 为帖子投票logic, 投票规则由帖子所在的社区决定, ip记录在投票记录中用于刷票分析
+func (s *Service) VoteForPost(userID int64, p *models.ParamVoteData, ip string) error {
+	postID, err := strconv.ParseInt(p.PostID, 10, 64)
+	if err != nil {
+		return mysql.ErrorInvalidID
+	}
+	// 查询帖子作者, 用来更新作者的karma
+	post, err := s.getPublishedPost(postID)
+	if err != nil {
+		return err
+	}
+	community, err := s.Communities.GetCommunityDetail(post.CommunityID)
+	if err != nil {
+		return err
+	}
+
+	// 取消投票不受限制
+	if *p.Direction != 0 {
+		if err := s.checkVoter(userID, *p.Direction, &community.CommunityVoteRule); err != nil {
+			return err
+		}
+		nullified, err := s.Audit.IsVoteNullified(post.ID, userID)
+		if err != nil {
+			return err
+		}
+		if nullified {
+			return ErrVoteNullified
+		}
+	}
+	rule := s.communityVoteRule(&community.CommunityVoteRule)
+	if err := s.Votes.VoteForPost(s.voter(post, userID), post, float64(*p.Direction), rule); err != nil {
+		return err
+	}
+	// 秘密投票不写投票记录, 投票记录中有用户id和ip
+	if !post.SecretBallot {
+		s.recordVote(post, userID, *p.Direction, ip)
+	}
+	if *p.Direction > 0 {
+		s.notifyVoteMilestone(post)
+	}
+	return nil
+}
+
+// checkVoter 判断用户是否满足社区的投票条件
+func (s *Service) checkVoter(userID int64, direction int8, rule *models.CommunityVoteRule) error {
+	if direction < 0 && rule.DownvoteDisabled {
+		return ErrDownvoteDisabled
+	}
+
+	if rule.MinAccountAge > 0 {
+		profile, err := s.Users.GetUserProfile(userID)
+		if err != nil {
+			return err
+		}
+		if s.now().Sub(profile.CreateTime) < time.Duration(rule.MinAccountAge)*time.Second {
+			return ErrAccountTooNew
+		}
+	}
+
+	// 投反对票需要同时满足全局和社区的karma门槛
+	min := rule.MinKarma
+	if direction < 0 {
+		if global := s.cfg.Get().Karma.MinToDownvote; global > min {
+			min = global
+		}
+	}
+	return s.checkKarma(strconv.FormatInt(userID, 10), min)
+}
+
+// VoteRule 全局配置的投票规则
+func (s *Service) VoteRule() models.VoteRule {
+	cfg := s.cfg.Get()
+	return models.VoteRule{
+		Window:       time.Duration(cfg.Vote.Window) * time.Second,
+		ScorePerVote: cfg.Vote.ScorePerVote,
+	}
+}
+
+// CommunityVoteRule 社区实际生效的投票规则
+func (s *Service) CommunityVoteRule(communityID int64) (models.VoteRule, error) {
+	community, err := s.Communities.GetCommunityDetail(communityID)
+	if err != nil {
+		return models.VoteRule{}, err
+	}
+	return s.communityVoteRule(&community.CommunityVoteRule), nil
+}
+
+// communityVoteRule 社区没有设置的窗口期和分数使用全局配置
+func (s *Service) communityVoteRule(c *models.CommunityVoteRule) models.VoteRule {
+	rule := s.VoteRule()
+	if c.VoteWindow > 0 {
+		rule.Window = time.Duration(c.VoteWindow) * time.Second
+	}
+	if c.ScorePerVote > 0 {
+		rule.ScorePerVote = c.ScorePerVote
+	}
+	return rule
+}
+
+// checkKarma 判断用户的karma是否达到门槛, 门槛小于等于0表示不限制
+func (s *Service) checkKarma(userID string, min int64) error {
+	if min <= 0 {
+		return nil
+	}
+	karma, err := s.Votes.GetUserKarma(userID)
+	if err != nil {
+		return err
+	}
+	if karma < min {
+		return ErrKarmaTooLow
+	}
+	return nil
 }
@@ -0,0 +1,200 @@
+package logic_test
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVoteForPost(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	pid := formatID(env.createPost(t, alice, 1, "hello"))
+	base := env.votes.GetPostScore(pid)
+
+	steps := []struct {
+		name      string
+		userID    int64
+		dir       int8
+		wantErr   error
+		wantScore float64
+		wantKarma int64
+	}{
+		{"upvote", bob, 1, nil, base + 432, 1},
+		{"repeat upvote", bob, 1, redis.ErrVoteRepeated, base + 432, 1},
+		{"change to downvote", bob, -1, nil, base - 432, -1},
+		{"cancel", bob, 0, nil, base, 0},
+		{"self vote does not count as karma", alice, 1, nil, base + 432, 0},
+	}
+	for _, st := range steps {
+		err := env.svc.VoteForPost(st.userID, &models.ParamVoteData{PostID: pid, Direction: direction(st.dir)}, "127.0.0.1")
+		if !errors.Is(err, st.wantErr) {
+			t.Fatalf("%s: got %v, want %v", st.name, err, st.wantErr)
+		}
+		if got := env.votes.GetPostScore(pid); got != st.wantScore {
+			t.Fatalf("%s: score = %v, want %v", st.name, got, st.wantScore)
+		}
+		if got, _ := env.votes.GetUserKarma(formatID(alice)); got != st.wantKarma {
+			t.Fatalf("%s: karma = %d, want %d", st.name, got, st.wantKarma)
+		}
+	}
+}
+
+func TestVoteRules(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	pid := formatID(env.createPost(t, alice, 1, "hello"))
+
+	// 帖子不存在
+	err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: "42", Direction: direction(1)}, "127.0.0.1")
+	if err == nil {
+		t.Fatal("vote for missing post succeeded")
+	}
+
+	// karma不足时不能投反对票
+	env.cfg.Karma.MinToDownvote = 1
+	err = env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(-1)}, "127.0.0.1")
+	if !errors.Is(err, logic.ErrKarmaTooLow) {
+		t.Fatalf("downvote with low karma: got %v, want %v", err, logic.ErrKarmaTooLow)
+	}
+
+	// 超过一周不能再投票
+	env.votes.Now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	err = env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1")
+	if !errors.Is(err, redis.ErrVoteTimeExpire) {
+		t.Fatalf("vote after window: got %v, want %v", err, redis.ErrVoteTimeExpire)
+	}
+}
+
+func TestCommunityVoteRules(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	pid := formatID(env.createPost(t, alice, 2, "hello"))
+	base := env.votes.GetPostScore(pid)
+
+	rule := &models.CommunityVoteRule{ScorePerVote: 100, DownvoteDisabled: true, MinAccountAge: 3600}
+	if err := env.svc.UpdateCommunityVoteRule(2, rule); err != nil {
+		t.Fatalf("UpdateCommunityVoteRule failed: %v", err)
+	}
+
+	// 注册时间不够
+	err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1")
+	if !errors.Is(err, logic.ErrAccountTooNew) {
+		t.Fatalf("vote with new account: got %v, want %v", err, logic.ErrAccountTooNew)
+	}
+
+	// 社区禁止反对票
+	rule.MinAccountAge = 0
+	if err := env.svc.UpdateCommunityVoteRule(2, rule); err != nil {
+		t.Fatalf("UpdateCommunityVoteRule failed: %v", err)
+	}
+	err = env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(-1)}, "127.0.0.1")
+	if !errors.Is(err, logic.ErrDownvoteDisabled) {
+		t.Fatalf("downvote: got %v, want %v", err, logic.ErrDownvoteDisabled)
+	}
+
+	// 使用社区的分数
+	if err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1"); err != nil {
+		t.Fatalf("upvote failed: %v", err)
+	}
+	if got := env.votes.GetPostScore(pid); got != base+100 {
+		t.Fatalf("score = %v, want %v", got, base+100)
+	}
+
+	// 社区的窗口期比全局短
+	rule.VoteWindow = 3600
+	if err := env.svc.UpdateCommunityVoteRule(2, rule); err != nil {
+		t.Fatalf("UpdateCommunityVoteRule failed: %v", err)
+	}
+	env.votes.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	err = env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(0)}, "127.0.0.1")
+	if !errors.Is(err, redis.ErrVoteTimeExpire) {
+		t.Fatalf("vote after community window: got %v, want %v", err, redis.ErrVoteTimeExpire)
+	}
+}
+
+func TestSecretBallot(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	plain := env.createPost(t, alice, 2, "plain")
+	if err := env.svc.UpdateCommunityVoteRule(2, &models.CommunityVoteRule{SecretBallot: true}); err != nil {
+		t.Fatalf("UpdateCommunityVoteRule failed: %v", err)
+	}
+	secret := env.createPost(t, alice, 2, "secret")
+	pid := formatID(secret)
+
+	if err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1"); err != nil {
+		t.Fatalf("VoteForPost failed: %v", err)
+	}
+	err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1")
+	if !errors.Is(err, redis.ErrVoteRepeated) {
+		t.Fatalf("repeated secret vote: got %v, want %v", err, redis.ErrVoteRepeated)
+	}
+	// 作者给自己投票不计入karma
+	if err := env.svc.VoteForPost(alice, &models.ParamVoteData{PostID: pid, Direction: direction(1)}, "127.0.0.1"); err != nil {
+		t.Fatalf("VoteForPost(author) failed: %v", err)
+	}
+
+	// 投票记录中没有用户id, 也没有用户的投票索引和刷票分析记录
+	if status, _ := env.votes.GetPostVoteForUser(formatID(bob), pid); status != 0 {
+		t.Fatalf("vote stored under plain user id")
+	}
+	if voted, _ := env.svc.GetUserVotedPosts(bob, 1, 10); len(voted) != 0 {
+		t.Fatalf("secret vote in user's voted posts: %+v", voted)
+	}
+	if records, _ := env.audit.GetVoteRecordsSince(time.Time{}); len(records) != 0 {
+		t.Fatalf("vote records for secret ballot = %+v", records)
+	}
+
+	// 只能看到总票数和自己的投票状态
+	data, err := env.svc.GetPostByID(secret, bob)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	if !data.SecretBallot || data.VoteNum != 2 || data.VoteStatus != 1 || data.AuthorKarma != 1 {
+		t.Fatalf("secret post detail = %+v", data)
+	}
+	detail, err := env.svc.GetPostByID(plain, 0)
+	if err != nil {
+		t.Fatalf("GetPostByID failed: %v", err)
+	}
+	if detail.SecretBallot {
+		t.Fatal("existing post became secret after community change")
+	}
+}
+
+func TestListByScore(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.signUp(t, "alice")
+	bob := env.signUp(t, "bob")
+	older := env.createPost(t, alice, 1, "older")
+	newer := env.createPost(t, alice, 1, "newer")
+
+	// 旧帖子获得一票之后排到前面
+	if err := env.svc.VoteForPost(bob, &models.ParamVoteData{PostID: formatID(older), Direction: direction(1)}, "127.0.0.1"); err != nil {
+		t.Fatalf("VoteForPost failed: %v", err)
+	}
+
+	data, err := env.svc.GetPostListNew(&models.ParamPostList{Page: 1, Size: 10, Order: models.OrderScore}, 0)
+	if err != nil {
+		t.Fatalf("GetPostListNew failed: %v", err)
+	}
+	if got, want := postIDs(data), []int64{older, newer}; !equalIDs(got, want) {
+		t.Fatalf("post ids by score = %v, want %v", got, want)
+	}
+	if data[0].VoteNum != 1 {
+		t.Fatalf("vote num = %d, want 1", data[0].VoteNum)
+	}
+}
+
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
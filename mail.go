@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bluebell/pkg/mailer"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runSendDigest 给开启了每周摘要的用户发送关注社区的热门帖子, 可以配置成每周执行一次的定时任务
+func runSendDigest(args []string) error {
+	fs, cf := newFlagSet("send-digest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := cf.bootstrap(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	n, err := a.Service.SendDigest()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("sent %d digest emails\n", n)
+	return nil
+}
+
+// runMailCapture 启动本地调试用的SMTP服务, 收到的邮件打印到标准输出, 不会真正投递
+func runMailCapture(args []string) error {
+	fs := flag.NewFlagSet("mail-capture", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:2525", "监听地址, 与配置文件中的mail.host和mail.port对应")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	srv, err := mailer.NewCaptureServer(*addr, func(c *mailer.Captured) {
+		msg, err := mailer.Parse(c.Data)
+		if err != nil {
+			fmt.Printf("---- from %s to %v, parse failed: %v\n%s\n", c.From, c.To, err, c.Data)
+			return
+		}
+		fmt.Printf("---- from %s to %v\nSubject: %s\n\n%s\n", c.From, c.To, msg.Subject, msg.Text)
+	})
+	if err != nil {
+		return err
+	}
+	defer srv.Close()
+	fmt.Printf("capturing mail on %s, press Ctrl+C to stop\n", srv.Addr())
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	return nil
+}
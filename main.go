@@ -1,55 +1,63 @@
 package main
 
 import (
-	"bluebell/dao/mysql"
-	"bluebell/dao/redis"
-	"bluebell/logger"
-	"bluebell/pkg/snowflake"
-	"bluebell/router"
-	"bluebell/setting"
+	"errors"
+	"flag"
 	"fmt"
-
-	"github.com/spf13/viper"
-	"go.uber.org/zap"
+	"os"
+	"strings"
 )
 
-func main() {
-
-	// 第一步 一定是加载配置文件yaml 才能够去做后续的操作
-	if err := setting.Init(); err != nil {
-		fmt.Println("load config failed, err:", zap.Error(err))
-		return
-	}
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
 
-	// 注意只有当配置文件生效之后才调用其他函数的初始化函数
-	// 初始化logger
-	logger.Init(viper.GetString("app.mode"))
+var commands = []command{
+	{"serve", "启动HTTP服务 (默认)", runServe},
+	{"migrate", "数据库迁移 up|down|status", runMigrate},
+	{"seed", "生成测试数据, 包括用户、社区、帖子和投票", runSeed},
+	{"loadgen", "对运行中的服务回放读和投票的混合流量, 统计延迟分位数", runLoadGen},
+	{"user", "用户管理, 例如 user create -username admin --admin", runUser},
+	{"analyze-votes", "立即分析最近的投票记录, 可疑的投票加入审核队列", runAnalyzeVotes},
+	{"send-digest", "给开启了每周摘要的用户发送关注社区的热门帖子", runSendDigest},
+	{"mail-capture", "启动本地调试用的SMTP服务, 收到的邮件打印到终端", runMailCapture},
+	{"rebuild-cache", "根据MySQL和投票记录重建Redis中的帖子排序和karma", runRebuildCache},
+	{"config", "配置管理, 例如 config validate", runConfig},
+}
 
-	// 连接mysql, 最后记得关闭数据库
-	if err := mysql.Init(); err != nil {
-		zap.L().Error("init mysql failed, err:%v\n", zap.Error(err))
-		return
+func main() {
+	args := os.Args[1:]
+	// 没有子命令时启动服务, 兼容 ./govote -auto-migrate 的用法
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-	defer mysql.Close() // 程序退出关闭数据库连接
 
-	// 连接redis, 并记得关闭数据库
-	if err := redis.Init(); err != nil {
-		zap.L().Error("init redis failed, err:%v\n", zap.Error(err))
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "%s failed, err: %v\n", name, err)
+			os.Exit(1)
+		}
 		return
 	}
-	defer redis.Close()
 
-	// 初始化雪花算法
-	if err := snowflake.Init(viper.GetString("app.start_time"), viper.GetInt64("app.machine_id")); err != nil {
-		zap.L().Error("init snowflake failed", zap.Error(err))
-		return
-	}
+	usage()
+	os.Exit(2)
+}
 
-	// 注册路由
-	r := router.SetupRouter(viper.GetString("app.mode"))
-	err := r.Run(fmt.Sprintf(":%d", viper.GetInt("app.port")))
-	if err != nil {
-		zap.L().Error("start server failed", zap.Error(err))
-		return
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: govote <command> [-config file] [-env dev|test|prod] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.usage)
 	}
 }
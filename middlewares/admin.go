@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"bluebell/controller"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MFARequiredMiddleware 被要求开启两步验证但还没有绑定的用户只能访问绑定接口, 需要放在JWTAuthMiddleware之后
+func MFARequiredMiddleware(svc *logic.Service, log *zap.Logger) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, _ := c.Get(controller.CtxUserIDKey)
+		uid, _ := userID.(int64)
+		if err := svc.CheckMFAEnrolled(uid); err != nil {
+			if errors.Is(err, logic.ErrMFARequired) {
+				controller.ResponseError(c, controller.CodeMFARequired)
+			} else {
+				log.Error("logic.CheckMFAEnrolled failed", zap.Error(err))
+				controller.ResponseError(c, controller.CodeServerBusy)
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminAuthMiddleware 管理员权限中间件, 需要放在JWTAuthMiddleware之后
+func AdminAuthMiddleware(svc *logic.Service, log *zap.Logger) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		uid, ok := c.Get(controller.CtxUserIDKey)
+		userID, _ := uid.(int64)
+		if !ok || userID == 0 {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+
+		// 角色以数据库为准, 降级之后立即生效
+		role, err := svc.GetUserRole(userID)
+		if err != nil {
+			log.Error("logic.GetUserRole failed", zap.Error(err))
+			controller.ResponseError(c, controller.CodeServerBusy)
+			c.Abort()
+			return
+		}
+		if role != models.RoleAdmin {
+			controller.ResponseError(c, controller.CodeNoPermission)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
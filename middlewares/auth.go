@@ -11,7 +11,7 @@ import (
 const CtxUserIDKey = "userID"
 
 // JWTAuthMiddleware 基于JWT的认证中间件
-func JWTAuthMiddleware() func(c *gin.Context) {
+func JWTAuthMiddleware(tokens *jwt.Manager) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		// 客户端携带Token有三种方式 1.放在请求头 2.放在请求体 3.放在URI
 		// 这里假设Token放在Header的Authorization中，并使用Bearer开头
@@ -31,7 +31,7 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			return
 		}
 		// parts[1]是获取到的tokenString，我们使用之前定义好的解析JWT的函数来解析它
-		mc, err := jwt.ParseToken(parts[1])
+		mc, err := tokens.ParseToken(parts[1])
 		if err != nil {
 			controller.ResponseError(c, controller.CodeInvalidToken)
 			c.Abort()
@@ -46,13 +46,13 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 
 // OptionalJWTAuthMiddleware 可选的JWT认证中间件
 // 如果用户携带了有效的token，则设置UserID，否则不设置，但不拦截请求
-func OptionalJWTAuthMiddleware() func(c *gin.Context) {
+func OptionalJWTAuthMiddleware(tokens *jwt.Manager) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				mc, err := jwt.ParseToken(parts[1])
+				mc, err := tokens.ParseToken(parts[1])
 				if err == nil {
 					c.Set(controller.CtxUserIDKey, mc.UserID)
 				}
@@ -61,3 +61,28 @@ func OptionalJWTAuthMiddleware() func(c *gin.Context) {
 		c.Next()
 	}
 }
+
+// MFAEnrollAuthMiddleware 绑定两步验证接口使用的认证中间件
+// 除了正常的访问token, 还接受被强制绑定两步验证的用户拿到的mfa_enroll token
+func MFAEnrollAuthMiddleware(tokens *jwt.Manager) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		authHeader := c.Request.Header.Get("Authorization")
+		parts := strings.SplitN(authHeader, " ", 2)
+		if !(len(parts) == 2 && parts[0] == "Bearer") {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		mc, err := tokens.ParseToken(parts[1])
+		if err != nil {
+			mc, err = tokens.ParsePurposeToken(parts[1], jwt.PurposeMFAEnroll)
+		}
+		if err != nil {
+			controller.ResponseError(c, controller.CodeInvalidToken)
+			c.Abort()
+			return
+		}
+		c.Set(controller.CtxUserIDKey, mc.UserID)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"bluebell/controller"
+	"bluebell/dao/redis"
+	"bluebell/setting"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RateLimitMiddleware 基于redis滑动窗口的限流中间件
+// rule对应配置文件 ratelimit.rules.<rule> 下的 limit(次数), window(秒) 和 key(ip/user)
+func RateLimitMiddleware(cfg *setting.Holder, store *redis.Store, log *zap.Logger, rule string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		// 每次请求都读取当前的配置, 热更新之后立即生效
+		rl := cfg.Get().RateLimit
+		if !rl.Enable {
+			c.Next()
+			return
+		}
+		r := rl.Rules[rule]
+		limit := r.Limit
+		window := time.Duration(r.Window) * time.Second
+		if limit <= 0 || window <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, wait, err := store.AllowRequest(rule, limitKey(c, r.Key), limit, window)
+		if err != nil {
+			// redis出问题时不影响正常业务, 直接放行
+			log.Error("redis.AllowRequest failed", zap.String("rule", rule), zap.Error(err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			controller.ResponseErrorWithStatus(c, http.StatusTooManyRequests, controller.CodeTooManyRequests)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitKey 获取限流的标识, 按用户限流时未登录的请求退化为按ip限流
+func limitKey(c *gin.Context, by string) string {
+	if by == "user" {
+		if uid, ok := c.Get(controller.CtxUserIDKey); ok {
+			if userID, ok := uid.(int64); ok {
+				return "user:" + strconv.FormatInt(userID, 10)
+			}
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"bluebell/controller"
+	"bluebell/setting"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceAllowed 维护模式下仍然可以访问的写接口, 管理员需要登录之后关闭维护模式
+var maintenanceAllowed = []string{
+	"/api/v1/login",
+	"/api/v1/admin/",
+}
+
+// MaintenanceMiddleware 维护模式中间件, 开启后只允许读请求、登录和管理员接口
+func MaintenanceMiddleware(cfg *setting.Holder) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		m := cfg.Get().Maintenance
+		if !m.Enable || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		for _, prefix := range maintenanceAllowed {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		msg := m.Message
+		if msg == "" {
+			msg = controller.CodeMaintenance.Msg()
+		}
+		controller.ResponseErrorWithMsg(c, controller.CodeMaintenance, msg)
+		c.Abort()
+	}
+}
+
+// FeatureMiddleware 功能开关中间件, 功能关闭时拒绝请求
+func FeatureMiddleware(cfg *setting.Holder, feature string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !cfg.Get().FeatureEnabled(feature) {
+			controller.ResponseError(c, controller.CodeFeatureDisabled)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bluebell/dao/mysql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runMigrate 执行数据库迁移子命令, govote migrate up|down|status
+func runMigrate(args []string) error {
+	fs, cf := newFlagSet("migrate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return errors.New("usage: govote migrate up|down [-steps n]|status")
+	}
+
+	// 只需要连接mysql
+	db, log, err := cf.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer log.Sync()
+
+	switch args[0] {
+	case "up":
+		return migrateUp(db, log)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "回滚的版本数")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		done, err := db.MigrateDown(*steps)
+		for _, m := range done {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := db.GetMigrationStatus()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			at := "pending"
+			if s.Applied {
+				at = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// migrateUp 执行全部未执行的迁移
+func migrateUp(db *mysql.Store, log *zap.Logger) error {
+	done, err := db.MigrateUp()
+	for _, m := range done {
+		log.Info("migration applied", zap.Int64("version", m.Version), zap.String("name", m.Name))
+	}
+	return err
+}
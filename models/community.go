@@ -12,4 +12,32 @@ type CommunityDetail struct {
 	Name         string    `json:"name" db:"community_name"`
 	Introduction string    `json:"introduction,omitempty" db:"introduction"`
 	CreateTime   time.Time `json:"create_time" db:"create_time"`
+	CommunityVoteRule
+}
+
+// CommunityVoteRule 社区的投票规则, 为0的窗口期和分数使用全局配置
+type CommunityVoteRule struct {
+	VoteWindow       int64   `json:"vote_window" db:"vote_window" binding:"min=0"` // 秒
+	ScorePerVote     float64 `json:"score_per_vote" db:"score_per_vote" binding:"min=0"`
+	DownvoteDisabled bool    `json:"downvote_disabled" db:"downvote_disabled"`
+	MinAccountAge    int64   `json:"min_account_age" db:"min_account_age" binding:"min=0"` // 秒
+	MinKarma         int64   `json:"min_karma" db:"min_karma" binding:"min=0"`
+	SecretBallot     bool    `json:"secret_ballot" db:"secret_ballot"` // 新帖子默认秘密投票, 已有的帖子不受影响
+}
+
+// CommunityTag 社区定义的标签(flair), 定义了标签的社区发帖时只能使用这些标签
+type CommunityTag struct {
+	CommunityID int64  `json:"-" db:"community_id"`
+	Name        string `json:"name" db:"name"`
+	Color       string `json:"color" db:"color"`
+	Description string `json:"description" db:"description"`
+}
+
+// ApiTagCount 社区中使用的标签及帖子数, defined为false的是自由标签
+type ApiTagCount struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Defined     bool   `json:"defined"`
+	PostNum     int64  `json:"post_num"`
 }
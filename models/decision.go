@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// 决策帖的计票方式
+const (
+	DecisionApproval = "approval" // 认可投票, 可以赞成任意多个选项, 得票最多的胜出
+	DecisionIRV      = "irv"      // 排序复选制, 逐轮淘汰得票最少的选项直到有选项过半
+)
+
+// Decision 决策帖的选项和规则, 选项按下标编号
+type Decision struct {
+	Method    string     `json:"method" binding:"required,oneof=approval irv"`
+	Options   []string   `json:"options" binding:"min=2,max=10,dive,required,max=128"`
+	Secret    bool       `json:"secret"`     // 秘密投票, 审计记录中不公开投票人
+	CloseTime *time.Time `json:"close_time"` // 截止时间, 为空表示不截止
+}
+
+// Closed 判断投票是否已经截止
+func (d *Decision) Closed(now time.Time) bool {
+	return d.CloseTime != nil && !now.Before(*d.CloseTime)
+}
+
+// Ballot 决策帖的一张选票
+type Ballot struct {
+	PostID     int64     `json:"-"`
+	Voter      string    `json:"-"`                        // 投票人, 秘密投票时为化名
+	UserID     int64     `json:"user_id,string,omitempty"` // 公开投票的审计记录中的投票人
+	Choices    []int     `json:"choices"`                  // 认可投票为赞成的选项, 排序复选制按偏好从高到低排列
+	CreateTime time.Time `json:"create_time"`
+}
+
+// TallyRound 计票的一轮, 认可投票只有一轮
+type TallyRound struct {
+	Round      int     `json:"round"`
+	Counts     []int64 `json:"counts"`     // 每个选项的票数, 已淘汰的选项为0
+	Eliminated []int   `json:"eliminated"` // 本轮之后淘汰的选项
+	Exhausted  int64   `json:"exhausted"`  // 排序的选项都已淘汰的选票数
+}
+
+// ApiDecisionTally 决策帖的计票结果
+type ApiDecisionTally struct {
+	Method   string        `json:"method"`
+	Closed   bool          `json:"closed"`
+	Ballots  int64         `json:"ballots"`   // 选票总数
+	Rounds   []*TallyRound `json:"rounds"`    // 每一轮的票数
+	Winners  []int         `json:"winners"`   // 胜出的选项, 平局时有多个, 没有选票时为空
+	MyBallot []int         `json:"my_ballot"` // 当前用户的选票, 没有投票时为空
+}
+
+// ApiDecisionAudit 决策帖截止之后导出的审计记录
+// 秘密投票时选票不包含投票人和投票时间, 并按选票内容排序
+type ApiDecisionAudit struct {
+	PostID      int64             `json:"post_id,string"`
+	Title       string            `json:"title"`
+	Method      string            `json:"method"`
+	Options     []string          `json:"options"`
+	Secret      bool              `json:"secret"`
+	CloseTime   time.Time         `json:"close_time"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Ballots     []*Ballot         `json:"ballots"`
+	Tally       *ApiDecisionTally `json:"tally"`
+	Digest      string            `json:"digest"` // 选票的sha256, 用于核对导出的选票没有被修改
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 实时事件的类型
+const (
+	EventVote    = "vote"     // 帖子的票数和分数变化
+	EventNewPost = "new_post" // 发布了新帖子
+	EventPoll    = "poll"     // 投票帖的结果变化
+)
+
+// 可以订阅的频道: 单个帖子、社区的帖子列表和首页
+const (
+	TopicFront       = "front"
+	TopicPostPF      = "post:"      // 参数是post id
+	TopicCommunityPF = "community:" // 参数是community id
+)
+
+// Event 通过WebSocket/SSE推送给客户端的实时事件
+type Event struct {
+	Type        string         `json:"type"`
+	PostID      int64          `json:"post_id,string"`
+	CommunityID int64          `json:"community_id"`
+	VoteNum     int64          `json:"vote_num,omitempty"`
+	Score       float64        `json:"score,omitempty"`
+	Post        *Post          `json:"post,omitempty"` // 新帖子的内容
+	Poll        *ApiPollResult `json:"poll,omitempty"` // 投票帖的最新结果, 不包含用户的选择
+}
+
+// Topics 事件所属的频道, 新帖子不属于帖子频道
+func (e *Event) Topics() []string {
+	topics := []string{TopicFront, TopicCommunityPF + strconv.FormatInt(e.CommunityID, 10)}
+	if e.Type != EventNewPost {
+		topics = append(topics, TopicPostPF+strconv.FormatInt(e.PostID, 10))
+	}
+	return topics
+}
+
+// ValidTopic 判断客户端订阅的频道是否合法
+func ValidTopic(topic string) bool {
+	if topic == TopicFront {
+		return true
+	}
+	for _, prefix := range []string{TopicPostPF, TopicCommunityPF} {
+		if id, ok := strings.CutPrefix(topic, prefix); ok {
+			_, err := strconv.ParseInt(id, 10, 64)
+			return err == nil
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotifyMention         = "mention"          // 在帖子中被@
+	NotifyVoteMilestone   = "vote_milestone"   // 帖子的票数达到里程碑
+	NotifyModeratorAction = "moderator_action" // 管理员对自己的账号或投票进行了操作
+	NotifyCommunityPost   = "community_post"   // 关注的社区有新帖子
+	NotifyEmailDigest     = "email_digest"     // 关注的社区每周热门帖子的邮件摘要
+)
+
+// NotificationTypes 全部通知类型, 用于校验和展示通知设置
+var NotificationTypes = []string{NotifyMention, NotifyVoteMilestone, NotifyModeratorAction, NotifyCommunityPost, NotifyEmailDigest}
+
+// OptInNotificationTypes 默认关闭, 需要用户主动开启的通知类型
+var OptInNotificationTypes = []string{NotifyEmailDigest}
+
+// Notification 站内通知
+type Notification struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"-" db:"user_id"`
+	Type        string    `json:"type" db:"type"`
+	ActorID     int64     `json:"actor_id,string" db:"actor_id"` // 系统通知为0
+	PostID      int64     `json:"post_id,string" db:"post_id"`
+	CommunityID int64     `json:"community_id" db:"community_id"`
+	Content     string    `json:"content" db:"content"`
+	DedupeKey   *string   `json:"-" db:"dedupe_key"` // 不为空时同一用户相同的标识只通知一次
+	IsRead      bool      `json:"is_read" db:"is_read"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiNotifications 通知列表接口的返回结果
+type ApiNotifications struct {
+	Unread int64           `json:"unread"`
+	List   []*Notification `json:"list"`
+}
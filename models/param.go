@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 const (
 	OrderTime  = "time"
 	OrderScore = "score"
@@ -10,6 +12,7 @@ type ParamSignUp struct {
 	Username   string `json:"username" binding:"required"`
 	Password   string `json:"password" binding:"required"`
 	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+	Email      string `json:"email" binding:"omitempty,email,max=64"` // 可以为空, 不为空时发送验证邮件
 }
 
 // 登录参数
@@ -30,4 +33,117 @@ type ParamPostList struct {
 	Page        int64  `json:"page" form:"page"`
 	Size        int64  `json:"size" form:"size"`
 	Order       string `json:"order" form:"order"`
+	Tag         string `json:"tag" form:"tag"` // 可以为空, 不为空时只返回带有该标签的帖子
+}
+
+// ParamUnlockUser 管理员解锁账号参数
+type ParamUnlockUser struct {
+	IP string `json:"ip"` // 可以为空, 不为空时同时解锁该ip
+}
+
+// ParamLoginAttempts 查询登录审计记录的参数
+type ParamLoginAttempts struct {
+	Username string `json:"username" form:"username" binding:"required"`
+	Page     int64  `json:"page" form:"page"`
+	Size     int64  `json:"size" form:"size"`
+}
+
+// ParamMFACode 两步验证码参数, code可以是验证码也可以是恢复码
+type ParamMFACode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ParamLoginMFA 登录第二步参数
+type ParamLoginMFA struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// ParamRequireMFA 管理员设置版主是否必须开启两步验证
+type ParamRequireMFA struct {
+	Moderators *bool `json:"moderators" binding:"required"`
+}
+
+// ParamUpdateProfile 修改个人资料参数, 为空的字段不修改
+type ParamUpdateProfile struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=64"`
+	Bio         *string `json:"bio" binding:"omitempty,max=512"`
+	Avatar      *string `json:"avatar" binding:"omitempty,max=256"`
+}
+
+// ParamCreateCommunity 创建社区参数
+type ParamCreateCommunity struct {
+	Name         string `json:"name" binding:"required,max=128"`
+	Introduction string `json:"introduction" binding:"required,max=256"`
+}
+
+// ParamVoteFlags 查询可疑投票审核队列的参数, status默认为待审核
+type ParamVoteFlags struct {
+	Status int8  `json:"status" form:"status" binding:"oneof=0 1 2"`
+	Page   int64 `json:"page" form:"page"`
+	Size   int64 `json:"size" form:"size"`
+}
+
+// ParamNotifications 查询通知列表的参数
+type ParamNotifications struct {
+	Unread bool  `json:"unread" form:"unread"` // 只看未读
+	Page   int64 `json:"page" form:"page"`
+	Size   int64 `json:"size" form:"size"`
+}
+
+// ParamMailToken 邮件链接中的token
+type ParamMailToken struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ParamUpdateEmail 修改邮箱参数, 修改后需要重新验证
+type ParamUpdateEmail struct {
+	Email string `json:"email" binding:"required,email,max=64"`
+}
+
+// ParamForgotPassword 忘记密码参数
+type ParamForgotPassword struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ParamResetPassword 通过邮件链接重置密码参数
+type ParamResetPassword struct {
+	Token      string `json:"token" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+}
+
+// ParamPostTags 修改帖子标签参数, 传空数组表示删除全部标签
+type ParamPostTags struct {
+	Tags []string `json:"tags" binding:"max=5"`
+}
+
+// ParamPollVote 投票帖投票的参数, 选项为下标
+type ParamPollVote struct {
+	Choices []int `json:"choices" binding:"required,min=1,max=10"`
+}
+
+// ParamBallot 决策帖投票的参数, 排序复选制按偏好从高到低排列选项
+type ParamBallot struct {
+	Choices []int `json:"choices" binding:"required,min=1,max=10"`
+}
+
+// ParamCommunityTag 社区定义标签参数
+type ParamCommunityTag struct {
+	Name        string `json:"name" binding:"required,max=32"`
+	Color       string `json:"color" binding:"omitempty,hexcolor"`
+	Description string `json:"description" binding:"max=128"`
+}
+
+// ParamDraft 修改草稿参数, publish_time为空时保存为草稿, 不为空时定时发布
+type ParamDraft struct {
+	Title       string     `json:"title" binding:"required"`
+	Content     string     `json:"content" binding:"required"`
+	Tags        []string   `json:"tags" binding:"max=5"`
+	PublishTime *time.Time `json:"publish_time"`
+}
+
+// ParamPreview 预览Markdown渲染结果的参数
+type ParamPreview struct {
+	Content string `json:"content" binding:"required,max=8192"`
 }
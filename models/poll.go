@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// 投票帖选项数量的限制
+const (
+	PollMinOptions = 2
+	PollMaxOptions = 10
+)
+
+// Poll 投票帖的选项和规则, 选项按下标编号
+type Poll struct {
+	Options     []string   `json:"options" binding:"min=2,max=10,dive,required,max=128"`
+	Multiple    bool       `json:"multiple"`     // 是否可以多选
+	HideResults bool       `json:"hide_results"` // 截止之前是否隐藏结果
+	CloseTime   *time.Time `json:"close_time"`   // 截止时间, 为空表示不截止
+}
+
+// Closed 判断投票是否已经截止
+func (p *Poll) Closed(now time.Time) bool {
+	return p.CloseTime != nil && !now.Before(*p.CloseTime)
+}
+
+// ApiPollResult 投票帖的结果
+type ApiPollResult struct {
+	Closed    bool    `json:"closed"`
+	Hidden    bool    `json:"hidden"`     // 结果在截止之前隐藏
+	Voters    int64   `json:"voters"`     // 参与投票的人数
+	Counts    []int64 `json:"counts"`     // 每个选项的票数, 隐藏时为空
+	MyChoices []int   `json:"my_choices"` // 当前用户选择的选项, 没有投票时为空
+}
@@ -2,23 +2,59 @@ package models
 
 import "time"
 
+// 帖子类型
+const (
+	PostTypeText     int8 = iota // 普通帖子
+	PostTypePoll                 // 投票帖, 带有多个选项
+	PostTypeDecision             // 决策帖, 使用认可投票或排序复选制
+)
+
+// 帖子内容的格式
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// 帖子状态
+const (
+	PostStatusDraft     int32 = iota // 草稿, 只有作者可见
+	PostStatusPublished              // 已发布
+	PostStatusScheduled              // 定时发布, 发布之前只有作者可见
+)
+
 // 内存对齐概念
 
 type Post struct {
-	ID          int64     `json:"id,string" db:"post_id"`
-	AuthorID    int64     `json:"author_id,string" db:"author_id"`
-	CommunityID int64     `json:"community_id" db:"community_id" binding:"required"`
-	Status      int32     `json:"status" db:"status"`
-	Title       string    `json:"title" db:"title" binding:"required"`
-	Content     string    `json:"content" db:"content" binding:"required"`
-	CreateTime  time.Time `json:"create_time" db:"create_time"`
+	ID           int64      `json:"id,string" db:"post_id"`
+	AuthorID     int64      `json:"author_id,string" db:"author_id"`
+	CommunityID  int64      `json:"community_id" db:"community_id" binding:"required"`
+	Status       int32      `json:"status" db:"status"`
+	Draft        bool       `json:"draft,omitempty" db:"-"`                   // 保存为草稿, 只在发帖时使用
+	PublishTime  *time.Time `json:"publish_time,omitempty" db:"publish_time"` // 定时发布的时间
+	Type         int8       `json:"type" db:"type"`                           // 帖子类型, 由服务端根据内容设置
+	SecretBallot bool       `json:"secret_ballot" db:"secret_ballot"`         // 秘密投票, 社区开启时新帖子都是秘密投票
+	Title        string     `json:"title" db:"title" binding:"required"`
+	Content      string     `json:"content" db:"content" binding:"required"`
+	ContentHTML  string     `json:"-" db:"content_html"` // 渲染并过滤之后的HTML, 详情接口按format参数返回
+	Tags         []string   `json:"tags" db:"-"`         // 标签, 社区定义了标签时只能使用定义的标签
+	Poll         *Poll      `json:"poll,omitempty" db:"-"`
+	Decision     *Decision  `json:"decision,omitempty" db:"-"`
+	CreateTime   time.Time  `json:"create_time" db:"create_time"` // 发布时间, 草稿为最后保存的时间
+}
+
+// Published 帖子是否已经发布, 只有发布的帖子才会进入redis中的排序
+func (p *Post) Published() bool {
+	return p.Status == PostStatusPublished
 }
 
 // ApiPostDetail 帖子详情接口的结构体
 type ApiPostDetail struct {
 	AuthorName       string             `json:"author_name"`
+	AuthorKarma      int64              `json:"author_karma"`
 	VoteNum          int64              `json:"vote_num"`
-	VoteStatus       int32              `json:"vote_status"` // 当前用户的投票状态
+	VoteStatus       int32              `json:"vote_status"`              // 当前用户的投票状态
+	PollResult       *ApiPollResult     `json:"poll_result,omitempty"`    // 投票帖的结果
+	DecisionTally    *ApiDecisionTally  `json:"decision_tally,omitempty"` // 决策帖的计票结果
 	*Post                               // 嵌入帖子结构体
 	*CommunityDetail `json:"community"` // 嵌入社区信息
 }
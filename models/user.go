@@ -1,7 +1,93 @@
 package models
 
+import "time"
+
+// 用户角色
+const (
+	RoleUser      int8 = 0 // 普通用户
+	RoleModerator int8 = 1 // 版主
+	RoleAdmin     int8 = 2 // 管理员
+)
+
 type User struct {
-	UserID   int64  `db:"user_id"`
-	Username string `db:"username"`
-	Password string `db:"password"`
+	UserID      int64  `db:"user_id"`
+	Username    string `db:"username"`
+	Password    string `db:"password"`
+	Role        int8   `db:"role"`
+	TOTPSecret  string `db:"totp_secret"`
+	TOTPEnabled bool   `db:"totp_enabled"`
+}
+
+// UserProfile 用户的公开资料
+type UserProfile struct {
+	UserID      int64     `json:"user_id,string" db:"user_id"`
+	Username    string    `json:"username" db:"username"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	Bio         string    `json:"bio" db:"bio"`
+	Avatar      string    `json:"avatar" db:"avatar"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"` // 注册时间
+}
+
+// UserEmail 用户的邮箱及验证状态, 没有设置邮箱时Email为空
+type UserEmail struct {
+	Email    string `db:"email"`
+	Verified bool   `db:"email_verified"`
+}
+
+// ApiMyProfile 当前用户自己的资料, 比公开资料多了邮箱
+type ApiMyProfile struct {
+	*UserProfile
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// ApiUserDetail 用户主页接口的结构体
+type ApiUserDetail struct {
+	*UserProfile
+	Karma int64            `json:"karma"` // 用户所有帖子获得的票数
+	Posts []*ApiPostDetail `json:"posts"`
+}
+
+// ApiLoginResult 登录接口的返回结果
+// 开启两步验证的用户只拿到mfa_token, 需要再调用/login/2fa换取正式token
+type ApiLoginResult struct {
+	UserID            int64  `json:"user_id,string"`
+	Username          string `json:"username"`
+	Token             string `json:"token,omitempty"`
+	MFARequired       bool   `json:"mfa_required,omitempty"`        // 需要输入两步验证码
+	MFAEnrollRequired bool   `json:"mfa_enroll_required,omitempty"` // 需要先绑定两步验证
+	MFAToken          string `json:"mfa_token,omitempty"`
+}
+
+// ApiMFAEnroll 绑定两步验证时返回的数据, otpauth_uri即二维码内容
+type ApiMFAEnroll struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// ApiMFAActivate 开启两步验证后返回的恢复码和新的访问token
+type ApiMFAActivate struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Token         string   `json:"token"`
+}
+
+// 登录失败的原因
+const (
+	LoginReasonSuccess       = "success"
+	LoginReasonUserNotExist  = "user_not_exist"
+	LoginReasonWrongPassword = "wrong_password"
+	LoginReasonLocked        = "locked"
+	LoginReasonMFAPending    = "mfa_pending"    // 密码正确, 等待两步验证
+	LoginReasonWrongMFACode  = "wrong_mfa_code" // 两步验证码错误
+)
+
+// LoginAttempt 登录审计记录
+type LoginAttempt struct {
+	ID         int64     `json:"id" db:"id"`
+	UserID     int64     `json:"user_id,string" db:"user_id"` // 用户不存在时为0
+	Username   string    `json:"username" db:"username"`
+	IP         string    `json:"ip" db:"ip"`
+	Success    bool      `json:"success" db:"success"`
+	Reason     string    `json:"reason" db:"reason"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
 }
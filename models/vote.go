@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// VoteRule 投票规则, 由配置决定, 可以热更新
+type VoteRule struct {
+	Window       time.Duration // 发帖后多久之内可以投票
+	ScorePerVote float64       // 每一票增加的分数
+}
+
+// Voter 投票人在投票记录中的身份
+// 秘密投票时ID是投票人在这个帖子中的化名, 不能关联到用户, 也不维护用户的投票索引
+type Voter struct {
+	ID   string
+	Self bool // 给自己的帖子投票, 不计入作者的karma
+}
+
+// 可疑投票的原因
+const (
+	VoteFlagNewAccounts = "new_accounts" // 大量新账号给同一作者投票
+	VoteFlagBurst       = "burst"        // 同一帖子短时间内集中收到投票
+	VoteFlagSharedIP    = "shared_ip"    // 同一ip的多个账号给同一作者投票
+)
+
+// 可疑投票的审核状态
+const (
+	VoteFlagPending   int8 = 0 // 待审核
+	VoteFlagNullified int8 = 1 // 已作废, 投票已经撤销
+	VoteFlagDismissed int8 = 2 // 已忽略
+)
+
+// VoteRecord 投票记录, 每次投票追加一条, 用于刷票分析
+type VoteRecord struct {
+	ID         int64     `json:"id" db:"id"`
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	AuthorID   int64     `json:"author_id,string" db:"author_id"`
+	UserID     int64     `json:"user_id,string" db:"user_id"`
+	Direction  int8      `json:"direction" db:"direction"`
+	IP         string    `json:"ip" db:"ip"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// VoteFlag 分析出的可疑投票, 进入管理员的审核队列
+type VoteFlag struct {
+	ID         int64      `json:"id" db:"id"`
+	PostID     int64      `json:"post_id,string" db:"post_id"`
+	AuthorID   int64      `json:"author_id,string" db:"author_id"`
+	UserID     int64      `json:"user_id,string" db:"user_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	Detail     string     `json:"detail" db:"detail"`
+	Status     int8       `json:"status" db:"status"`
+	ReviewerID int64      `json:"reviewer_id,string" db:"reviewer_id"`
+	CreateTime time.Time  `json:"create_time" db:"create_time"`
+	ReviewTime *time.Time `json:"review_time" db:"review_time"`
+}
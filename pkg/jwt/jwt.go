@@ -4,12 +4,22 @@ import (
 	"errors"
 	"time"
 
-	"github.com/spf13/viper"
-
 	"github.com/dgrijalva/jwt-go"
 )
 
-var mySecret = []byte("康海洋")
+// Manager 负责签发和解析JWT
+type Manager struct {
+	secret []byte
+	expire time.Duration // 访问token的有效期
+}
+
+// New 创建JWT管理器
+func New(secret string, expire time.Duration) *Manager {
+	return &Manager{
+		secret: []byte(secret),
+		expire: expire,
+	}
+}
 
 // MyClaims 自定义声明结构体并内嵌jwt.StandardClaims
 // jwt包自带的jwt.StandardClaims只包含了官方字段
@@ -18,33 +28,81 @@ var mySecret = []byte("康海洋")
 type MyClaims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
+	Purpose  string `json:"purpose,omitempty"` // 为空表示正常的访问token
 	jwt.StandardClaims
 }
 
+// 特殊用途的token, 只能在对应的接口使用
+const (
+	PurposeMFA       = "mfa"        // 密码已校验, 等待第二步验证码
+	PurposeMFAEnroll = "mfa_enroll" // 必须先绑定两步验证
+)
+
+// ErrTokenPurpose token用途不匹配
+var ErrTokenPurpose = errors.New("token purpose mismatch")
+
 // GenToken 生成JWT
-func GenToken(userID int64, username string) (string, error) {
+func (m *Manager) GenToken(userID int64, username string) (string, error) {
 	// 创建一个我们自己的声明的数据
 	c := MyClaims{
 		userID,
-		"username", // 自定义字段
+		username, // 自定义字段
+		"",
 		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(
-				time.Duration(viper.GetInt("auth.jwt_expire")) * time.Hour).Unix(), // 1年的过期时间
-			Issuer: "govote", // 签发人
+			ExpiresAt: time.Now().Add(m.expire).Unix(), // 默认1年的过期时间
+			Issuer:    "govote",                        // 签发人
 		},
 	}
 	// 使用指定的签名方法创建签名对象
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
 	// 使用指定的secret签名并获得完整的编码后的字符串token
-	return token.SignedString(mySecret)
+	return token.SignedString(m.secret)
+}
+
+// GenPurposeToken 生成有特定用途的短期JWT, 例如两步验证的中间状态
+func (m *Manager) GenPurposeToken(userID int64, username, purpose string, expire time.Duration) (string, error) {
+	c := MyClaims{
+		UserID:   userID,
+		Username: username,
+		Purpose:  purpose,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(expire).Unix(),
+			Issuer:    "govote",
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	return token.SignedString(m.secret)
+}
+
+// ParseToken 解析访问JWT, 特殊用途的token不能当作访问token使用
+func (m *Manager) ParseToken(tokenString string) (*MyClaims, error) {
+	mc, err := m.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if mc.Purpose != "" {
+		return nil, ErrTokenPurpose
+	}
+	return mc, nil
+}
+
+// ParsePurposeToken 解析特殊用途的JWT并校验用途
+func (m *Manager) ParsePurposeToken(tokenString, purpose string) (*MyClaims, error) {
+	mc, err := m.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if mc.Purpose != purpose {
+		return nil, ErrTokenPurpose
+	}
+	return mc, nil
 }
 
-// ParseToken 解析JWT
-func ParseToken(tokenString string) (*MyClaims, error) {
+func (m *Manager) parseToken(tokenString string) (*MyClaims, error) {
 	// 解析token
 	var mc = new(MyClaims)
 	token, err := jwt.ParseWithClaims(tokenString, mc, func(token *jwt.Token) (i interface{}, err error) {
-		return mySecret, nil
+		return m.secret, nil
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,153 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Captured 捕获服务收到的一封邮件
+type Captured struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// CaptureServer 本地调试和测试用的SMTP服务, 只接收邮件不投递
+// 只实现了发送邮件需要的最少命令, 不支持认证和TLS
+type CaptureServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []*Captured
+	onMail   func(*Captured)
+	wg       sync.WaitGroup
+}
+
+// NewCaptureServer 在addr上启动捕获服务, onMail不为nil时每收到一封邮件调用一次
+func NewCaptureServer(addr string, onMail func(*Captured)) (*CaptureServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &CaptureServer{ln: ln, onMail: onMail}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr 实际监听的地址
+func (s *CaptureServer) Addr() *net.TCPAddr {
+	return s.ln.Addr().(*net.TCPAddr)
+}
+
+// Messages 已经收到的邮件
+func (s *CaptureServer) Messages() []*Captured {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Captured(nil), s.messages...)
+}
+
+// Close 停止服务
+func (s *CaptureServer) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *CaptureServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *CaptureServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) bool {
+		_, err := conn.Write([]byte(line + "\r\n"))
+		return err == nil
+	}
+
+	reply("220 govote mail capture")
+	msg := new(Captured)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			reply("250 govote")
+		case "MAIL":
+			msg = &Captured{From: trimPath(arg)}
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, trimPath(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			if s.onMail != nil {
+				s.onMail(msg)
+			}
+			msg = new(Captured)
+			reply("250 OK")
+		case "RSET":
+			msg = new(Captured)
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// readData 读取DATA命令之后的内容, 以单独一行的"."结束
+func readData(r *bufio.Reader) ([]byte, error) {
+	var data []byte
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimRight(line, "\r\n") == "." {
+			return data, nil
+		}
+		// 以"."开头的行发送时会多加一个"."
+		line = strings.TrimPrefix(line, ".")
+		data = append(data, line...)
+	}
+}
+
+// trimPath 从 "FROM:<a@b.c>" 中取出地址
+func trimPath(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr = strings.TrimSpace(addr)
+	if i := strings.IndexByte(addr, ' '); i >= 0 {
+		addr = addr[:i]
+	}
+	return strings.Trim(addr, "<>")
+}
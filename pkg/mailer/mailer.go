@@ -0,0 +1,165 @@
+// Package mailer 发送邮件, 提供SMTP实现、只写日志的实现以及本地调试用的SMTP捕获服务
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message 一封邮件, 同时包含纯文本和HTML两种正文
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer 发送邮件
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// SMTPMailer 通过SMTP服务器发送邮件, 服务器支持时自动使用STARTTLS
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+}
+
+// NewSMTP 创建SMTP发送器, username为空时不进行认证
+func NewSMTP(host string, port int, username, password, from string) (*SMTPMailer, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	m := &SMTPMailer{
+		addr: host + ":" + strconv.Itoa(port),
+		from: addr,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(msg *Message) error {
+	data, err := Build(m.from.String(), msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from.Address, []string{msg.To}, data)
+}
+
+// LogMailer 不发送邮件, 只把收件人和标题写到日志, 没有配置SMTP时使用
+type LogMailer struct {
+	log  *zap.Logger
+	body bool
+}
+
+// NewLog 创建只写日志的发送器, body为true时正文也写到日志
+// 正文中有一次性的验证和重置密码链接, 只能在本地调试时打开
+func NewLog(log *zap.Logger, body bool) *LogMailer {
+	return &LogMailer{log: log, body: body}
+}
+
+// Send 把邮件写到日志
+func (m *LogMailer) Send(msg *Message) error {
+	fields := []zap.Field{zap.String("to", msg.To), zap.String("subject", msg.Subject)}
+	if m.body {
+		fields = append(fields, zap.String("text", msg.Text))
+	}
+	m.log.Info("mail not sent, smtp disabled", fields...)
+	return nil
+}
+
+// Build 生成multipart/alternative格式的邮件内容
+func Build(from string, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := []string{
+		"From: " + from,
+		"To: " + msg.To,
+		"Subject: " + mime.BEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse 解析Build生成的邮件, 用于捕获服务和测试
+func Parse(data []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{To: m.Header.Get("To"), Subject: subject}
+
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart() // NextPart会自动解码quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			msg.Text = string(body)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/html"):
+			msg.HTML = string(body)
+		}
+	}
+	return msg, nil
+}
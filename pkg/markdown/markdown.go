@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// md 支持GFM的表格、删除线、自动链接和任务列表
+// 没有开启WithUnsafe, 原始HTML不会输出
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// policy 渲染结果的白名单, 在UGC规则的基础上只允许http、https和mailto链接
+// 外部链接加上rel="nofollow noreferrer noopener"并在新窗口打开, 代码块只保留language-xxx的class
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	// 任务列表的复选框
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render 把Markdown渲染成过滤之后的HTML
+func Render(src string) string {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		// 只有写入失败时才会出错, 退回到转义之后的原文
+		return "<p>" + html.EscapeString(src) + "</p>"
+	}
+	return policy.Sanitize(buf.String())
+}
@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		src     string
+		want    []string // 渲染结果中必须包含
+		notWant []string // 渲染结果中不能包含
+	}{
+		{
+			name:    "script tag",
+			src:     "hi <script>alert(1)</script>",
+			notWant: []string{"<script", "alert(1)</script>"},
+		},
+		{
+			name:    "iframe",
+			src:     `<iframe src="https://evil.example.com"></iframe>`,
+			notWant: []string{"<iframe"},
+		},
+		{
+			name:    "event handler attribute",
+			src:     `<img src="https://example.com/a.png" onerror="alert(1)">`,
+			notWant: []string{"onerror"},
+		},
+		{
+			name:    "event handler on inline html",
+			src:     `<a href="https://example.com" onclick="alert(1)">x</a>`,
+			notWant: []string{"onclick"},
+		},
+		{
+			name:    "raw html block",
+			src:     "<div style=\"color:red\">raw</div>",
+			notWant: []string{"<div", "style="},
+		},
+		{
+			name:    "javascript link",
+			src:     "[x](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "javascript link with mixed case",
+			src:     "[x](JaVaScRiPt:alert(1))",
+			notWant: []string{"javascript:", "JaVaScRiPt:"},
+		},
+		{
+			name:    "data link",
+			src:     "[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)",
+			notWant: []string{"data:"},
+		},
+		{
+			name:    "data image",
+			src:     "![x](data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=)",
+			notWant: []string{"data:"},
+		},
+		{
+			name: "external link",
+			src:  "[go](https://go.dev)",
+			want: []string{`href="https://go.dev"`, `rel="nofollow noreferrer noopener"`, `target="_blank"`},
+		},
+		{
+			name: "mailto link",
+			src:  "[mail](mailto:a@example.com)",
+			want: []string{`href="mailto:a@example.com"`},
+		},
+		{
+			name: "code block language class",
+			src:  "```go\nfmt.Println(1)\n```",
+			want: []string{`<code class="language-go">`},
+		},
+		{
+			name:    "code block with unsafe class",
+			src:     "```go\" onmouseover=\"alert(1)\nx\n```",
+			notWant: []string{"onmouseover"},
+		},
+		{
+			name:    "class on other elements",
+			src:     `<p class="language-go">x</p>`,
+			notWant: []string{"class="},
+		},
+		{
+			name:    "escaped html in code",
+			src:     "`<script>`",
+			want:    []string{"<code>&lt;script&gt;</code>"},
+			notWant: []string{"<script>"},
+		},
+		{
+			name: "task list",
+			src:  "- [x] done",
+			want: []string{`<input checked="" disabled="" type="checkbox"`},
+		},
+		{
+			name: "table",
+			src:  "| a |\n| - |\n| 1 |",
+			want: []string{"<table>", "<td>1</td>"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.src)
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("Render(%q) = %q, want it to contain %q", tt.src, got, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("Render(%q) = %q, must not contain %q", tt.src, got, s)
+				}
+			}
+		})
+	}
+}
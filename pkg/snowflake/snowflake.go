@@ -6,22 +6,30 @@ import (
 	sf "github.com/bwmarrin/snowflake"
 )
 
-var node *sf.Node
+// Node 雪花算法的id生成器
+type Node struct {
+	node *sf.Node
+}
 
-func Init(startTime string, machineID int64) (err error) {
-	var st time.Time
-	st, err = time.Parse("2006-01-02", startTime)
+// New 创建id生成器
+// 注意起始时间会修改sf.Epoch, 同一进程中的所有Node需要使用相同的起始时间
+func New(startTime string, machineID int64) (*Node, error) {
+	st, err := time.Parse("2006-01-02", startTime)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	// 起始时间
 	sf.Epoch = st.UnixMilli()
 	// 初始化节点, 机器的id
-	node, err = sf.NewNode(machineID)
-	return
+	node, err := sf.NewNode(machineID)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{node: node}, nil
 }
 
-func GenID() int64 {
-	return node.Generate().Int64()
+// NextID 生成一个新的id
+func (n *Node) NextID() int64 {
+	return n.node.Generate().Int64()
 }
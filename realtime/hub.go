@@ -0,0 +1,115 @@
+// Package realtime 把Redis pub/sub收到的实时事件分发给当前实例上订阅的WebSocket/SSE连接
+package realtime
+
+import (
+	"bluebell/models"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// subscriptionBuffer 每个订阅缓存的事件数量, 客户端消费太慢时丢弃新事件
+const subscriptionBuffer = 64
+
+// Source 跨实例的事件来源, 由dao/redis实现
+type Source interface {
+	SubscribeEvents() (<-chan *models.Event, func() error, error)
+}
+
+// Subscription 一个客户端连接的订阅
+type Subscription struct {
+	C      <-chan *models.Event // Hub停止时会被关闭
+	ch     chan *models.Event
+	topics map[string]struct{}
+}
+
+// Hub 保存当前实例上的全部订阅
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	log  *zap.Logger
+}
+
+// NewHub 创建Hub, 需要调用Listen之后才能收到其他实例发布的事件
+func NewHub(log *zap.Logger) *Hub {
+	return &Hub{
+		subs: make(map[*Subscription]struct{}),
+		log:  log,
+	}
+}
+
+// Subscribe 订阅一个或多个频道
+func (h *Hub) Subscribe(topics []string) *Subscription {
+	ch := make(chan *models.Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, topics: make(map[string]struct{}, len(topics))}
+	for _, t := range topics {
+		sub.topics[t] = struct{}{}
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe 取消订阅, 连接断开时调用
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// Broadcast 把事件发送给订阅了相关频道的连接, 不会阻塞
+func (h *Hub) Broadcast(e *models.Event) {
+	topics := e.Topics()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.match(topics) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			h.log.Warn("subscription buffer full, event dropped", zap.String("type", e.Type), zap.Int64("post_id", e.PostID))
+		}
+	}
+}
+
+func (sub *Subscription) match(topics []string) bool {
+	for _, t := range topics {
+		if _, ok := sub.topics[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Listen 从src接收事件并分发, 调用stop停止接收并关闭全部订阅
+func (h *Hub) Listen(src Source) (stop func(), err error) {
+	events, closeSrc, err := src.SubscribeEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range events {
+			h.Broadcast(e)
+		}
+	}()
+
+	return func() {
+		if err := closeSrc(); err != nil {
+			h.log.Error("close event subscription failed", zap.Error(err))
+		}
+		<-done
+		h.mu.Lock()
+		for sub := range h.subs {
+			close(sub.ch)
+			delete(h.subs, sub)
+		}
+		h.mu.Unlock()
+	}, nil
+}
@@ -1,45 +1,147 @@
 package router
 
 import (
+	"bluebell/app"
 	"bluebell/controller"
 	"bluebell/logger"
 	"bluebell/middlewares"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func SetupRouter(mode string) *gin.Engine {
-	if mode == gin.ReleaseMode {
+// SetupRouter 使用App中的依赖注册路由
+func SetupRouter(a *app.App) *gin.Engine {
+	if a.Config.Get().App.Mode == gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	// 默认为debug模式
 	r := gin.New()
-	r.Use(logger.GinLogger(), logger.GinRecovery(true))
+	// 只信任配置的反向代理, 否则客户端可以伪造X-Forwarded-For绕过按ip的限流
+	if err := r.SetTrustedProxies(a.Config.Get().App.TrustedProxies); err != nil {
+		a.Logger.Error("set trusted proxies failed", zap.Error(err))
+	}
+	r.Use(logger.GinLogger(a.Logger), logger.GinRecovery(a.Logger, true))
+
+	h := controller.NewHandler(a.Service, a.Hub, a.Logger)
+	limit := func(rule string) gin.HandlerFunc {
+		return middlewares.RateLimitMiddleware(a.Config, a.Redis, a.Logger, rule)
+	}
+	feature := func(name string) gin.HandlerFunc {
+		return middlewares.FeatureMiddleware(a.Config, name)
+	}
 
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", middlewares.MaintenanceMiddleware(a.Config))
 
 	// 注册
-	v1.POST("/signup", controller.SignUpHandler)
+	v1.POST("/signup", feature("signup"), limit("signup"), h.SignUpHandler)
 	// 登录
-	v1.POST("/login", controller.LoginHandler)
+	v1.POST("/login", limit("login"), h.LoginHandler)
+	// 登录第二步, 校验两步验证码
+	v1.POST("/login/2fa", limit("login"), h.LoginMFAHandler)
 
-	// 根据时间或分数获取帖子列表
-	v1.GET("/posts2", controller.GetPostListHandler2)
-	v1.GET("/posts", controller.GetPostListHandler)
-	v1.GET("/community", controller.CommunityHandler)
-	v1.GET("/community/:id", controller.CommunityDetailHandler)
+	// 绑定两步验证, 被强制绑定的用户使用登录返回的mfa_token访问
+	v1.POST("/2fa/enroll", middlewares.MFAEnrollAuthMiddleware(a.Tokens), h.EnrollMFAHandler)
+	v1.POST("/2fa/activate", middlewares.MFAEnrollAuthMiddleware(a.Tokens), h.ActivateMFAHandler)
+
+	// 邮箱验证和找回密码
+	v1.POST("/email/verify", h.VerifyEmailHandler)
+	v1.POST("/password/forgot", limit("mail"), h.ForgotPasswordHandler)
+	v1.POST("/password/reset", limit("mail"), h.ResetPasswordHandler)
+
+	// 根据时间或分数获取帖子列表, 登录时返回当前用户的投票状态
+	v1.GET("/posts2", middlewares.OptionalJWTAuthMiddleware(a.Tokens), h.GetPostListHandler2)
+	v1.GET("/posts", middlewares.OptionalJWTAuthMiddleware(a.Tokens), h.GetPostListHandler)
+	v1.GET("/community", h.CommunityHandler)
+	v1.GET("/community/:id", h.CommunityDetailHandler)
+	// 社区的标签及每个标签的帖子数
+	v1.GET("/community/:id/tags", h.CommunityTagsHandler)
+	// 实时推送投票和新帖子事件
+	v1.GET("/stream/sse", h.StreamSSEHandler)
+	v1.GET("/stream/ws", h.StreamWSHandler)
+	// 用户主页
+	v1.GET("/user/:id", h.UserDetailHandler)
 
 	// 使用 OptionalJWTAuthMiddleware，让 GetPostDetailHandler 可以获取到 userID
-	v1.GET("/post/:id", middlewares.OptionalJWTAuthMiddleware(), controller.GetPostDetailHandler)
+	v1.GET("/post/:id", middlewares.OptionalJWTAuthMiddleware(a.Tokens), h.GetPostDetailHandler)
+	// 投票帖的结果, 登录时返回当前用户的选择
+	v1.GET("/post/:id/poll", middlewares.OptionalJWTAuthMiddleware(a.Tokens), h.PollResultHandler)
+	// 决策帖的计票结果, 以及截止之后的审计记录
+	v1.GET("/post/:id/tally", middlewares.OptionalJWTAuthMiddleware(a.Tokens), h.DecisionTallyHandler)
+	v1.GET("/post/:id/audit", h.DecisionAuditHandler)
 
-	v1.Use(middlewares.JWTAuthMiddleware()) // 应用JWT认证中间件
+	v1.Use(middlewares.JWTAuthMiddleware(a.Tokens)) // 应用JWT认证中间件
+	// 开启强制两步验证之前签发的token, 没有绑定的版主同样不能继续使用
+	v1.Use(middlewares.MFARequiredMiddleware(a.Service, a.Logger))
 
 	{
 		// 发表帖子
-		v1.POST("/post", controller.CreatePostHandler)
+		v1.POST("/post", feature("create_post"), limit("post"), h.CreatePostHandler)
+		// 预览Markdown渲染结果
+		v1.POST("/post/preview", h.PreviewPostHandler)
+		// 修改帖子的标签
+		v1.PUT("/post/:id/tags", h.UpdatePostTagsHandler)
+		// 草稿和定时发布的帖子, 只有作者可见
+		v1.PUT("/post/:id/draft", h.UpdateDraftHandler)
+		v1.POST("/post/:id/publish", h.PublishPostHandler)
+
+		// 创建社区
+		v1.POST("/community", feature("create_community"), h.CreateCommunityHandler)
+
+		// 关注社区, 社区有新帖子时收到通知
+		v1.POST("/community/:id/follow", h.FollowCommunityHandler)
+		v1.DELETE("/community/:id/follow", h.UnfollowCommunityHandler)
 
 		// 为帖子投票
-		v1.POST("/vote", controller.PostVoteHandler)
+		v1.POST("/vote", feature("vote"), limit("vote"), h.PostVoteHandler)
+		// 给投票帖投票
+		v1.POST("/post/:id/poll", feature("vote"), limit("vote"), h.PollVoteHandler)
+		// 给决策帖投票, 作者可以提前截止
+		v1.POST("/post/:id/ballot", feature("vote"), limit("vote"), h.CastBallotHandler)
+		v1.POST("/post/:id/close", h.CloseDecisionHandler)
+
+		// 个人资料
+		v1.GET("/me", h.MyProfileHandler)
+		v1.PATCH("/me", h.UpdateProfileHandler)
+		// 修改邮箱, 重新发送验证邮件
+		v1.PUT("/me/email", limit("mail"), h.UpdateEmailHandler)
+		v1.POST("/email/resend", limit("mail"), h.ResendVerifyEmailHandler)
+		// 投票记录
+		v1.GET("/me/votes", h.MyVotesHandler)
+		// 草稿和定时发布的帖子
+		v1.GET("/me/drafts", h.MyDraftsHandler)
+
+		// 站内通知
+		v1.GET("/notifications", h.NotificationsHandler)
+		v1.POST("/notifications/:id/read", h.ReadNotificationHandler)
+		v1.POST("/notifications/read_all", h.ReadAllNotificationsHandler)
+		v1.GET("/notifications/preferences", h.NotificationPrefsHandler)
+		v1.PUT("/notifications/preferences", h.UpdateNotificationPrefsHandler)
+
+		// 关闭两步验证
+		v1.POST("/2fa/disable", h.DisableMFAHandler)
+		// 重新生成恢复码
+		v1.POST("/2fa/recovery_codes", h.RecoveryCodesHandler)
+	}
+
+	// 管理员接口
+	admin := v1.Group("/admin", middlewares.AdminAuthMiddleware(a.Service, a.Logger))
+	{
+		// 解除账号的登录锁定
+		admin.POST("/user/:id/unlock", h.UnlockUserHandler)
+		// 查询登录审计记录
+		admin.GET("/login_attempts", h.LoginAttemptsHandler)
+		// 设置版主是否必须开启两步验证
+		admin.PUT("/settings/require_mfa", h.SetRequireMFAHandler)
+		// 修改社区的投票规则
+		admin.PUT("/community/:id/vote_rule", h.UpdateCommunityVoteRuleHandler)
+		// 社区定义的标签, 定义之后社区只能使用定义的标签
+		admin.POST("/community/:id/tags", h.CreateCommunityTagHandler)
+		admin.DELETE("/community/:id/tags/:name", h.DeleteCommunityTagHandler)
+		// 可疑投票的审核队列
+		admin.GET("/vote_flags", h.VoteFlagsHandler)
+		admin.POST("/vote_flags/:id/nullify", h.NullifyVoteFlagHandler)
+		admin.POST("/vote_flags/:id/dismiss", h.DismissVoteFlagHandler)
 	}
 
 	return r
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bluebell/seeder"
+	"fmt"
+	"time"
+)
+
+// runSeed 生成测试数据, 通过存储接口写入, 保证MySQL和Redis中的数据一致
+func runSeed(args []string) error {
+	fs, cf := newFlagSet("seed")
+	var o seeder.Options
+	fs.IntVar(&o.Users, "users", 100, "用户数量, 密码都是"+seeder.Password)
+	fs.IntVar(&o.Communities, "communities", 0, "新建的社区数量, 为0时使用已有的社区")
+	fs.IntVar(&o.Posts, "posts", 1000, "帖子数量, 随机分配作者和社区")
+	fs.Float64Var(&o.VotesPerPost, "votes-per-post", 5, "平均每个帖子的票数")
+	fs.Float64Var(&o.DownvoteRatio, "downvote-ratio", 0.2, "反对票的比例")
+	fs.StringVar(&o.TimeDist, "time-dist", seeder.TimeRecent, "帖子发布时间的分布 uniform|recent")
+	fs.StringVar(&o.VoteDist, "vote-dist", seeder.VoteZipf, "投票在帖子之间的分布 uniform|zipf")
+	fs.DurationVar(&o.Window, "window", 0, "帖子的发布时间分布在最近多长时间内, 默认为投票的窗口期")
+	fs.Int64Var(&o.Seed, "seed", time.Now().UnixNano(), "随机数种子")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := cf.bootstrap(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if o.Window == 0 {
+		o.Window = a.Service.VoteRule().Window
+	}
+
+	start := time.Now()
+	res, err := seeder.New(a.Service.Stores, a.IDs, a.Service.CommunityVoteRule, a.Clock).Run(o)
+	if res != nil {
+		fmt.Printf("created %d users (%s0..%s%d), %d communities, %d posts, %d votes (%d skipped) in %s\n",
+			res.Users, res.Prefix, res.Prefix, res.Users-1, res.Communities, res.Posts, res.Votes, res.SkippedVotes,
+			time.Since(start).Round(time.Millisecond))
+	}
+	return err
+}
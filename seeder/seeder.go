@@ -0,0 +1,239 @@
+// Package seeder 生成用于测试排序和分页的模拟数据
+// 数据通过存储接口写入, MySQL和Redis中的数据与正常业务写入的保持一致
+package seeder
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// 帖子发布时间的分布
+const (
+	TimeUniform = "uniform" // 均匀分布在窗口期内
+	TimeRecent  = "recent"  // 指数分布, 越新的帖子越多
+)
+
+// 投票在帖子之间的分布
+const (
+	VoteUniform = "uniform" // 每个帖子被投票的概率相同
+	VoteZipf    = "zipf"    // 长尾分布, 少数帖子拿到大部分的票
+)
+
+// Password 生成的用户的密码
+const Password = "123456"
+
+// Options 生成数据的规模和分布
+type Options struct {
+	Users         int
+	Communities   int // 为0时使用已有的社区
+	Posts         int
+	VotesPerPost  float64       // 平均每个帖子的票数
+	DownvoteRatio float64       // 反对票的比例
+	TimeDist      string        // 帖子发布时间的分布
+	VoteDist      string        // 投票在帖子之间的分布
+	Window        time.Duration // 帖子的发布时间分布在最近的Window内, 超过社区投票窗口期的帖子不会被投票
+	Seed          int64         // 随机数种子, 相同的种子生成相同分布的数据
+}
+
+// Result 实际生成的数据量
+type Result struct {
+	Prefix       string // 用户名前缀, 用户名为 Prefix+序号
+	Users        int
+	Communities  int
+	Posts        int
+	Votes        int
+	SkippedVotes int // 重复投票或者帖子刚好超过投票窗口期时跳过
+}
+
+// RuleFunc 获取社区实际生效的投票规则
+type RuleFunc func(communityID int64) (models.VoteRule, error)
+
+// Seeder 模拟数据生成器
+type Seeder struct {
+	stores logic.Stores
+	ids    logic.IDGenerator
+	rules  RuleFunc
+	now    func() time.Time
+}
+
+// New 创建模拟数据生成器, rules用于获取每个社区的投票规则
+func New(stores logic.Stores, ids logic.IDGenerator, rules RuleFunc, now func() time.Time) *Seeder {
+	return &Seeder{stores: stores, ids: ids, rules: rules, now: now}
+}
+
+// Validate 校验参数
+func (o *Options) Validate() error {
+	if o.Users <= 0 || o.Posts < 0 || o.Communities < 0 || o.VotesPerPost < 0 {
+		return errors.New("users must be positive, communities, posts and votes must not be negative")
+	}
+	if o.DownvoteRatio < 0 || o.DownvoteRatio > 1 {
+		return errors.New("downvote ratio must be between 0 and 1")
+	}
+	if o.TimeDist != TimeUniform && o.TimeDist != TimeRecent {
+		return fmt.Errorf("unknown time distribution %q", o.TimeDist)
+	}
+	if o.VoteDist != VoteUniform && o.VoteDist != VoteZipf {
+		return fmt.Errorf("unknown vote distribution %q", o.VoteDist)
+	}
+	if o.Window <= 0 {
+		return errors.New("window must be positive")
+	}
+	return nil
+}
+
+// Run 按照参数生成用户、社区、帖子和投票
+func (s *Seeder) Run(o Options) (*Result, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(o.Seed))
+	// 名字加上id的后缀, 重复执行也不会冲突
+	suffix := strconv.FormatInt(s.ids.NextID()%1000000, 10)
+	res := &Result{Prefix: "seed_" + suffix + "_"}
+
+	// 1 用户
+	userIDs := make([]int64, 0, o.Users)
+	for i := 0; i < o.Users; i++ {
+		user := &models.User{
+			UserID:   s.ids.NextID(),
+			Username: res.Prefix + strconv.Itoa(i),
+			Password: Password,
+		}
+		if err := s.stores.Users.InsertUser(user); err != nil {
+			return res, err
+		}
+		userIDs = append(userIDs, user.UserID)
+		res.Users++
+	}
+
+	// 2 社区
+	var communityIDs []int64
+	for i := 0; i < o.Communities; i++ {
+		c, err := s.stores.Communities.CreateCommunity("seed-"+suffix+"-"+strconv.Itoa(i), "seeded community")
+		if err != nil {
+			return res, err
+		}
+		communityIDs = append(communityIDs, c.ID)
+		res.Communities++
+	}
+	if len(communityIDs) == 0 {
+		list, err := s.stores.Communities.GetCommunityList()
+		if err != nil {
+			return res, err
+		}
+		for _, c := range list {
+			communityIDs = append(communityIDs, c.ID)
+		}
+	}
+	if len(communityIDs) == 0 && o.Posts > 0 {
+		return res, errors.New("no community to post in")
+	}
+
+	// 3 帖子, 发布时间按照分布落在窗口期内
+	now := s.now()
+	posts := make([]*models.Post, 0, o.Posts)
+	for i := 0; i < o.Posts; i++ {
+		p := &models.Post{
+			ID:          s.ids.NextID(),
+			AuthorID:    userIDs[r.Intn(len(userIDs))],
+			CommunityID: communityIDs[r.Intn(len(communityIDs))],
+			Status:      models.PostStatusPublished,
+			Title:       fmt.Sprintf("seed post %d", i),
+			Content:     fmt.Sprintf("seeded content %d", i),
+			CreateTime:  now.Add(-postAge(r, o.TimeDist, o.Window)).Truncate(time.Second),
+		}
+		if err := s.stores.Posts.CreatePost(p); err != nil {
+			return res, err
+		}
+		if err := s.stores.Votes.CreatePost(p); err != nil {
+			return res, err
+		}
+		posts = append(posts, p)
+		res.Posts++
+	}
+
+	// 4 投票, 使用帖子所在社区的投票规则
+	if len(posts) == 0 {
+		return res, nil
+	}
+	rules := make(map[int64]models.VoteRule)
+	for _, id := range communityIDs {
+		rule, err := s.rules(id)
+		if err != nil {
+			return res, err
+		}
+		rules[id] = rule
+	}
+	var zipf *rand.Zipf
+	if o.VoteDist == VoteZipf && len(posts) > 1 {
+		zipf = rand.NewZipf(r, 1.2, 1, uint64(len(posts)-1))
+	}
+	// 热门帖子是随机的, 而不是最早或最新的帖子
+	popularity := r.Perm(len(posts))
+	total := int(math.Round(o.VotesPerPost * float64(len(posts))))
+	for i := 0; i < total; i++ {
+		idx := r.Intn(len(posts))
+		if zipf != nil {
+			idx = popularity[zipf.Uint64()]
+		}
+		p := posts[idx]
+		dir := 1.0
+		if r.Float64() < o.DownvoteRatio {
+			dir = -1
+		}
+		voter := userIDs[r.Intn(len(userIDs))]
+		err := s.stores.Votes.VoteForPost(models.Voter{
+			ID:   strconv.FormatInt(voter, 10),
+			Self: voter == p.AuthorID,
+		}, p, dir, rules[p.CommunityID])
+		if errors.Is(err, redis.ErrVoteRepeated) || errors.Is(err, redis.ErrVoteTimeExpire) {
+			res.SkippedVotes++
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		// 投票记录的时间落在帖子的投票窗口期内, 用于刷票分析
+		err = s.stores.Audit.InsertVoteRecord(&models.VoteRecord{
+			PostID:     p.ID,
+			AuthorID:   p.AuthorID,
+			UserID:     voter,
+			Direction:  int8(dir),
+			CreateTime: voteTime(r, p.CreateTime, now, rules[p.CommunityID].Window),
+		})
+		if err != nil {
+			return res, err
+		}
+		res.Votes++
+	}
+	return res, nil
+}
+
+// voteTime 投票时间, 均匀分布在帖子发布之后的投票窗口期内, 不晚于now
+func voteTime(r *rand.Rand, created, now time.Time, window time.Duration) time.Time {
+	span := now.Sub(created)
+	if window > 0 && window < span {
+		span = window
+	}
+	if span <= 0 {
+		return created
+	}
+	return created.Add(time.Duration(r.Int63n(int64(span)))).Truncate(time.Second)
+}
+
+// postAge 帖子距今的时间
+func postAge(r *rand.Rand, dist string, window time.Duration) time.Duration {
+	if dist == TimeRecent {
+		// 平均值为窗口期的1/5, 超出窗口期的重新落在窗口期内
+		age := time.Duration(r.ExpFloat64() * float64(window) / 5)
+		return age % window
+	}
+	return time.Duration(r.Int63n(int64(window)))
+}
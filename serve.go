@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bluebell/router"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// runServe 启动HTTP服务
+func runServe(args []string) error {
+	fs, cf := newFlagSet("serve")
+	// 也可以通过配置文件的mysql.auto_migrate开启
+	autoMigrate := fs.Bool("auto-migrate", false, "启动时自动执行数据库迁移")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// 创建App, 所有依赖都保存在App中, 退出时统一关闭
+	a, err := cf.bootstrap(*autoMigrate)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// 配置文件变化时热更新日志级别、限流、投票规则、功能开关和维护模式
+	stop, err := a.WatchConfig(cf.config, cf.env)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	// 订阅实时事件, 推送给当前实例上的WebSocket/SSE连接
+	stopEvents, err := a.ListenEvents()
+	if err != nil {
+		return err
+	}
+	defer stopEvents()
+
+	// 后台定期分析投票记录, 可以通过vote_audit.enable关闭
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Service.RunVoteAnalyzer(ctx)
+	// 后台发布到期的定时帖子
+	go a.Service.RunPostScheduler(ctx)
+
+	// 注册路由
+	r := router.SetupRouter(a)
+	if err := r.Run(fmt.Sprintf(":%d", a.Config.Get().App.Port)); err != nil {
+		a.Logger.Error("start server failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
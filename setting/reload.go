@@ -0,0 +1,64 @@
+package setting
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Holder 保存当前生效的配置, 热更新时整体替换, 读取方每次通过Get获取最新的配置
+type Holder struct {
+	cfg atomic.Pointer[Config]
+}
+
+// NewHolder 创建配置的Holder
+func NewHolder(cfg *Config) *Holder {
+	h := new(Holder)
+	h.cfg.Store(cfg)
+	return h
+}
+
+// Get 获取当前生效的配置, 返回的配置不能修改
+func (h *Holder) Get() *Config {
+	return h.cfg.Load()
+}
+
+// Set 替换当前生效的配置
+func (h *Holder) Set(cfg *Config) {
+	h.cfg.Store(cfg)
+}
+
+// MergeReloadable 在当前配置的基础上应用next中可以热更新的配置项
+// 返回合并后的配置, 以及发生变化但需要重启才能生效的配置项
+func MergeReloadable(cur, next *Config) (*Config, []string) {
+	merged := *cur
+	merged.Log.Level = next.Log.Level
+	merged.Karma = next.Karma
+	merged.Vote = next.Vote
+	merged.VoteAudit = next.VoteAudit
+	merged.RateLimit = next.RateLimit
+	merged.Features = next.Features
+	merged.Maintenance = next.Maintenance
+
+	// 合并之后仍然不同的就是不能热更新的配置
+	return &merged, diffKeys("", reflect.ValueOf(merged), reflect.ValueOf(*next))
+}
+
+// diffKeys 按mapstructure的key列出两个配置中不同的配置项
+func diffKeys(prefix string, a, b reflect.Value) []string {
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{prefix}
+	}
+
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		key := a.Type().Field(i).Tag.Get("mapstructure")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		keys = append(keys, diffKeys(key, a.Field(i), b.Field(i))...)
+	}
+	return keys
+}
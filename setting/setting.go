@@ -1,31 +1,315 @@
 package setting
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
 )
 
-func Init() (err error) {
+// DefaultConfigFile 默认的配置文件路径
+const DefaultConfigFile = "./config/config.yaml"
 
-	viper.SetConfigFile("./config/config.yaml") // 设置文件的路径
-	err = viper.ReadInConfig()                  // 读取配置信息
+// 运行模式, release模式下gin不输出debug信息, 日志只写文件
+const (
+	ModeDev     = "dev"
+	ModeRelease = "release"
+)
+
+// Config 全部配置项, 启动时从yaml文件解析
+type Config struct {
+	App       AppConfig       `mapstructure:"app"`
+	Log       LogConfig       `mapstructure:"log"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Karma     KarmaConfig     `mapstructure:"karma"`
+	Vote      VoteConfig      `mapstructure:"vote"`
+	VoteAudit VoteAuditConfig `mapstructure:"vote_audit"`
+	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
+	MySQL     MySQLConfig     `mapstructure:"mysql"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Mail      MailConfig      `mapstructure:"mail"`
+
+	Features    map[string]bool   `mapstructure:"features"` // 功能开关, 未配置的功能默认开启
+	Maintenance MaintenanceConfig `mapstructure:"maintenance"`
+}
+
+type AppConfig struct {
+	Name           string   `mapstructure:"name"`
+	Mode           string   `mapstructure:"mode"` // dev开启debug模式, release为发布模式
+	Port           int      `mapstructure:"port"`
+	Version        string   `mapstructure:"version"`
+	StartTime      string   `mapstructure:"start_time"` // 雪花算法的起始时间
+	MachineID      int64    `mapstructure:"machine_id"`
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // 反向代理的ip或网段, 为空表示不信任X-Forwarded-For
+}
+
+type LogConfig struct {
+	LogDir string `mapstructure:"logDir"`
+	Level  string `mapstructure:"level"` // debug/info/warn/error
+}
+
+type AuthConfig struct {
+	JWTSecret    string           `mapstructure:"jwt_secret"`
+	JWTExpire    int64            `mapstructure:"jwt_expire"`    // 小时
+	BallotSecret string           `mapstructure:"ballot_secret"` // 秘密投票生成投票人化名的HMAC密钥, 修改后无法识别之前的重复投票
+	LoginGuard   LoginGuardConfig `mapstructure:"login_guard"`
+}
+
+// LoginGuardConfig 登录保护: Window秒内连续失败DelayAfter次之后开始延迟响应, 达到阈值后锁定LockDuration秒
+type LoginGuardConfig struct {
+	Window               int64 `mapstructure:"window"`
+	DelayAfter           int64 `mapstructure:"delay_after"`
+	DelayBaseMs          int64 `mapstructure:"delay_base_ms"`
+	DelayMaxMs           int64 `mapstructure:"delay_max_ms"`
+	AccountLockThreshold int64 `mapstructure:"account_lock_threshold"`
+	IPLockThreshold      int64 `mapstructure:"ip_lock_threshold"`
+	LockDuration         int64 `mapstructure:"lock_duration"`
+}
+
+// KarmaConfig karma门槛, 小于等于0表示不限制
+type KarmaConfig struct {
+	MinToDownvote        int64 `mapstructure:"min_to_downvote"`
+	MinToCreateCommunity int64 `mapstructure:"min_to_create_community"`
+}
+
+// VoteConfig 投票规则: 发帖后Window秒内可以投票, 每一票给帖子增加ScorePerVote分
+type VoteConfig struct {
+	Window       int64   `mapstructure:"window"`
+	ScorePerVote float64 `mapstructure:"score_per_vote"`
+}
+
+// VoteAuditConfig 刷票检测: 每Interval秒分析最近Lookback秒的投票记录, 可疑的投票进入审核队列
+type VoteAuditConfig struct {
+	Enable           bool  `mapstructure:"enable"`
+	Interval         int64 `mapstructure:"interval"`
+	Lookback         int64 `mapstructure:"lookback"`
+	NewAccountAge    int64 `mapstructure:"new_account_age"`    // 注册不到多少秒算新账号
+	NewAccountVoters int64 `mapstructure:"new_account_voters"` // 同一作者收到多少个新账号的投票时标记
+	BurstWindow      int64 `mapstructure:"burst_window"`       // 秒
+	BurstVoters      int64 `mapstructure:"burst_voters"`       // 同一帖子在burst_window秒内收到多少票时标记
+	SharedIPVoters   int64 `mapstructure:"shared_ip_voters"`   // 同一ip有多少个账号给同一作者投票时标记
+}
+
+// MaintenanceConfig 维护模式, 开启后只允许读请求和管理员接口
+type MaintenanceConfig struct {
+	Enable  bool   `mapstructure:"enable"`
+	Message string `mapstructure:"message"`
+}
+
+type RateLimitConfig struct {
+	Enable bool                     `mapstructure:"enable"`
+	Rules  map[string]RateLimitRule `mapstructure:"rules"`
+}
+
+// RateLimitRule 限流规则: Window秒内最多Limit次请求, Key为限流维度(ip/user)
+type RateLimitRule struct {
+	Limit  int64  `mapstructure:"limit"`
+	Window int64  `mapstructure:"window"`
+	Key    string `mapstructure:"key"`
+}
+
+type MySQLConfig struct {
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	User         string `mapstructure:"user"`
+	Password     string `mapstructure:"password"`
+	DBName       string `mapstructure:"db_name"`
+	MaxOpenConns int    `mapstructure:"max_open_conns"`
+	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	AutoMigrate  bool   `mapstructure:"auto_migrate"`
+}
+
+type RedisConfig struct {
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Password     string `mapstructure:"password"`
+	DB           int    `mapstructure:"db"`
+	PoolSize     int    `mapstructure:"pool_size"`
+	MinIdleConns int    `mapstructure:"min_idle_conns"`
+}
+
+// MailConfig 邮件配置, 未开启时邮件只写到日志
+type MailConfig struct {
+	Enable       bool   `mapstructure:"enable"`
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Username     string `mapstructure:"username"` // 为空时不进行SMTP认证
+	Password     string `mapstructure:"password"`
+	From         string `mapstructure:"from"`
+	BaseURL      string `mapstructure:"base_url"`      // 邮件中链接指向的前端地址
+	VerifyExpire int64  `mapstructure:"verify_expire"` // 邮箱验证链接的有效期, 秒
+	ResetExpire  int64  `mapstructure:"reset_expire"`  // 重置密码链接的有效期, 秒
+	DigestTop    int64  `mapstructure:"digest_top"`    // 每周摘要最多包含多少个帖子
+}
+
+// secretKeys 可以通过 <KEY>_FILE 环境变量从文件读取的配置项, 例如 MYSQL_PASSWORD_FILE=/run/secrets/mysql_password
+var secretKeys = []string{
+	"auth.jwt_secret",
+	"auth.ballot_secret",
+	"mysql.password",
+	"redis.password",
+	"mail.password",
+}
+
+// Load 读取配置并校验, 优先级从低到高依次为:
+// 默认配置文件 < 环境配置文件(config.<env>.yaml) < 环境变量 < 密钥文件
+func Load(file, env string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(file) // 设置文件的路径
+	// 支持环境变量 可以在服务器配置上面自动配置环境, 例如 MYSQL_HOST 覆盖 mysql.host
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
 
-	if err != nil { // 读取配置信息失败
-		fmt.Printf("viper.ReadInConfig failed, err:%v\n", err)
-		return
+	if err := v.ReadInConfig(); err != nil { // 读取配置信息失败
+		return nil, fmt.Errorf("read config %s: %w", file, err)
 	}
 
-	// 支持环境变量 可以在服务器配置上面自动配置环境
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
+	// 合并对应环境的配置文件
+	if env != "" {
+		overlay := overlayFile(file, env)
+		v.SetConfigFile(overlay)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("read config %s: %w", overlay, err)
+		}
+	}
+
+	// 从文件读取密钥, 避免把密码直接写在配置文件或环境变量中
+	for _, key := range secretKeys {
+		name := strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + "_FILE"
+		path := os.Getenv(name)
+		if path == "" {
+			continue
+		}
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read secret %s from %s: %w", key, name, err)
+		}
+		v.Set(key, strings.TrimSpace(string(secret)))
+	}
+
+	cfg := new(Config)
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal config %s: %w", file, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// overlayFile 环境配置文件与默认配置文件在同一目录, config.yaml -> config.<env>.yaml
+func overlayFile(file, env string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "." + env + ext
+}
+
+// Validate 校验配置, 返回全部不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.App.Mode == ModeDev || c.App.Mode == ModeRelease, "app.mode", "must be %q or %q, got %q", ModeDev, ModeRelease, c.App.Mode)
+	check(validPort(c.App.Port), "app.port", "must be between 1 and 65535, got %d", c.App.Port)
+	_, err := time.Parse("2006-01-02", c.App.StartTime)
+	check(err == nil, "app.start_time", "must be a date like 2025-09-30, got %q", c.App.StartTime)
+	check(c.App.MachineID >= 0 && c.App.MachineID <= 1023, "app.machine_id", "must be between 0 and 1023, got %d", c.App.MachineID)
+	for _, p := range c.App.TrustedProxies {
+		check(validProxy(p), "app.trusted_proxies", "must be an ip or cidr, got %q", p)
+	}
+
+	check(c.Log.LogDir != "", "log.logDir", "must not be empty")
+	_, err = zapcore.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level", "must be one of debug/info/warn/error, got %q", c.Log.Level)
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret", "must not be empty, set it in the config file or AUTH_JWT_SECRET_FILE")
+	if c.App.Mode == ModeRelease && c.Auth.JWTSecret != "" {
+		check(len(c.Auth.JWTSecret) >= 16, "auth.jwt_secret", "must be at least 16 bytes in release mode")
+	}
+	check(c.Auth.BallotSecret != "", "auth.ballot_secret", "must not be empty, set it in the config file or AUTH_BALLOT_SECRET_FILE")
+	if c.App.Mode == ModeRelease && c.Auth.BallotSecret != "" {
+		check(len(c.Auth.BallotSecret) >= 16, "auth.ballot_secret", "must be at least 16 bytes in release mode")
+	}
+	check(c.Auth.JWTExpire > 0, "auth.jwt_expire", "must be positive, got %d", c.Auth.JWTExpire)
+	lg := c.Auth.LoginGuard
+	check(lg.Window > 0, "auth.login_guard.window", "must be positive, got %d", lg.Window)
+	check(lg.DelayMaxMs >= lg.DelayBaseMs, "auth.login_guard.delay_max_ms", "must not be less than delay_base_ms")
+	check(lg.LockDuration > 0, "auth.login_guard.lock_duration", "must be positive, got %d", lg.LockDuration)
 
-	// 监视, 如果配置文件发生了改变,启动回调函数,重新绑定配置到结构体上
-	viper.WatchConfig()
-	viper.OnConfigChange(func(in fsnotify.Event) {
-		fmt.Println("配置文件修改了...")
-	})
-	return
+	check(c.Vote.Window > 0, "vote.window", "must be positive, got %d", c.Vote.Window)
+	check(c.Vote.ScorePerVote > 0, "vote.score_per_vote", "must be positive, got %v", c.Vote.ScorePerVote)
+
+	if va := c.VoteAudit; va.Enable {
+		check(va.Interval > 0, "vote_audit.interval", "must be positive, got %d", va.Interval)
+		check(va.Lookback > 0, "vote_audit.lookback", "must be positive, got %d", va.Lookback)
+		check(va.NewAccountVoters >= 2, "vote_audit.new_account_voters", "must be at least 2, got %d", va.NewAccountVoters)
+		check(va.BurstWindow > 0, "vote_audit.burst_window", "must be positive, got %d", va.BurstWindow)
+		check(va.BurstVoters >= 2, "vote_audit.burst_voters", "must be at least 2, got %d", va.BurstVoters)
+		check(va.SharedIPVoters >= 2, "vote_audit.shared_ip_voters", "must be at least 2, got %d", va.SharedIPVoters)
+	}
+
+	check(c.MySQL.Host != "", "mysql.host", "must not be empty")
+	check(validPort(c.MySQL.Port), "mysql.port", "must be between 1 and 65535, got %d", c.MySQL.Port)
+	check(c.MySQL.User != "", "mysql.user", "must not be empty")
+	check(c.MySQL.DBName != "", "mysql.db_name", "must not be empty")
+	check(c.MySQL.MaxOpenConns > 0, "mysql.max_open_conns", "must be positive, got %d", c.MySQL.MaxOpenConns)
+
+	check(c.Redis.Host != "", "redis.host", "must not be empty")
+	check(validPort(c.Redis.Port), "redis.port", "must be between 1 and 65535, got %d", c.Redis.Port)
+	check(c.Redis.DB >= 0 && c.Redis.DB <= 15, "redis.db", "must be between 0 and 15, got %d", c.Redis.DB)
+	check(c.Redis.PoolSize > 0, "redis.pool_size", "must be positive, got %d", c.Redis.PoolSize)
+
+	check(c.Mail.BaseURL != "", "mail.base_url", "must not be empty")
+	check(c.Mail.VerifyExpire > 0, "mail.verify_expire", "must be positive, got %d", c.Mail.VerifyExpire)
+	check(c.Mail.ResetExpire > 0, "mail.reset_expire", "must be positive, got %d", c.Mail.ResetExpire)
+	check(c.Mail.DigestTop > 0, "mail.digest_top", "must be positive, got %d", c.Mail.DigestTop)
+	if c.Mail.Enable {
+		check(c.Mail.Host != "", "mail.host", "must not be empty")
+		check(validPort(c.Mail.Port), "mail.port", "must be between 1 and 65535, got %d", c.Mail.Port)
+		_, err = mail.ParseAddress(c.Mail.From)
+		check(err == nil, "mail.from", "must be an email address, got %q", c.Mail.From)
+	}
+
+	names := make([]string, 0, len(c.RateLimit.Rules))
+	for name := range c.RateLimit.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := c.RateLimit.Rules[name]
+		key := "ratelimit.rules." + name
+		check(r.Limit > 0, key+".limit", "must be positive, got %d", r.Limit)
+		check(r.Window > 0, key+".window", "must be positive, got %d", r.Window)
+		check(r.Key == "ip" || r.Key == "user", key+".key", "must be \"ip\" or \"user\", got %q", r.Key)
+	}
+	return errors.Join(errs...)
+}
+
+// FeatureEnabled 判断功能是否开启, 未配置的功能默认开启
+func (c *Config) FeatureEnabled(name string) bool {
+	enabled, ok := c.Features[name]
+	return !ok || enabled
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validProxy(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
 }
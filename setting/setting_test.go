@@ -0,0 +1,126 @@
+package setting
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadOverlays(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(secret, []byte("a-very-long-production-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AUTH_JWT_SECRET_FILE", secret)
+	t.Setenv("AUTH_BALLOT_SECRET_FILE", secret)
+	t.Setenv("MYSQL_HOST", "db.internal")
+
+	cfg, err := Load("../config/config.yaml", "prod")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.App.Mode != ModeRelease {
+		t.Errorf("app.mode = %q, want %q from overlay", cfg.App.Mode, ModeRelease)
+	}
+	if cfg.App.Port != 8080 {
+		t.Errorf("app.port = %d, want 8080 from base file", cfg.App.Port)
+	}
+	if cfg.MySQL.Host != "db.internal" {
+		t.Errorf("mysql.host = %q, want value from env", cfg.MySQL.Host)
+	}
+	if cfg.Auth.JWTSecret != "a-very-long-production-secret" {
+		t.Errorf("auth.jwt_secret = %q, want value from secret file", cfg.Auth.JWTSecret)
+	}
+	if r := cfg.RateLimit.Rules["login"]; r.Limit != 10 || r.Key != "ip" {
+		t.Errorf("ratelimit.rules.login = %+v", r)
+	}
+
+	// 生产环境没有配置密钥时拒绝启动
+	t.Setenv("AUTH_JWT_SECRET_FILE", "")
+	if _, err := Load("../config/config.yaml", "prod"); err == nil || !strings.Contains(err.Error(), "auth.jwt_secret") {
+		t.Fatalf("Load without secret: err = %v", err)
+	}
+	if _, err := Load("../config/config.yaml", "staging"); err == nil {
+		t.Fatal("Load with missing overlay succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Load("../config/config.yaml", "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfg.App.Mode = "debug"
+	cfg.Redis.Port = 0
+	cfg.RateLimit.Rules["vote"] = RateLimitRule{Limit: 1, Window: 60, Key: "session"}
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, key := range []string{"app.mode", "redis.port", "ratelimit.rules.vote.key"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not mention %s", err, key)
+		}
+	}
+}
+
+func TestMergeReloadable(t *testing.T) {
+	cur, err := Load("../config/config.yaml", "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	next := *cur
+	next.Log.Level = "warn"
+	next.Vote.ScorePerVote = 100
+	next.App.Port = 9090
+	next.MySQL.Host = "db.internal"
+
+	merged, restart := MergeReloadable(cur, &next)
+	if merged.Log.Level != "warn" || merged.Vote.ScorePerVote != 100 {
+		t.Errorf("reloadable settings not applied: %+v %+v", merged.Log, merged.Vote)
+	}
+	if merged.App.Port != cur.App.Port || merged.MySQL.Host != cur.MySQL.Host {
+		t.Errorf("non-reloadable settings applied: port %d host %q", merged.App.Port, merged.MySQL.Host)
+	}
+	if strings.Join(restart, ",") != "app.port,mysql.host" {
+		t.Errorf("restart = %v, want [app.port mysql.host]", restart)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	base, err := os.ReadFile("../config/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, base, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan *Config, 1)
+	stop, err := Watch(file, "", func(cfg *Config, err error) {
+		if err == nil {
+			changed <- cfg
+		}
+	})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	defer stop()
+
+	next := strings.Replace(string(base), "maintenance:\n  enable: false", "maintenance:\n  enable: true", 1)
+	if err := os.WriteFile(file, []byte(next), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-changed:
+		if !cfg.Maintenance.Enable {
+			t.Fatal("maintenance not enabled after reload")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after config change")
+	}
+}
@@ -0,0 +1,61 @@
+package setting
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 编辑器保存文件时会产生多个事件, 合并成一次重新加载
+const watchDebounce = 200 * time.Millisecond
+
+// Watch 监听配置文件和环境配置文件, 文件变化时重新加载配置并回调onChange
+// 加载、校验或监听失败时cfg为nil, 由调用方决定是否继续使用旧的配置
+func Watch(file, env string, onChange func(cfg *Config, err error)) (stop func(), err error) {
+	files := map[string]bool{filepath.Clean(file): true}
+	if env != "" {
+		files[filepath.Clean(overlayFile(file, env))] = true
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// 监听目录而不是文件, 很多编辑器保存时会先删除再重新创建文件
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					timer = time.After(watchDebounce)
+				}
+			case <-timer:
+				timer = nil
+				onChange(Load(file, env))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onChange(nil, err)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
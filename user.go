@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bluebell/models"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runUser 用户管理子命令, govote user create -username name [-password pwd] [--admin|--moderator]
+func runUser(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New("usage: govote user create -username name [-password pwd] [--admin|--moderator]")
+	}
+
+	fs, cf := newFlagSet("user create")
+	username := fs.String("username", "", "用户名")
+	password := fs.String("password", "", "密码, 为空时从标准输入读取")
+	admin := fs.Bool("admin", false, "创建管理员")
+	moderator := fs.Bool("moderator", false, "创建版主")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if *admin && *moderator {
+		return errors.New("-admin and -moderator are mutually exclusive")
+	}
+
+	// 避免密码出现在命令行历史中
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		*password = strings.TrimSpace(line)
+	}
+	if *password == "" {
+		return errors.New("password must not be empty")
+	}
+
+	role := models.RoleUser
+	switch {
+	case *admin:
+		role = models.RoleAdmin
+	case *moderator:
+		role = models.RoleModerator
+	}
+
+	a, err := cf.bootstrap(false)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	userID, err := a.Service.CreateUser(&models.ParamSignUp{Username: *username, Password: *password}, role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created user %s, id %d, role %d\n", *username, userID, role)
+	return nil
+}